@@ -28,11 +28,42 @@ import (
 // AppSec is disabled or the given context is incorrect.
 // Note that passing the raw bytes of the HTTP request body is not expected and would
 // result in inaccurate attack detection.
+// Use MonitorParsedHTTPBodyWithError to block the request according to its body.
 func MonitorParsedHTTPBody(ctx context.Context, body interface{}) {
-	if appsec.Enabled() {
-		httpsec.MonitorParsedBody(ctx, body)
+	MonitorParsedHTTPBodyWithError(ctx, body)
+}
+
+// MonitorParsedHTTPBodyWithError is like MonitorParsedHTTPBody but returns an
+// error when the body triggers the blocking of the request. In that case, the
+// caller must immediately abort its execution and the request handler's. The
+// blocking response will be automatically sent by the APM tracer middleware on
+// use according to your blocking configuration.
+// This function always returns nil when appsec is disabled.
+func MonitorParsedHTTPBodyWithError(ctx context.Context, body interface{}) error {
+	if !appsec.Enabled() {
+		// bonus: use sync.Once to log a debug message once if AppSec is disabled
+		return nil
+	}
+	return httpsec.MonitorParsedBody(ctx, body)
+}
+
+// MonitorParsedHTTPResponseBody runs the security monitoring rules on the given
+// *parsed* HTTP response body, before it gets written. The given context must be
+// the HTTP request context as returned by the Context() method of an HTTP request.
+// Calls to this function are ignored if AppSec is disabled or the given context is
+// incorrect. Monitoring response bodies is opt-in and allows detecting data leaks,
+// such as sensitive data returned to the client.
+// An error is returned when the response body triggers the blocking of the request.
+// In that case, the caller must immediately abort its execution and the request
+// handler's, without writing the response. The blocking response will be
+// automatically sent by the APM tracer middleware on use according to your
+// blocking configuration.
+// This function always returns nil when appsec is disabled.
+func MonitorParsedHTTPResponseBody(ctx context.Context, body interface{}) error {
+	if !appsec.Enabled() {
+		return nil
 	}
-	// bonus: use sync.Once to log a debug message once if AppSec is disabled
+	return httpsec.MonitorParsedResponseBody(ctx, body)
 }
 
 // SetUser wraps tracer.SetUser() and extends it with user blocking.
@@ -68,7 +99,8 @@ func SetUser(ctx context.Context, id string, opts ...tracer.UserMonitoringOption
 // is found in the given Go context and logs an error message instead.
 // Such events trigger the backend-side events monitoring, such as the Account
 // Take-Over (ATO) monitoring, ultimately blocking the IP address and/or user id
-// associated to them.
+// associated to them. Manually tracked events take precedence over the automated
+// user login events of the request.
 func TrackUserLoginSuccessEvent(ctx context.Context, uid string, md map[string]string, opts ...tracer.UserMonitoringOption) error {
 	span := getRootSpan(ctx)
 	if span == nil {
@@ -81,6 +113,9 @@ func TrackUserLoginSuccessEvent(ctx context.Context, uid string, md map[string]s
 		span.SetTag(tagPrefix+k, v)
 	}
 	span.SetTag(ext.SamplingPriority, ext.PriorityUserKeep)
+	if appsec.Enabled() {
+		sharedsec.MonitorUserLoginEvent(ctx, true, uid)
+	}
 	return SetUser(ctx, uid, opts...)
 }
 
@@ -93,7 +128,8 @@ func TrackUserLoginSuccessEvent(ctx context.Context, uid string, md map[string]s
 // is found in the given Go context and logs an error message instead.
 // Such events trigger the backend-side events monitoring, such as the Account
 // Take-Over (ATO) monitoring, ultimately blocking the IP address and/or user id
-// associated to them.
+// associated to them. Manually tracked events take precedence over the automated
+// user login events of the request.
 func TrackUserLoginFailureEvent(ctx context.Context, uid string, exists bool, md map[string]string) {
 	span := getRootSpan(ctx)
 	if span == nil {
@@ -108,6 +144,9 @@ func TrackUserLoginFailureEvent(ctx context.Context, uid string, exists bool, md
 		span.SetTag(tagPrefix+k, v)
 	}
 	span.SetTag(ext.SamplingPriority, ext.PriorityUserKeep)
+	if appsec.Enabled() {
+		sharedsec.MonitorUserLoginEvent(ctx, false, uid)
+	}
 }
 
 // TrackCustomEvent sets a custom event as service entry span tags. This span is
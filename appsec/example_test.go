@@ -61,6 +61,25 @@ func ExampleMonitorParsedHTTPBody_customContext() {
 	r.Start(":8080")
 }
 
+// Monitor HTTP request parsed body and block the request when required
+func ExampleMonitorParsedHTTPBodyWithError() {
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/body", func(w http.ResponseWriter, r *http.Request) {
+		// Use the SDK to monitor the request's parsed body
+		body, err := customBodyParser(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := appsec.MonitorParsedHTTPBodyWithError(r.Context(), body); err != nil {
+			// Abort the request handler: the request is blocked
+			return
+		}
+		w.Write([]byte("Body monitored using AppSec SDK\n"))
+	})
+	http.ListenAndServe(":8080", mux)
+}
+
 func userIDFromRequest(r *http.Request) string {
 	return r.Header.Get("user-id")
 }
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package gqlgen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+)
+
+func TestAppSec(t *testing.T) {
+	appsectest.Start(t, appsectest.GraphQLRules)
+
+	var resolved []string
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query {
+			user(name: String!): String!
+		}
+	`})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			// Simulate the execution of the generated code of the `user` field.
+			return func(ctx context.Context) *graphql.Response {
+				field := graphql.GetOperationContext(ctx).Operation.SelectionSet[0].(*ast.Field)
+				name := field.Arguments.ForName("name").Value.Raw
+				ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+					Object: "Query",
+					Field:  graphql.CollectedField{Field: field},
+					Args:   map[string]interface{}{"name": name},
+				})
+				res, err := graphql.GetOperationContext(ctx).ResolverMiddleware(ctx, func(ctx context.Context) (interface{}, error) {
+					resolved = append(resolved, name)
+					return json.Marshal(map[string]string{"user": name})
+				})
+				if err != nil {
+					return graphql.ErrorResponse(ctx, "%s", err)
+				}
+				return &graphql.Response{Data: res.([]byte)}
+			}
+		},
+		SchemaFunc: func() *ast.Schema {
+			return schema
+		},
+	})
+	srv.AddTransport(transport.POST{})
+	srv.Use(NewTracer())
+	server := httptest.NewServer(httptrace.WrapHandler(srv, "graphql-server", "/query"))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		name    string
+		user    string
+		blocked bool
+		event   string
+	}{
+		{name: "no-attack", user: "gopher"},
+		{name: "monitoring", user: "dd-scanner", event: "gql-002"},
+		{name: "blocking", user: "' UNION SELECT password FROM users --", blocked: true, event: "gql-001"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			resolved = nil
+
+			query, err := json.Marshal(map[string]string{"query": `{ user(name: "` + strings.ReplaceAll(tc.user, `"`, `\"`) + `") }`})
+			require.NoError(t, err)
+			res, err := server.Client().Post(server.URL, "application/json", strings.NewReader(string(query)))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			var body struct {
+				Data   json.RawMessage
+				Errors []struct{ Message string }
+			}
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+			// Blocking is reported as a GraphQL error instead of an HTTP blocking response.
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			var event interface{}
+			for _, s := range mt.FinishedSpans() {
+				if s.OperationName() == "http.request" {
+					event = s.Tag("_dd.appsec.json")
+				}
+			}
+			if tc.blocked {
+				require.Empty(t, resolved)
+				require.Len(t, body.Errors, 1)
+				require.Equal(t, "Request blocked", body.Errors[0].Message)
+			} else {
+				require.Equal(t, []string{tc.user}, resolved)
+				require.Empty(t, body.Errors)
+			}
+			if tc.event != "" {
+				require.Contains(t, event, tc.event)
+			} else {
+				require.Nil(t, event)
+			}
+		})
+	}
+}
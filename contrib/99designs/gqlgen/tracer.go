@@ -52,6 +52,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/graphqlsec"
 )
 
 const (
@@ -146,6 +148,34 @@ func (t *gqlTracer) InterceptResponse(ctx context.Context, next graphql.Response
 		createChildSpan(parsingOp, octx.Stats.Parsing.Start, octx.Stats.Parsing.End)
 		createChildSpan(validationOp, octx.Stats.Validation.Start, octx.Stats.Validation.End)
 	}
+	if appsec.Enabled() {
+		var args graphqlsec.RequestOperationArgs
+		if octx != nil {
+			args = graphqlsec.RequestOperationArgs{
+				Query:         octx.RawQuery,
+				OperationName: octx.OperationName,
+				Variables:     octx.Variables,
+			}
+		}
+		var op *graphqlsec.RequestOperation
+		ctx, op = graphqlsec.StartRequestOperation(ctx, args)
+		defer op.Finish()
+	}
+	return next(ctx)
+}
+
+// InterceptField monitors the arguments of the field resolvers when appsec is
+// enabled. When the request must be blocked, the resolver isn't executed and
+// the blocking error is returned as the GraphQL error of the field.
+func (t *gqlTracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	if !appsec.Enabled() {
+		return next(ctx)
+	}
+	if fctx := graphql.GetFieldContext(ctx); fctx != nil && fctx.Field.Field != nil && len(fctx.Args) > 0 {
+		if err := graphqlsec.ProtectResolver(ctx, fctx.Field.Name, fctx.Args); err != nil {
+			return nil, err
+		}
+	}
 	return next(ctx)
 }
 
@@ -153,4 +183,5 @@ func (t *gqlTracer) InterceptResponse(ctx context.Context, next graphql.Response
 var _ interface {
 	graphql.HandlerExtension
 	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
 } = &gqlTracer{}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package sarama
+
+import (
+	"context"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/Shopify/sarama"
+)
+
+type consumerGroupHandler struct {
+	sarama.ConsumerGroupHandler
+	cfg *config
+}
+
+// WrapConsumerGroupHandler wraps a sarama.ConsumerGroupHandler causing each
+// message received from the claims to be traced. The consume span of a message
+// is finished when the next message of the claim is pulled, or when the claim
+// ends. While a message is being handled, its consume span can be retrieved
+// using ContextFromMessage:
+//
+//	for msg := range claim.Messages() {
+//		ctx := saramatrace.ContextFromMessage(session, msg)
+//		...
+//	}
+//
+// The consumer group ID should be provided using the WithGroupID option, so that
+// the spans and metrics can be tagged with it.
+func WrapConsumerGroupHandler(handler sarama.ConsumerGroupHandler, opts ...Option) sarama.ConsumerGroupHandler {
+	cfg := new(config)
+	defaults(cfg)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	log.Debug("contrib/Shopify/sarama: Wrapping Consumer Group Handler: %#v", cfg)
+	return &consumerGroupHandler{
+		ConsumerGroupHandler: handler,
+		cfg:                  cfg,
+	}
+}
+
+// ConsumeClaim calls the wrapped handler's ConsumeClaim with a claim whose
+// messages are traced.
+func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	wrapped := &consumerGroupSession{
+		ConsumerGroupSession: session,
+		groupID:              h.cfg.groupID,
+		spans:                make(map[*sarama.ConsumerMessage]ddtrace.Span),
+	}
+	return h.ConsumerGroupHandler.ConsumeClaim(wrapped, wrapConsumerGroupClaim(h.cfg, wrapped, claim))
+}
+
+// consumerGroupSession is the session passed to the wrapped handler's
+// ConsumeClaim. A session is created per claim, holding the spans of the
+// messages of the claim which are not finished yet.
+type consumerGroupSession struct {
+	sarama.ConsumerGroupSession
+	groupID string
+
+	mu    sync.RWMutex // guards spans
+	spans map[*sarama.ConsumerMessage]ddtrace.Span
+}
+
+func (s *consumerGroupSession) startSpan(cfg *config, msg *sarama.ConsumerMessage) {
+	span := startConsumerSpan(cfg, msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spans[msg] = span
+}
+
+func (s *consumerGroupSession) finishSpan(msg *sarama.ConsumerMessage) {
+	s.mu.Lock()
+	span, ok := s.spans[msg]
+	delete(s.spans, msg)
+	s.mu.Unlock()
+	if ok {
+		span.Finish()
+	}
+}
+
+// ContextFromMessage returns the context of the session, containing the
+// consume span of msg when the session was given to a handler wrapped using
+// WrapConsumerGroupHandler, and msg is being handled.
+func ContextFromMessage(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) context.Context {
+	s, ok := session.(*consumerGroupSession)
+	if !ok {
+		return session.Context()
+	}
+	ctx := s.ConsumerGroupSession.Context()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if span, ok := s.spans[msg]; ok {
+		return tracer.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
+// MarkOffset calls the underlying session's MarkOffset and tracks the offset
+// committed by the consumer group, when its ID was given with WithGroupID.
+func (s *consumerGroupSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.ConsumerGroupSession.MarkOffset(topic, partition, offset, metadata)
+	s.trackCommitOffset(topic, partition, offset)
+}
+
+// MarkMessage calls the underlying session's MarkMessage and tracks the offset
+// committed by the consumer group, when its ID was given with WithGroupID.
+func (s *consumerGroupSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.ConsumerGroupSession.MarkMessage(msg, metadata)
+	// the marked offset is the one of the next message to consume
+	s.trackCommitOffset(msg.Topic, msg.Partition, msg.Offset+1)
+}
+
+func (s *consumerGroupSession) trackCommitOffset(topic string, partition int32, offset int64) {
+	if s.groupID == "" {
+		// the committed offset can't be attributed to a consumer group
+		return
+	}
+	tracer.TrackKafkaCommitOffset(s.groupID, topic, partition, offset)
+}
+
+type consumerGroupClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+// Messages returns the read channel for the messages of the claim.
+func (c *consumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func wrapConsumerGroupClaim(cfg *config, session *consumerGroupSession, claim sarama.ConsumerGroupClaim) sarama.ConsumerGroupClaim {
+	wrapped := &consumerGroupClaim{
+		ConsumerGroupClaim: claim,
+		messages:           make(chan *sarama.ConsumerMessage),
+	}
+	go func() {
+		defer close(wrapped.messages)
+		var prev *sarama.ConsumerMessage
+		defer func() {
+			// finish any remaining span
+			if prev != nil {
+				session.finishSpan(prev)
+			}
+		}()
+		done := session.ConsumerGroupSession.Context().Done()
+		for msg := range claim.Messages() {
+			session.startSpan(cfg, msg)
+			tracer.TrackKafkaHighWatermarkOffset(msg.Topic, msg.Partition, claim.HighWaterMarkOffset())
+			select {
+			case wrapped.messages <- msg:
+			case <-done:
+				// the session ended before the message could be handled
+				session.finishSpan(msg)
+				return
+			}
+			// if the next message was received, finish the previous span
+			if prev != nil {
+				session.finishSpan(prev)
+			}
+			prev = msg
+		}
+	}()
+	return wrapped
+}
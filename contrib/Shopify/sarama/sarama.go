@@ -7,8 +7,10 @@
 package sarama // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/Shopify/sarama"
 
 import (
+	"context"
 	"math"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -46,28 +48,8 @@ func WrapPartitionConsumer(pc sarama.PartitionConsumer, opts ...Option) sarama.P
 		var prev ddtrace.Span
 		for msg := range msgs {
 			// create the next span from the message
-			opts := []tracer.StartSpanOption{
-				tracer.ServiceName(cfg.consumerServiceName),
-				tracer.ResourceName("Consume Topic " + msg.Topic),
-				tracer.SpanType(ext.SpanTypeMessageConsumer),
-				tracer.Tag(ext.MessagingKafkaPartition, msg.Partition),
-				tracer.Tag("offset", msg.Offset),
-				tracer.Tag(ext.Component, "Shopify/sarama"),
-				tracer.Tag(ext.SpanKind, ext.SpanKindConsumer),
-				tracer.Tag(ext.MessagingSystem, "kafka"),
-				tracer.Measured(),
-			}
-			if !math.IsNaN(cfg.analyticsRate) {
-				opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
-			}
-			// kafka supports headers, so try to extract a span context
-			carrier := NewConsumerMessageCarrier(msg)
-			if spanctx, err := tracer.Extract(carrier); err == nil {
-				opts = append(opts, tracer.ChildOf(spanctx))
-			}
-			next := tracer.StartSpan("kafka.consume", opts...)
-			// reinject the span context so consumers can pick it up
-			tracer.Inject(next.Context(), carrier)
+			next := startConsumerSpan(cfg, msg)
+			tracer.TrackKafkaHighWatermarkOffset(msg.Topic, msg.Partition, pc.HighWaterMarkOffset())
 
 			wrapped.messages <- msg
 
@@ -86,6 +68,38 @@ func WrapPartitionConsumer(pc sarama.PartitionConsumer, opts ...Option) sarama.P
 	return wrapped
 }
 
+// startConsumerSpan starts the consume span of msg, as a child of the span
+// context propagated in its headers, and sets the data streams checkpoint.
+func startConsumerSpan(cfg *config, msg *sarama.ConsumerMessage) ddtrace.Span {
+	opts := []tracer.StartSpanOption{
+		tracer.ServiceName(cfg.consumerServiceName),
+		tracer.ResourceName("Consume Topic " + msg.Topic),
+		tracer.SpanType(ext.SpanTypeMessageConsumer),
+		tracer.Tag(ext.MessagingKafkaPartition, msg.Partition),
+		tracer.Tag("offset", msg.Offset),
+		tracer.Tag(ext.Component, "Shopify/sarama"),
+		tracer.Tag(ext.SpanKind, ext.SpanKindConsumer),
+		tracer.Tag(ext.MessagingSystem, "kafka"),
+		tracer.Measured(),
+	}
+	if cfg.groupID != "" {
+		opts = append(opts, tracer.Tag(ext.MessagingKafkaConsumerGroup, cfg.groupID))
+	}
+	if !math.IsNaN(cfg.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
+	}
+	// kafka supports headers, so try to extract a span context
+	carrier := NewConsumerMessageCarrier(msg)
+	if spanctx, err := tracer.Extract(carrier); err == nil {
+		opts = append(opts, tracer.ChildOf(spanctx))
+	}
+	span := tracer.StartSpan("kafka.consume", opts...)
+	// reinject the span context so consumers can pick it up
+	tracer.Inject(span.Context(), carrier)
+	setConsumeCheckpoint(cfg.groupID, msg)
+	return span
+}
+
 type consumer struct {
 	sarama.Consumer
 	opts []Option
@@ -120,7 +134,7 @@ type syncProducer struct {
 func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
 	span := startProducerSpan(p.cfg, p.version, msg)
 	partition, offset, err = p.SyncProducer.SendMessage(msg)
-	finishProducerSpan(span, partition, offset, err)
+	finishProducerSpan(span, msg.Topic, partition, offset, err)
 	return partition, offset, err
 }
 
@@ -134,7 +148,7 @@ func (p *syncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
 	}
 	err := p.SyncProducer.SendMessages(msgs)
 	for i, span := range spans {
-		finishProducerSpan(span, msgs[i].Partition, msgs[i].Offset, err)
+		finishProducerSpan(span, msgs[i].Topic, msgs[i].Partition, msgs[i].Offset, err)
 	}
 	return err
 }
@@ -232,7 +246,7 @@ func WrapAsyncProducer(saramaConfig *sarama.Config, p sarama.AsyncProducer, opts
 					spanID := spanctx.SpanID()
 					if span, ok := spans[spanID]; ok {
 						delete(spans, spanID)
-						finishProducerSpan(span, msg.Partition, msg.Offset, nil)
+						finishProducerSpan(span, msg.Topic, msg.Partition, msg.Offset, nil)
 					}
 				}
 				wrapped.successes <- msg
@@ -277,13 +291,45 @@ func startProducerSpan(cfg *config, version sarama.KafkaVersion, msg *sarama.Pro
 		// re-inject the span context so consumers can pick it up
 		tracer.Inject(span.Context(), carrier)
 	}
+	setProduceCheckpoint(version, msg)
 	return span
 }
 
-func finishProducerSpan(span ddtrace.Span, partition int32, offset int64, err error) {
+// setConsumeCheckpoint sets a data streams consume checkpoint on the pathway
+// propagated in the message headers, and re-injects the resulting pathway so
+// that the messages produced from this one continue it.
+func setConsumeCheckpoint(groupID string, msg *sarama.ConsumerMessage) {
+	edges := []string{"direction:in", "topic:" + msg.Topic, "type:kafka"}
+	if groupID != "" {
+		edges = append(edges, "group:"+groupID)
+	}
+	carrier := NewConsumerMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromBase64Carrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToBase64Carrier(ctx, carrier)
+}
+
+// setProduceCheckpoint sets a data streams produce checkpoint on the pathway
+// found in the message headers, if any, and injects the resulting pathway.
+func setProduceCheckpoint(version sarama.KafkaVersion, msg *sarama.ProducerMessage) {
+	edges := []string{"direction:out", "topic:" + msg.Topic, "type:kafka"}
+	carrier := NewProducerMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromBase64Carrier(context.Background(), carrier), edges...)
+	if !ok || !version.IsAtLeast(sarama.V0_11_0_0) {
+		return
+	}
+	datastreams.InjectToBase64Carrier(ctx, carrier)
+}
+
+func finishProducerSpan(span ddtrace.Span, topic string, partition int32, offset int64, err error) {
 	span.SetTag(ext.MessagingKafkaPartition, partition)
 	span.SetTag("offset", offset)
 	span.Finish(tracer.WithError(err))
+	if err == nil {
+		tracer.TrackKafkaProduceOffset(topic, partition, offset)
+	}
 }
 
 func getSpanContext(msg *sarama.ProducerMessage) (ddtrace.SpanContext, bool) {
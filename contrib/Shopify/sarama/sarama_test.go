@@ -7,15 +7,21 @@ package sarama
 
 import (
 	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 
 	"github.com/Shopify/sarama"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConsumer(t *testing.T) {
@@ -322,3 +328,218 @@ func waitForSpans(mt mocktracer.Tracer, sz int, maxWait time.Duration) {
 		time.Sleep(time.Millisecond * 100)
 	}
 }
+
+func TestDataStreams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte(`{"endpoints":["/v0.4/traces","/v0.1/pipeline_stats"]}`))
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+	tracer.Start(tracer.WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	pathway := func(headers []sarama.RecordHeader) datastreams.Pathway {
+		for _, h := range headers {
+			if string(h.Key) == datastreams.PropagationKeyBase64 {
+				p, err := datastreams.DecodeBase64(string(h.Value))
+				require.NoError(t, err)
+				return p
+			}
+		}
+		t.Fatal("pathway context not found in the message headers")
+		return datastreams.Pathway{}
+	}
+
+	produced := &sarama.ProducerMessage{Topic: "test-topic", Value: sarama.StringEncoder("hello")}
+	setProduceCheckpoint(sarama.V0_11_0_0, produced)
+	producer := pathway(produced.Headers)
+
+	consumed := &sarama.ConsumerMessage{Topic: "test-topic", Value: []byte("hello")}
+	for i := range produced.Headers {
+		consumed.Headers = append(consumed.Headers, &produced.Headers[i])
+	}
+	setConsumeCheckpoint("test-group", consumed)
+	var headers []sarama.RecordHeader
+	for _, h := range consumed.Headers {
+		headers = append(headers, *h)
+	}
+	consumer := pathway(headers)
+	assert.NotEqual(t, producer.GetHash(), consumer.GetHash())
+	assert.True(t, producer.PathwayStart().Equal(consumer.PathwayStart()))
+
+	t.Run("headers-unsupported", func(t *testing.T) {
+		msg := &sarama.ProducerMessage{Topic: "test-topic", Value: sarama.StringEncoder("hello")}
+		setProduceCheckpoint(sarama.V0_10_2_0, msg)
+		assert.Empty(t, msg.Headers)
+	})
+}
+
+func TestKafkaOffsets(t *testing.T) {
+	statsd, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer statsd.Close()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	tracer.Start(
+		tracer.WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")),
+		tracer.WithDogstatsdAddress(statsd.LocalAddr().String()),
+		tracer.WithLogStartup(false),
+	)
+	defer tracer.Stop()
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	prodSuccess := new(sarama.ProduceResponse)
+	prodSuccess.AddTopicPartition("test-topic", 0, sarama.ErrNoError)
+	prodSuccess.Blocks["test-topic"][0].Offset = 42
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("test-topic", 0, sarama.OffsetOldest, 0).
+			SetOffset("test-topic", 0, sarama.OffsetNewest, 1),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).
+			SetMessage("test-topic", 0, 0, sarama.StringEncoder("hello")).
+			SetHighWaterMark("test-topic", 0, 7),
+		"ProduceRequest": sarama.NewMockWrapper(prodSuccess),
+	})
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.MinVersion
+	cfg.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer([]string{broker.Addr()}, cfg)
+	require.NoError(t, err)
+	producer = WrapSyncProducer(cfg, producer)
+	_, _, err = producer.SendMessage(&sarama.ProducerMessage{Topic: "test-topic", Value: sarama.StringEncoder("test")})
+	require.NoError(t, err)
+	producer.Close()
+
+	consumer, err := sarama.NewConsumer([]string{broker.Addr()}, cfg)
+	require.NoError(t, err)
+	consumer = WrapConsumer(consumer)
+	partitionConsumer, err := consumer.ConsumePartition("test-topic", 0, 0)
+	require.NoError(t, err)
+	<-partitionConsumer.Messages()
+	partitionConsumer.Close()
+	consumer.Close()
+
+	// only the offsets marked by a consumer group whose ID is known are tracked
+	for _, groupID := range []string{"", "test-group"} {
+		session := &consumerGroupSession{
+			ConsumerGroupSession: &testConsumerGroupSession{ctx: context.Background()},
+			groupID:              groupID,
+		}
+		session.MarkOffset("test-topic", 0, 1, "")
+	}
+
+	// the latest offsets are reported when the tracer stops
+	tracer.Stop()
+	var metrics strings.Builder
+	buf := make([]byte, 65536)
+	statsd.SetReadDeadline(time.Now().Add(time.Second))
+	for !strings.Contains(metrics.String(), "kafka.high_watermark_offset") || !strings.Contains(metrics.String(), "kafka.produce_offset") || !strings.Contains(metrics.String(), "kafka.commit_offset") {
+		n, _, err := statsd.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		metrics.Write(buf[:n])
+		metrics.WriteByte('\n')
+	}
+	assert.Regexp(t, `datadog\.tracer\.kafka\.produce_offset:42\|g\|#[^\n]*topic:test-topic,partition:0`, metrics.String())
+	assert.Regexp(t, `datadog\.tracer\.kafka\.high_watermark_offset:7\|g\|#[^\n]*topic:test-topic,partition:0`, metrics.String())
+	assert.Regexp(t, `datadog\.tracer\.kafka\.commit_offset:1\|g\|#[^\n]*topic:test-topic,partition:0,consumer_group:test-group`, metrics.String())
+	assert.NotRegexp(t, `consumer_group:(,|\n|$)`, metrics.String())
+}
+
+type testConsumerGroupSession struct {
+	sarama.ConsumerGroupSession
+	ctx context.Context
+}
+
+func (s *testConsumerGroupSession) Context() context.Context { return s.ctx }
+
+func (s *testConsumerGroupSession) MarkMessage(*sarama.ConsumerMessage, string) {}
+
+func (s *testConsumerGroupSession) MarkOffset(string, int32, int64, string) {}
+
+type testConsumerGroupClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *testConsumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func (c *testConsumerGroupClaim) HighWaterMarkOffset() int64 { return 2 }
+
+type testConsumerGroupHandler struct {
+	consume func(sarama.ConsumerGroupSession, sarama.ConsumerGroupClaim) error
+}
+
+func (testConsumerGroupHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (testConsumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h testConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	return h.consume(session, claim)
+}
+
+func TestConsumerGroupHandler(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	// the messages are produced by a traced producer
+	producerSpan := tracer.StartSpan("kafka.produce")
+	msg1 := &sarama.ConsumerMessage{Topic: "test-topic", Partition: 1, Offset: 0}
+	err := tracer.Inject(producerSpan.Context(), NewConsumerMessageCarrier(msg1))
+	require.NoError(t, err)
+	producerSpan.Finish()
+	msg2 := &sarama.ConsumerMessage{Topic: "test-topic", Partition: 1, Offset: 1}
+
+	claim := &testConsumerGroupClaim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	claim.messages <- msg1
+	claim.messages <- msg2
+	close(claim.messages)
+	session := &testConsumerGroupSession{ctx: context.Background()}
+
+	var received []*sarama.ConsumerMessage
+	handler := WrapConsumerGroupHandler(testConsumerGroupHandler{
+		consume: func(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+			for msg := range claim.Messages() {
+				span, ok := tracer.SpanFromContext(ContextFromMessage(session, msg))
+				require.True(t, ok, "no consume span in the message context")
+				assert.Equal(t, "kafka.consume", span.(mocktracer.Span).OperationName())
+				assert.Equal(t, msg.Offset, span.(mocktracer.Span).Tag("offset"))
+				// the span is only finished once the next message is pulled
+				for _, s := range mt.FinishedSpans() {
+					assert.NotEqual(t, span.Context().SpanID(), s.SpanID(), "span finished while handling the message")
+				}
+				received = append(received, msg)
+				session.MarkMessage(msg, "")
+			}
+			return nil
+		},
+	}, WithGroupID("test-group"))
+	assert.NoError(t, handler.ConsumeClaim(session, claim))
+	assert.Equal(t, []*sarama.ConsumerMessage{msg1, msg2}, received)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 3)
+	for i, s := range spans[1:] {
+		assert.Equal(t, "kafka.consume", s.OperationName())
+		assert.Equal(t, "Consume Topic test-topic", s.Tag(ext.ResourceName))
+		assert.Equal(t, int32(1), s.Tag(ext.MessagingKafkaPartition))
+		assert.Equal(t, int64(i), s.Tag("offset"))
+		assert.Equal(t, "test-group", s.Tag(ext.MessagingKafkaConsumerGroup))
+		assert.Equal(t, ext.SpanKindConsumer, s.Tag(ext.SpanKind))
+		assert.Equal(t, "kafka", s.Tag(ext.MessagingSystem))
+	}
+	assert.Equal(t, spans[0].SpanID(), spans[1].ParentID(),
+		"the consume span should be a child of the producer span")
+	assert.Zero(t, spans[2].ParentID())
+	spanctx, err := tracer.Extract(NewConsumerMessageCarrier(msg2))
+	assert.NoError(t, err)
+	assert.Equal(t, spans[2].SpanID(), spanctx.SpanID(),
+		"span context should be injected into the consumer message headers")
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"sync"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -55,6 +56,11 @@ func Publish(ctx context.Context, t *pubsub.Topic, msg *pubsub.Message, opts ...
 	if err := tracer.Inject(span.Context(), tracer.TextMapCarrier(msg.Attributes)); err != nil {
 		log.Debug("contrib/cloud.google.com/go/pubsub.v1/: failed injecting tracing attributes: %v", err)
 	}
+	// continue the data streams pathway found in ctx, if any, such as the
+	// one of a message received by a wrapped receive handler
+	if dsCtx, ok := tracer.SetDataStreamsCheckpoint(ctx, "direction:out", "topic:"+t.ID(), "type:google-pubsub"); ok {
+		datastreams.InjectToBase64Carrier(dsCtx, tracer.TextMapCarrier(msg.Attributes))
+	}
 	span.SetTag("num_attributes", len(msg.Attributes))
 	return &PublishResult{
 		PublishResult: t.Publish(ctx, msg),
@@ -82,7 +88,9 @@ func (r *PublishResult) Get(ctx context.Context) (string, error) {
 
 // WrapReceiveHandler returns a receive handler that wraps the supplied handler,
 // extracts any tracing metadata attached to the received message, and starts a
-// receive span.
+// receive span. The data streams pathway of the message is added to the context
+// passed to the handler, so that the messages it publishes using Publish continue
+// the pathway.
 func WrapReceiveHandler(s *pubsub.Subscription, f func(context.Context, *pubsub.Message), opts ...Option) func(context.Context, *pubsub.Message) {
 	var cfg config
 	for _, opt := range opts {
@@ -111,6 +119,10 @@ func WrapReceiveHandler(s *pubsub.Subscription, f func(context.Context, *pubsub.
 			opts = append(opts, tracer.Measured())
 		}
 		span, ctx := tracer.StartSpanFromContext(ctx, "pubsub.receive", opts...)
+		ctx, _ = tracer.SetDataStreamsCheckpoint(
+			datastreams.ExtractFromBase64Carrier(ctx, tracer.TextMapCarrier(msg.Attributes)),
+			"direction:in", "subscription:"+s.ID(), "type:google-pubsub",
+		)
 		if msg.DeliveryAttempt != nil {
 			span.SetTag("delivery_attempt", *msg.DeliveryAttempt)
 		}
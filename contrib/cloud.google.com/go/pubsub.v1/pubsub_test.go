@@ -7,12 +7,16 @@ package pubsub
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/pubsub/pstest"
@@ -236,6 +240,43 @@ func TestPropagationNoPubsliherSpan(t *testing.T) {
 	}, spans[0].Tags())
 }
 
+func TestDataStreams(t *testing.T) {
+	assert := assert.New(t)
+	ctx, topic, sub, mt, cleanup := setup(t)
+	defer cleanup()
+	mt.Stop()
+
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte(`{"endpoints":["/v0.4/traces","/v0.1/pipeline_stats"]}`))
+		}
+	}))
+	defer agent.Close()
+	t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+	tracer.Start(tracer.WithAgentAddr(strings.TrimPrefix(agent.URL, "http://")), tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	// Publisher
+	msg := &pubsub.Message{Data: []byte("hello")}
+	_, err := Publish(ctx, topic, msg).Get(ctx)
+	assert.NoError(err)
+	published, err := datastreams.DecodeBase64(msg.Attributes[datastreams.PropagationKeyBase64])
+	assert.NoError(err)
+
+	// Subscriber
+	var called bool
+	err = sub.Receive(ctx, WrapReceiveHandler(sub, func(ctx context.Context, msg *pubsub.Message) {
+		called = true
+		msg.Ack()
+		received, ok := datastreams.PathwayFromContext(ctx)
+		assert.True(ok, "no pathway in the handler context")
+		assert.NotEqual(published.GetHash(), received.GetHash())
+		assert.True(published.PathwayStart().Equal(received.PathwayStart()))
+	}))
+	assert.NoError(err)
+	assert.True(called, "callback not called")
+}
+
 func setup(t *testing.T) (context.Context, *pubsub.Topic, *pubsub.Subscription, mocktracer.Tracer, func()) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
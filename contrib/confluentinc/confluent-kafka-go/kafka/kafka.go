@@ -7,9 +7,11 @@
 package kafka // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/confluentinc/confluent-kafka-go/kafka"
 
 import (
+	"context"
 	"math"
 	"time"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -24,6 +26,7 @@ func NewConsumer(conf *kafka.ConfigMap, opts ...Option) (*Consumer, error) {
 	if err != nil {
 		return nil, err
 	}
+	opts = append([]Option{WithConfig(conf)}, opts...)
 	return WrapConsumer(c, opts...), nil
 }
 
@@ -45,6 +48,7 @@ type Consumer struct {
 }
 
 // WrapConsumer wraps a kafka.Consumer so that any consumed events are traced.
+// The consumer group is only known when its config is given with WithConfig.
 func WrapConsumer(c *kafka.Consumer, opts ...Option) *Consumer {
 	wrapped := &Consumer{
 		Consumer: c,
@@ -67,9 +71,12 @@ func (c *Consumer) traceEventsChannel(in chan kafka.Event) chan kafka.Event {
 		for evt := range in {
 			var next ddtrace.Span
 
-			// only trace messages
-			if msg, ok := evt.(*kafka.Message); ok {
-				next = c.startSpan(msg)
+			switch e := evt.(type) {
+			case *kafka.Message:
+				next = c.startSpan(e)
+			case kafka.OffsetsCommitted:
+				// the offsets committed in the background when enable.auto.commit is set
+				c.trackCommitOffsets(e.Offsets, e.Error)
 			}
 
 			out <- evt
@@ -117,6 +124,11 @@ func (c *Consumer) startSpan(msg *kafka.Message) ddtrace.Span {
 	span, _ := tracer.StartSpanFromContext(c.cfg.ctx, "kafka.consume", opts...)
 	// reinject the span context so consumers can pick it up
 	tracer.Inject(span.Context(), carrier)
+	setConsumeCheckpoint(c.cfg.groupID, msg)
+	// the watermarks are cached by the client, this doesn't query the broker
+	if _, high, err := c.Consumer.GetWatermarkOffsets(*msg.TopicPartition.Topic, msg.TopicPartition.Partition); err == nil && high >= 0 {
+		tracer.TrackKafkaHighWatermarkOffset(*msg.TopicPartition.Topic, msg.TopicPartition.Partition, high)
+	}
 	return span
 }
 
@@ -135,21 +147,24 @@ func (c *Consumer) Close() error {
 }
 
 // Events returns the kafka Events channel (if enabled). Message events will be
-// traced.
+// traced, and the offsets reported by OffsetsCommitted events tracked.
 func (c *Consumer) Events() chan kafka.Event {
 	return c.events
 }
 
 // Poll polls the consumer for messages or events. Message will be
-// traced.
+// traced, and the offsets reported by OffsetsCommitted events tracked.
 func (c *Consumer) Poll(timeoutMS int) (event kafka.Event) {
 	if c.prev != nil {
 		c.prev.Finish()
 		c.prev = nil
 	}
 	evt := c.Consumer.Poll(timeoutMS)
-	if msg, ok := evt.(*kafka.Message); ok {
-		c.prev = c.startSpan(msg)
+	switch e := evt.(type) {
+	case *kafka.Message:
+		c.prev = c.startSpan(e)
+	case kafka.OffsetsCommitted:
+		c.trackCommitOffsets(e.Offsets, e.Error)
 	}
 	return evt
 }
@@ -168,11 +183,48 @@ func (c *Consumer) ReadMessage(timeout time.Duration) (*kafka.Message, error) {
 	return msg, nil
 }
 
+// Commit calls the underlying Consumer.Commit and tracks the committed offsets.
+func (c *Consumer) Commit() ([]kafka.TopicPartition, error) {
+	tps, err := c.Consumer.Commit()
+	c.trackCommitOffsets(tps, err)
+	return tps, err
+}
+
+// CommitMessage calls the underlying Consumer.CommitMessage and tracks the
+// committed offsets.
+func (c *Consumer) CommitMessage(msg *kafka.Message) ([]kafka.TopicPartition, error) {
+	tps, err := c.Consumer.CommitMessage(msg)
+	c.trackCommitOffsets(tps, err)
+	return tps, err
+}
+
+// CommitOffsets calls the underlying Consumer.CommitOffsets and tracks the
+// committed offsets.
+func (c *Consumer) CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	tps, err := c.Consumer.CommitOffsets(offsets)
+	c.trackCommitOffsets(tps, err)
+	return tps, err
+}
+
+func (c *Consumer) trackCommitOffsets(offsets []kafka.TopicPartition, err error) {
+	if err != nil || c.cfg.groupID == "" {
+		// the committed offsets can't be attributed to a consumer group
+		return
+	}
+	for _, tp := range offsets {
+		if tp.Error != nil || tp.Topic == nil || tp.Offset < 0 {
+			continue
+		}
+		tracer.TrackKafkaCommitOffset(c.cfg.groupID, *tp.Topic, tp.Partition, int64(tp.Offset))
+	}
+}
+
 // A Producer wraps a kafka.Producer.
 type Producer struct {
 	*kafka.Producer
 	cfg            *config
 	produceChannel chan *kafka.Message
+	events         chan kafka.Event
 }
 
 // WrapProducer wraps a kafka.Producer so requests are traced.
@@ -183,9 +235,31 @@ func WrapProducer(p *kafka.Producer, opts ...Option) *Producer {
 	}
 	log.Debug("contrib/confluentinc/confluent-kafka-go/kafka: Wrapping Producer: %#v", wrapped.cfg)
 	wrapped.produceChannel = wrapped.traceProduceChannel(p.ProduceChannel())
+	wrapped.events = wrapped.traceEventsChannel(p.Events())
 	return wrapped
 }
 
+func (p *Producer) traceEventsChannel(in chan kafka.Event) chan kafka.Event {
+	if in == nil {
+		return nil
+	}
+
+	out := make(chan kafka.Event, 1)
+	go func() {
+		defer close(out)
+		for evt := range in {
+			// the delivery reports of the messages produced without a
+			// delivery channel
+			if msg, ok := evt.(*kafka.Message); ok {
+				trackProduceOffset(msg)
+			}
+			out <- evt
+		}
+	}()
+
+	return out
+}
+
 func (p *Producer) traceProduceChannel(out chan *kafka.Message) chan *kafka.Message {
 	if out == nil {
 		return out
@@ -225,9 +299,38 @@ func (p *Producer) startSpan(msg *kafka.Message) ddtrace.Span {
 	span, _ := tracer.StartSpanFromContext(p.cfg.ctx, "kafka.produce", opts...)
 	// inject the span context so consumers can pick it up
 	tracer.Inject(span.Context(), carrier)
+	setProduceCheckpoint(msg)
 	return span
 }
 
+// setConsumeCheckpoint sets a data streams consume checkpoint on the pathway
+// propagated in the message headers, and re-injects the resulting pathway so
+// that the messages produced from this one continue it.
+func setConsumeCheckpoint(groupID string, msg *kafka.Message) {
+	edges := []string{"direction:in", "topic:" + *msg.TopicPartition.Topic, "type:kafka"}
+	if groupID != "" {
+		edges = append(edges, "group:"+groupID)
+	}
+	carrier := NewMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromBase64Carrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToBase64Carrier(ctx, carrier)
+}
+
+// setProduceCheckpoint sets a data streams produce checkpoint on the pathway
+// found in the message headers, if any, and injects the resulting pathway.
+func setProduceCheckpoint(msg *kafka.Message) {
+	edges := []string{"direction:out", "topic:" + *msg.TopicPartition.Topic, "type:kafka"}
+	carrier := NewMessageCarrier(msg)
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromBase64Carrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToBase64Carrier(ctx, carrier)
+}
+
 // Close calls the underlying Producer.Close and also closes the internal
 // wrapping producer channel.
 func (p *Producer) Close() {
@@ -250,6 +353,7 @@ func (p *Producer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) er
 			if msg, ok := evt.(*kafka.Message); ok {
 				// delivery errors are returned via TopicPartition.Error
 				err = msg.TopicPartition.Error
+				trackProduceOffset(msg)
 			}
 			span.Finish(tracer.WithError(err))
 			oldDeliveryChan <- evt
@@ -270,3 +374,18 @@ func (p *Producer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) er
 func (p *Producer) ProduceChannel() chan *kafka.Message {
 	return p.produceChannel
 }
+
+// Events returns the kafka Events channel of the underlying producer. The
+// offsets of the messages successfully delivered are tracked.
+func (p *Producer) Events() chan kafka.Event {
+	return p.events
+}
+
+// trackProduceOffset tracks the offset of the given message, as reported by
+// its delivery report, unless it failed to be delivered.
+func trackProduceOffset(msg *kafka.Message) {
+	if msg.TopicPartition.Error != nil || msg.TopicPartition.Topic == nil || msg.TopicPartition.Offset < 0 {
+		return
+	}
+	tracer.TrackKafkaProduceOffset(*msg.TopicPartition.Topic, msg.TopicPartition.Partition, int64(msg.TopicPartition.Offset))
+}
@@ -8,17 +8,23 @@ package kafka
 import (
 	"context"
 	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -332,3 +338,120 @@ func TestCustomTags(t *testing.T) {
 	assert.Equal(t, "bar", s.Tag("foo"))
 	assert.Equal(t, []byte("key1"), s.Tag("key"))
 }
+
+func TestDataStreams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte(`{"endpoints":["/v0.4/traces","/v0.1/pipeline_stats"]}`))
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+	tracer.Start(tracer.WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	c, err := NewConsumer(&kafka.ConfigMap{
+		"group.id":           testGroupID,
+		"socket.timeout.ms":  10,
+		"session.timeout.ms": 10,
+	})
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, testGroupID, c.cfg.groupID)
+
+	pathway := func(msg *kafka.Message) datastreams.Pathway {
+		for _, h := range msg.Headers {
+			if h.Key == datastreams.PropagationKeyBase64 {
+				p, err := datastreams.DecodeBase64(string(h.Value))
+				require.NoError(t, err)
+				return p
+			}
+		}
+		t.Fatal("pathway context not found in the message headers")
+		return datastreams.Pathway{}
+	}
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &testTopic},
+		Value:          []byte("value"),
+	}
+	setProduceCheckpoint(msg)
+	producer := pathway(msg)
+	setConsumeCheckpoint(c.cfg.groupID, msg)
+	consumer := pathway(msg)
+	assert.NotEqual(t, producer.GetHash(), consumer.GetHash())
+	assert.True(t, producer.PathwayStart().Equal(consumer.PathwayStart()))
+}
+
+func TestWrapConsumerGroup(t *testing.T) {
+	conf := &kafka.ConfigMap{
+		"group.id":           testGroupID,
+		"socket.timeout.ms":  10,
+		"session.timeout.ms": 10,
+	}
+	c, err := kafka.NewConsumer(conf)
+	require.NoError(t, err)
+	defer c.Close()
+
+	// the group is unknown unless the config is given
+	assert.Empty(t, WrapConsumer(c).cfg.groupID)
+	assert.Equal(t, testGroupID, WrapConsumer(c, WithConfig(conf)).cfg.groupID)
+}
+
+func TestKafkaOffsets(t *testing.T) {
+	statsd, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer statsd.Close()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	tracer.Start(
+		tracer.WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")),
+		tracer.WithDogstatsdAddress(statsd.LocalAddr().String()),
+		tracer.WithLogStartup(false),
+	)
+	defer tracer.Stop()
+
+	c, err := NewConsumer(&kafka.ConfigMap{
+		"go.events.channel.enable": true,
+		"group.id":                 testGroupID,
+		"socket.timeout.ms":        10,
+		"session.timeout.ms":       10,
+	})
+	require.NoError(t, err)
+	// the offsets committed in the background are reported as events
+	go func() {
+		c.Consumer.Events() <- kafka.OffsetsCommitted{Offsets: []kafka.TopicPartition{
+			{Topic: &testTopic, Partition: 1, Offset: 5},
+		}}
+	}()
+	_, ok := (<-c.Events()).(kafka.OffsetsCommitted)
+	assert.True(t, ok)
+	c.Close()
+
+	p, err := NewProducer(&kafka.ConfigMap{"socket.timeout.ms": 10})
+	require.NoError(t, err)
+	// the delivery reports of the messages produced without a delivery
+	// channel are sent to the events channel
+	go func() {
+		p.Producer.Events() <- &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &testTopic, Partition: 0, Offset: 42}}
+	}()
+	_, ok = (<-p.Events()).(*kafka.Message)
+	assert.True(t, ok)
+	p.Close()
+
+	// the latest offsets are reported when the tracer stops
+	tracer.Stop()
+	var metrics strings.Builder
+	buf := make([]byte, 65536)
+	statsd.SetReadDeadline(time.Now().Add(time.Second))
+	for !strings.Contains(metrics.String(), "kafka.commit_offset") || !strings.Contains(metrics.String(), "kafka.produce_offset") {
+		n, _, err := statsd.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		metrics.Write(buf[:n])
+		metrics.WriteByte('\n')
+	}
+	assert.Regexp(t, `datadog\.tracer\.kafka\.produce_offset:42\|g\|#[^\n]*topic:gotest,partition:0`, metrics.String())
+	assert.Regexp(t, `datadog\.tracer\.kafka\.commit_offset:5\|g\|#[^\n]*topic:gotest,partition:1,consumer_group:gotest`, metrics.String())
+}
@@ -21,6 +21,7 @@ type config struct {
 	producerServiceName string
 	analyticsRate       float64
 	tagFns              map[string]func(msg *kafka.Message) interface{}
+	groupID             string
 }
 
 // An Option customizes the config.
@@ -96,3 +97,14 @@ func WithCustomTag(tag string, tagFn func(msg *kafka.Message) interface{}) Optio
 		cfg.tagFns[tag] = tagFn
 	}
 }
+
+// WithConfig extracts the consumer group from the group.id of the given config,
+// so that it is reported by the consumers wrapped with WrapConsumer. Consumers
+// created with NewConsumer use their own config by default.
+func WithConfig(cg *kafka.ConfigMap) Option {
+	return func(cfg *config) {
+		if groupID, err := cg.Get("group.id", ""); err == nil {
+			cfg.groupID, _ = groupID.(string)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"testing"
 
+	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/stretchr/testify/assert"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
@@ -44,3 +45,15 @@ func TestAnalyticsSettings(t *testing.T) {
 		assert.Equal(t, 0.2, cfg.analyticsRate)
 	})
 }
+
+func TestWithConfig(t *testing.T) {
+	t.Run("group", func(t *testing.T) {
+		cfg := newConfig(WithConfig(&kafka.ConfigMap{"group.id": "my-group"}))
+		assert.Equal(t, "my-group", cfg.groupID)
+	})
+
+	t.Run("no-group", func(t *testing.T) {
+		cfg := newConfig(WithConfig(&kafka.ConfigMap{"bootstrap.servers": "localhost:9092"}))
+		assert.Empty(t, cfg.groupID)
+	})
+}
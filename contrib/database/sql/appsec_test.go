@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package sql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql/internal"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+)
+
+// sqliRules blocks the requests executing SQL statements with a tautology
+const sqliRules = `{
+	"version": "2.2",
+	"metadata": {"rules_version": "1.0.0"},
+	"rules": [{
+		"id": "sqli-001",
+		"name": "SQL injection",
+		"tags": {"type": "sql_injection", "category": "exploit"},
+		"conditions": [{
+			"operator": "match_regex",
+			"parameters": {"inputs": [{"address": "server.db.statement"}], "regex": "(?i)or\\s+1\\s*=\\s*1"}
+		}],
+		"transformers": [],
+		"on_match": ["block"]
+	}]
+}`
+
+func TestAppSec(t *testing.T) {
+	rules := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(rules, []byte(sqliRules), 0644))
+	t.Setenv("DD_APPSEC_RULES", rules)
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	d := &internal.MockDriver{}
+	Register("appsec-test", d)
+	defer unregister("appsec-test")
+	db, err := Open("appsec-test", "dn")
+	require.NoError(t, err)
+	defer db.Close()
+
+	mux := httptrace.NewServeMux()
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		query := "SELECT * FROM users WHERE name = '" + r.URL.Query().Get("name") + "'"
+		if _, err := db.QueryContext(r.Context(), query); err != nil {
+			return
+		}
+		w.Write([]byte("Hello World!\n"))
+	})
+	mux.HandleFunc("/stmt", func(w http.ResponseWriter, r *http.Request) {
+		stmt, err := db.PrepareContext(r.Context(), "DELETE FROM users WHERE name = '"+r.URL.Query().Get("name")+"'")
+		if err != nil {
+			return
+		}
+		defer stmt.Close()
+		if _, err := stmt.ExecContext(r.Context()); err != nil {
+			return
+		}
+		w.Write([]byte("Hello World!\n"))
+	})
+	mux.HandleFunc("/hardcoded", func(w http.ResponseWriter, r *http.Request) {
+		// the tautology is not controlled by the request
+		if _, err := db.QueryContext(r.Context(), "SELECT * FROM users WHERE deleted_at IS NULL OR 1=1"); err != nil {
+			return
+		}
+		w.Write([]byte("Hello World!\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name    string
+		url     string
+		blocked bool
+	}{
+		{name: "query/no-block", url: "/query?name=gopher"},
+		{name: "query/block", url: "/query?name=%27%20or%201%3D1%20--", blocked: true},
+		{name: "stmt/no-block", url: "/stmt?name=gopher"},
+		{name: "stmt/block", url: "/stmt?name=%27%20or%201%3D1%20--", blocked: true},
+		{name: "hardcoded/no-block", url: "/hardcoded?name=gopher"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			d.Executed = nil
+
+			res, err := srv.Client().Get(srv.URL + tc.url)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			var event interface{}
+			for _, s := range mt.FinishedSpans() {
+				if s.OperationName() == "http.request" {
+					event = s.Tag("_dd.appsec.json")
+				}
+			}
+			if tc.blocked {
+				require.Equal(t, http.StatusForbidden, res.StatusCode)
+				require.Empty(t, d.Executed)
+				require.Contains(t, event, "sqli-001")
+			} else {
+				require.Equal(t, http.StatusOK, res.StatusCode)
+				require.Len(t, d.Executed, 1)
+				require.Nil(t, event)
+			}
+		})
+	}
+}
@@ -10,11 +10,14 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"math"
+	"sync/atomic"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/sqlsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
@@ -32,10 +35,14 @@ const (
 	queryTypeClose              = "Close"
 	queryTypeCommit             = "Commit"
 	queryTypeRollback           = "Rollback"
+	queryTypeRows               = "Rows"
 )
 
 const (
 	keyDBMTraceInjected = "_dd.dbm_trace_injected"
+	keyPoolWait         = "sql.pool.wait_ns"
+	keyRowsAffected     = "sql.rows_affected"
+	keyRowsReturned     = "sql.rows_returned"
 )
 
 // TracedConn holds a traced connection with tracing parameters.
@@ -114,9 +121,14 @@ func (tc *TracedConn) PrepareContext(ctx context.Context, query string) (stmt dr
 func (tc *TracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (r driver.Result, err error) {
 	start := time.Now()
 	if execContext, ok := tc.Conn.(driver.ExecerContext); ok {
+		if err := tc.protect(ctx, query); err != nil {
+			tc.tryTrace(ctx, queryTypeExec, query, start, err)
+			return nil, err
+		}
 		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.dbmPropagationMode)
 		r, err := execContext.ExecContext(ctx, cquery, args)
-		tc.tryTrace(ctx, queryTypeExec, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))...)
+		opts := append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))
+		tc.tryTrace(ctx, queryTypeExec, query, start, err, append(opts, withRowsAffectedTag(r, err)...)...)
 		return r, err
 	}
 	if execer, ok := tc.Conn.(driver.Execer); ok {
@@ -129,9 +141,14 @@ func (tc *TracedConn) ExecContext(ctx context.Context, query string, args []driv
 			return nil, ctx.Err()
 		default:
 		}
+		if err := tc.protect(ctx, query); err != nil {
+			tc.tryTrace(ctx, queryTypeExec, query, start, err)
+			return nil, err
+		}
 		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.dbmPropagationMode)
 		r, err = execer.Exec(cquery, dargs)
-		tc.tryTrace(ctx, queryTypeExec, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))...)
+		opts := append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))
+		tc.tryTrace(ctx, queryTypeExec, query, start, err, append(opts, withRowsAffectedTag(r, err)...)...)
 		return r, err
 	}
 	return nil, driver.ErrSkip
@@ -152,10 +169,14 @@ func (tc *TracedConn) Ping(ctx context.Context) (err error) {
 func (tc *TracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
 	start := time.Now()
 	if queryerContext, ok := tc.Conn.(driver.QueryerContext); ok {
+		if err := tc.protect(ctx, query); err != nil {
+			tc.tryTrace(ctx, queryTypeQuery, query, start, err)
+			return nil, err
+		}
 		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.dbmPropagationMode)
 		rows, err := queryerContext.QueryContext(ctx, cquery, args)
-		tc.tryTrace(ctx, queryTypeQuery, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))...)
-		return rows, err
+		span := tc.tryTrace(ctx, queryTypeQuery, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))...)
+		return tc.wrapRows(ctx, span, query, rows), err
 	}
 	if queryer, ok := tc.Conn.(driver.Queryer); ok {
 		dargs, err := namedValueToValue(args)
@@ -167,10 +188,14 @@ func (tc *TracedConn) QueryContext(ctx context.Context, query string, args []dri
 			return nil, ctx.Err()
 		default:
 		}
+		if err := tc.protect(ctx, query); err != nil {
+			tc.tryTrace(ctx, queryTypeQuery, query, start, err)
+			return nil, err
+		}
 		cquery, spanID := tc.injectComments(ctx, query, tc.cfg.dbmPropagationMode)
 		rows, err = queryer.Query(cquery, dargs)
-		tc.tryTrace(ctx, queryTypeQuery, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))...)
-		return rows, err
+		span := tc.tryTrace(ctx, queryTypeQuery, query, start, err, append(withDBMTraceInjectedTag(tc.cfg.dbmPropagationMode), tracer.WithSpanID(spanID))...)
+		return tc.wrapRows(ctx, span, query, rows), err
 	}
 	return nil, driver.ErrSkip
 }
@@ -189,6 +214,10 @@ var _ driver.SessionResetter = (*TracedConn)(nil)
 
 // ResetSession implements driver.SessionResetter
 func (tc *TracedConn) ResetSession(ctx context.Context) error {
+	// the connection is being taken from the pool
+	if tc.stats != nil {
+		atomic.StoreInt64(&tc.connWait, int64(tc.stats.connWait()))
+	}
 	if resetter, ok := tc.Conn.(driver.SessionResetter); ok {
 		return resetter.ResetSession(ctx)
 	}
@@ -198,9 +227,16 @@ func (tc *TracedConn) ResetSession(ctx context.Context) error {
 
 // traceParams stores all information related to tracing the driver.Conn
 type traceParams struct {
+	// connWait holds the nanoseconds spent waiting for the connection when it
+	// was last taken from the pool, until it is reported by the next span.
+	// It is the first field to be 64-bit aligned for the atomic operations.
+	connWait int64 // atomic
+
 	cfg        *config
 	driverName string
 	meta       map[string]string
+	// stats is nil when the reporting of the pool statistics is disabled.
+	stats *dbStats
 }
 
 type contextKey int
@@ -240,17 +276,44 @@ func withDBMTraceInjectedTag(mode tracer.DBMPropagationMode) []tracer.StartSpanO
 	return nil
 }
 
-// tryTrace will create a span using the given arguments, but will act as a no-op when err is driver.ErrSkip.
-func (tp *traceParams) tryTrace(ctx context.Context, qtype queryType, query string, startTime time.Time, err error, spanOpts ...ddtrace.StartSpanOption) {
+// withRowsAffectedTag returns the option tagging the span of an execution with
+// the number of rows affected by it, when known.
+func withRowsAffectedTag(res driver.Result, err error) []tracer.StartSpanOption {
+	if err != nil || res == nil {
+		return nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil
+	}
+	return []tracer.StartSpanOption{tracer.Tag(keyRowsAffected, n)}
+}
+
+// protect runs the AppSec protections, when enabled, against the given query before its execution. An error is
+// returned when the query must not be executed.
+func (tp *traceParams) protect(ctx context.Context, query string) error {
+	if !appsec.Enabled() {
+		return nil
+	}
+	system := tp.meta[ext.DBSystem]
+	if system == "" {
+		system = tp.driverName
+	}
+	return sqlsec.ProtectSQLOperation(ctx, query, system)
+}
+
+// tryTrace will create and finish a span using the given arguments, but will act as a no-op when err
+// is driver.ErrSkip. It returns the finished span, or nil if none was created.
+func (tp *traceParams) tryTrace(ctx context.Context, qtype queryType, query string, startTime time.Time, err error, spanOpts ...ddtrace.StartSpanOption) ddtrace.Span {
 	if err == driver.ErrSkip {
 		// Not a user error: driver is telling sql package that an
 		// optional interface method is not implemented. There is
 		// nothing to trace here.
 		// See: https://github.com/DataDog/dd-trace-go/issues/270
-		return
+		return nil
 	}
 	if _, exists := tracer.SpanFromContext(ctx); tp.cfg.childSpansOnly && !exists {
-		return
+		return nil
 	}
 	name := fmt.Sprintf("%s.query", tp.driverName)
 	opts := append(spanOpts,
@@ -285,8 +348,12 @@ func (tp *traceParams) tryTrace(ctx context.Context, qtype queryType, query stri
 			span.SetTag(k, v)
 		}
 	}
+	if wait := atomic.SwapInt64(&tp.connWait, 0); wait > 0 {
+		span.SetTag(keyPoolWait, wait)
+	}
 	if err != nil && (tp.cfg.errCheck == nil || tp.cfg.errCheck(err)) {
 		span.SetTag(ext.Error, err)
 	}
 	span.Finish()
+	return span
 }
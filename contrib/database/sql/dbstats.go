@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package sql
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// dbStatsInterval specifies the interval at which the statistics of the
+// connection pools are reported.
+var dbStatsInterval = 10 * time.Second
+
+// dbStats reports the statistics of the connection pool of a database, and
+// tracks the time spent waiting for its connections.
+type dbStats struct {
+	db   *sql.DB
+	tags []string
+	stop chan struct{}
+	once sync.Once // guards the closing of stop
+
+	mu           sync.Mutex // guards the fields below
+	waitCount    int64
+	waitDuration time.Duration
+}
+
+func newDBStats(db *sql.DB, tags []string) *dbStats {
+	return &dbStats{
+		db:   db,
+		tags: tags,
+		stop: make(chan struct{}),
+	}
+}
+
+// run periodically reports the statistics of the connection pool at the given
+// interval, until the database is closed.
+func (s *dbStats) run(interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			tracer.ReportDBStats(s.db.Stats(), s.tags)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// close stops reporting the statistics of the connection pool.
+func (s *dbStats) close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// connWait returns the time spent waiting for the connection which was just
+// taken from the pool. The pool only reports the total time spent waiting for
+// connections, so the time is derived from the waits which completed since the
+// previous call, and is their average when several of them did.
+func (s *dbStats) connWait() time.Duration {
+	stats := s.db.Stats()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := stats.WaitCount - s.waitCount
+	duration := stats.WaitDuration - s.waitDuration
+	s.waitCount, s.waitDuration = stats.WaitCount, stats.WaitDuration
+	if count <= 0 {
+		return 0
+	}
+	return duration / time.Duration(count)
+}
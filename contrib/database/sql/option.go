@@ -21,6 +21,8 @@ type config struct {
 	errCheck           func(err error) bool
 	tags               map[string]interface{}
 	dbmPropagationMode tracer.DBMPropagationMode
+	dbStats            bool
+	traceRows          bool
 }
 
 // Option represents an option that can be passed to Register, Open or OpenDB.
@@ -134,3 +136,28 @@ func WithDBMPropagation(mode tracer.DBMPropagationMode) Option {
 		cfg.dbmPropagationMode = mode
 	}
 }
+
+// WithDBStats enables the periodic reporting of the statistics of the
+// connection pool of the database (see sql.DBStats), as metrics sent using the
+// tracer's statsd client. The metrics are tagged with the service name, the
+// driver name and the database instance. Additionally, the spans of the
+// operations which had to wait for a connection of the pool are tagged with
+// the time spent waiting, in nanoseconds, as sql.pool.wait_ns. As database/sql
+// only reports the total time spent waiting for connections, it is the average
+// time of the waits which completed since the previous connection was taken
+// from the pool when several operations waited concurrently.
+func WithDBStats() Option {
+	return func(cfg *config) {
+		cfg.dbStats = true
+	}
+}
+
+// WithRowsTracing enables the tracing of the iteration over the rows returned
+// by the queries. The iteration, from the end of the query until the rows are
+// closed, is traced as a child span of the query span, tagged with the number
+// of rows returned and the iteration error, if any.
+func WithRowsTracing() Option {
+	return func(cfg *config) {
+		cfg.traceRows = true
+	}
+}
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+var _ driver.Rows = (*tracedRows)(nil)
+
+// tracedRows is a traced version of driver.Rows. It traces the iteration over
+// the rows, from the end of the query until the rows are closed, as a child
+// span of the query span.
+type tracedRows struct {
+	driver.Rows
+	*traceParams
+	ctx   context.Context
+	query string
+	start time.Time
+	count int64 // number of rows returned
+	err   error // first iteration error
+}
+
+// wrapRows returns rows traced as the result of the query traced by span, or
+// rows when the tracing of the rows is disabled or the query was not traced.
+func (tp *traceParams) wrapRows(ctx context.Context, span ddtrace.Span, query string, rows driver.Rows) driver.Rows {
+	if !tp.cfg.traceRows || span == nil || rows == nil {
+		return rows
+	}
+	return &tracedRows{
+		Rows:        rows,
+		traceParams: tp,
+		ctx:         tracer.ContextWithSpan(ctx, span),
+		query:       query,
+		start:       time.Now(),
+	}
+}
+
+// Next calls the wrapped Next, counting the returned rows.
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case err != io.EOF && r.err == nil:
+		r.err = err
+	}
+	return err
+}
+
+// Close closes the rows and sends the span of the iteration.
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	traceErr := r.err
+	if traceErr == nil {
+		traceErr = err
+	}
+	r.tryTrace(r.ctx, queryTypeRows, r.query, r.start, traceErr, tracer.Tag(keyRowsReturned, r.count))
+	return err
+}
+
+// HasNextResultSet implements driver.RowsNextResultSet.
+func (r *tracedRows) HasNextResultSet() bool {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+// NextResultSet implements driver.RowsNextResultSet.
+func (r *tracedRows) NextResultSet() error {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		err := rs.NextResultSet()
+		if err != nil && err != io.EOF && r.err == nil {
+			r.err = err
+		}
+		return err
+	}
+	return io.EOF
+}
+
+// The methods below implement the optional interfaces describing the columns,
+// returning the same values as the sql package when the wrapped rows don't
+// implement them.
+
+// ColumnTypeScanType implements driver.RowsColumnTypeScanType.
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if rs, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return rs.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName.
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if rs, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return rs.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeLength implements driver.RowsColumnTypeLength.
+func (r *tracedRows) ColumnTypeLength(index int) (length int64, ok bool) {
+	if rs, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return rs.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypeNullable implements driver.RowsColumnTypeNullable.
+func (r *tracedRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if rs, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return rs.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// ColumnTypePrecisionScale implements driver.RowsColumnTypePrecisionScale.
+func (r *tracedRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if rs, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return rs.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
@@ -20,12 +20,14 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"io"
 	"math"
 	"reflect"
 	"sync"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
@@ -142,17 +144,16 @@ type tracedConnector struct {
 	connector  driver.Connector
 	driverName string
 	cfg        *config
+	// stats is nil when the reporting of the pool statistics is disabled.
+	stats *dbStats
 }
 
 func (t *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
 	tp := &traceParams{
 		driverName: t.driverName,
 		cfg:        t.cfg,
-	}
-	if dc, ok := t.connector.(*dsnConnector); ok {
-		tp.meta, _ = internal.ParseDSN(t.driverName, dc.dsn)
-	} else if t.cfg.dsn != "" {
-		tp.meta, _ = internal.ParseDSN(t.driverName, t.cfg.dsn)
+		stats:      t.stats,
+		meta:       t.meta(),
 	}
 	start := time.Now()
 	conn, err := t.connector.Connect(ctx)
@@ -167,6 +168,29 @@ func (t *tracedConnector) Driver() driver.Driver {
 	return t.connector.Driver()
 }
 
+// meta returns the tags parsed from the DSN of the database, if known.
+func (t *tracedConnector) meta() map[string]string {
+	var meta map[string]string
+	if dc, ok := t.connector.(*dsnConnector); ok {
+		meta, _ = internal.ParseDSN(t.driverName, dc.dsn)
+	} else if t.cfg.dsn != "" {
+		meta, _ = internal.ParseDSN(t.driverName, t.cfg.dsn)
+	}
+	return meta
+}
+
+// Close is called by sql.DB.Close. It stops the reporting of the pool
+// statistics, and closes the wrapped connector if it implements io.Closer.
+func (t *tracedConnector) Close() error {
+	if t.stats != nil {
+		t.stats.close()
+	}
+	if c, ok := t.connector.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // from Go stdlib implementation of sql.Open
 type dsnConnector struct {
 	dsn    string
@@ -208,12 +232,27 @@ func OpenDB(c driver.Connector, opts ...Option) *sql.DB {
 		cfg.dbmPropagationMode = rc.dbmPropagationMode
 	}
 	cfg.childSpansOnly = rc.childSpansOnly
+	if !cfg.dbStats {
+		cfg.dbStats = rc.dbStats
+	}
+	if !cfg.traceRows {
+		cfg.traceRows = rc.traceRows
+	}
 	tc := &tracedConnector{
 		connector:  c,
 		driverName: name,
 		cfg:        cfg,
 	}
-	return sql.OpenDB(tc)
+	db := sql.OpenDB(tc)
+	if cfg.dbStats {
+		tags := []string{"service:" + cfg.serviceName, ext.DBType + ":" + name}
+		if instance := tc.meta()[ext.DBName]; instance != "" {
+			tags = append(tags, ext.DBInstance+":"+instance)
+		}
+		tc.stats = newDBStats(db, tags)
+		go tc.stats.run(dbStatsInterval)
+	}
+	return db
 }
 
 // Open returns connection to a DB using the traced version of the given driver. In order for Open
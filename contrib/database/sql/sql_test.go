@@ -22,10 +22,13 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/database/sql/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/sqltest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
 // tableName holds the SQL table that these tests will be run against. It must be unique cross-repo.
@@ -283,3 +286,161 @@ func TestRegister(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestDBStats(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	Register("dbstats-test", &internal.MockDriver{})
+	defer unregister("dbstats-test")
+	db, err := Open("dbstats-test", "dn", WithDBStats())
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// waits for conn to be released to the pool
+		_, err := db.ExecContext(ctx, "DELETE FROM users")
+		assert.NoError(t, err)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	conn.Close()
+	<-done
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "Connect", spans[0].Tag("sql.query_type"))
+	assert.Nil(t, spans[0].Tag(keyPoolWait))
+	assert.Equal(t, "Exec", spans[1].Tag("sql.query_type"))
+	wait, ok := spans[1].Tag(keyPoolWait).(int64)
+	require.True(t, ok)
+	assert.Equal(t, db.Stats().WaitDuration, time.Duration(wait))
+	assert.GreaterOrEqual(t, time.Duration(wait), 50*time.Millisecond)
+}
+
+func TestDBStatsClose(t *testing.T) {
+	Register("dbstats-test", &internal.MockDriver{})
+	defer unregister("dbstats-test")
+	db, err := Open("dbstats-test", "dn")
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := newDBStats(db, nil)
+	stopped := make(chan struct{})
+	go func() {
+		s.run(time.Millisecond)
+		close(stopped)
+	}()
+	s.close()
+	s.close()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the pool statistics are still reported")
+	}
+}
+
+func TestRowsTracing(t *testing.T) {
+	Register("rows-test", &internal.MockDriver{})
+	defer unregister("rows-test")
+
+	query := func(t *testing.T, opts ...Option) []mocktracer.Span {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		db, err := Open("rows-test", "dn", opts...)
+		require.NoError(t, err)
+		defer db.Close()
+		rows, err := db.QueryContext(context.Background(), "SELECT name FROM users")
+		require.NoError(t, err)
+		assert.False(t, rows.Next())
+		assert.NoError(t, rows.Close())
+		return mt.FinishedSpans()
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		spans := query(t, WithRowsTracing())
+		require.Len(t, spans, 3)
+		q, r := spans[1], spans[2]
+		assert.Equal(t, "Query", q.Tag("sql.query_type"))
+		assert.Equal(t, "Rows", r.Tag("sql.query_type"))
+		assert.Equal(t, "rows-test.query", r.OperationName())
+		assert.Equal(t, "SELECT name FROM users", r.Tag(ext.ResourceName))
+		assert.Equal(t, int64(0), r.Tag(keyRowsReturned))
+		assert.Equal(t, q.SpanID(), r.ParentID())
+		assert.Equal(t, q.TraceID(), r.TraceID())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		spans := query(t)
+		require.Len(t, spans, 2)
+		assert.Equal(t, "Query", spans[1].Tag("sql.query_type"))
+	})
+}
+
+// testRows returns n rows, followed by err.
+type testRows struct {
+	n   int
+	err error
+}
+
+func (r *testRows) Columns() []string { return []string{"name"} }
+
+func (r *testRows) Close() error { return nil }
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return r.err
+	}
+	r.n--
+	dest[0] = "name"
+	return nil
+}
+
+func TestTracedRows(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	tp := &traceParams{cfg: &config{traceRows: true}, driverName: "test"}
+	parent := tracer.StartSpan("test.query")
+	rows := tp.wrapRows(context.Background(), parent, "SELECT name FROM users", &testRows{n: 2, err: errors.New("broken")})
+	dest := make([]driver.Value, 1)
+	assert.NoError(t, rows.Next(dest))
+	assert.NoError(t, rows.Next(dest))
+	assert.EqualError(t, rows.Next(dest), "broken")
+	assert.NoError(t, rows.Close())
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, int64(2), s.Tag(keyRowsReturned))
+	assert.Equal(t, "broken", s.Tag(ext.Error).(error).Error())
+	assert.Equal(t, parent.Context().SpanID(), s.ParentID())
+
+	// rows are not wrapped when the query is not traced
+	r := &testRows{}
+	assert.Equal(t, driver.Rows(r), tp.wrapRows(context.Background(), nil, "", r))
+}
+
+func TestRowsAffected(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	Register("rows-affected-test", &internal.MockDriver{})
+	defer unregister("rows-affected-test")
+	db, err := Open("rows-affected-test", "dn")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.ExecContext(context.Background(), "DELETE FROM users")
+	require.NoError(t, err)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "Exec", spans[1].Tag("sql.query_type"))
+	assert.Equal(t, int64(0), spans[1].Tag(keyRowsAffected))
+}
@@ -33,9 +33,13 @@ func (s *tracedStmt) Close() (err error) {
 // ExecContext is needed to implement the driver.StmtExecContext interface
 func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (res driver.Result, err error) {
 	start := time.Now()
+	if err := s.protect(ctx, s.query); err != nil {
+		s.tryTrace(ctx, queryTypeExec, s.query, start, err)
+		return nil, err
+	}
 	if stmtExecContext, ok := s.Stmt.(driver.StmtExecContext); ok {
 		res, err := stmtExecContext.ExecContext(ctx, args)
-		s.tryTrace(ctx, queryTypeExec, s.query, start, err)
+		s.tryTrace(ctx, queryTypeExec, s.query, start, err, withRowsAffectedTag(res, err)...)
 		return res, err
 	}
 	dargs, err := namedValueToValue(args)
@@ -48,17 +52,21 @@ func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue)
 	default:
 	}
 	res, err = s.Exec(dargs)
-	s.tryTrace(ctx, queryTypeExec, s.query, start, err)
+	s.tryTrace(ctx, queryTypeExec, s.query, start, err, withRowsAffectedTag(res, err)...)
 	return res, err
 }
 
 // QueryContext is needed to implement the driver.StmtQueryContext interface
 func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
 	start := time.Now()
+	if err := s.protect(ctx, s.query); err != nil {
+		s.tryTrace(ctx, queryTypeQuery, s.query, start, err)
+		return nil, err
+	}
 	if stmtQueryContext, ok := s.Stmt.(driver.StmtQueryContext); ok {
 		rows, err := stmtQueryContext.QueryContext(ctx, args)
-		s.tryTrace(ctx, queryTypeQuery, s.query, start, err)
-		return rows, err
+		span := s.tryTrace(ctx, queryTypeQuery, s.query, start, err)
+		return s.wrapRows(ctx, span, s.query, rows), err
 	}
 	dargs, err := namedValueToValue(args)
 	if err != nil {
@@ -70,8 +78,8 @@ func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue)
 	default:
 	}
 	rows, err = s.Query(dargs)
-	s.tryTrace(ctx, queryTypeQuery, s.query, start, err)
-	return rows, err
+	span := s.tryTrace(ctx, queryTypeQuery, s.query, start, err)
+	return s.wrapRows(ctx, span, s.query, rows), err
 }
 
 // copied from stdlib database/sql package: src/database/sql/ctxutil.go
@@ -41,11 +41,15 @@ func New(opts ...RouterOption) *Router {
 // ServeHTTP implements http.Handler.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	resource := r.config.resourceNamer(r.TreeMux, w, req)
+	route, params, _ := lookupRoute(r.TreeMux, w, req)
 	// pass r.TreeMux to avoid a circular reference panic on calling r.ServeHTTP
 	httptrace.TraceAndServe(r.TreeMux, w, req, &httptrace.ServeConfig{
-		Service:  r.config.serviceName,
-		Resource: resource,
-		SpanOpts: r.config.spanOpts,
+		Service:       r.config.serviceName,
+		Resource:      resource,
+		SpanOpts:      r.config.spanOpts,
+		Route:         route,
+		RouteParams:   params,
+		IsStatusError: r.config.isStatusError,
 	})
 }
 
@@ -74,24 +78,27 @@ func NewWithContext(opts ...RouterOption) *ContextRouter {
 // ServeHTTP implements http.Handler.
 func (r *ContextRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	resource := r.config.resourceNamer(r.TreeMux, w, req)
+	route, params, _ := lookupRoute(r.TreeMux, w, req)
 	// pass r.TreeMux to avoid a circular reference panic on calling r.ServeHTTP
 	httptrace.TraceAndServe(r.TreeMux, w, req, &httptrace.ServeConfig{
-		Service:  r.config.serviceName,
-		Resource: resource,
-		SpanOpts: r.config.spanOpts,
+		Service:       r.config.serviceName,
+		Resource:      resource,
+		SpanOpts:      r.config.spanOpts,
+		Route:         route,
+		RouteParams:   params,
+		IsStatusError: r.config.isStatusError,
 	})
 }
 
-// defaultResourceNamer attempts to determine the resource name for an HTTP
-// request by performing a lookup using the path template associated with the
-// route from the request. If the lookup fails to find a match the route is set
-// to "unknown".
-func defaultResourceNamer(router *httptreemux.TreeMux, w http.ResponseWriter, req *http.Request) string {
-	route := req.URL.Path
+// lookupRoute returns the route template matching the request, such as "/user/:id", along with its path parameters.
+// The route template is rebuilt from the request path by replacing the path parameter values with their names, since
+// the router doesn't expose the templates of its routes.
+func lookupRoute(router *httptreemux.TreeMux, w http.ResponseWriter, req *http.Request) (route string, params map[string]string, found bool) {
 	lr, found := router.Lookup(w, req)
 	if !found {
-		return req.Method + " unknown"
+		return "", nil, false
 	}
+	route = req.URL.Path
 	for k, v := range lr.Params {
 		// replace parameter surrounded by a set of "/", i.e. ".../:param/..."
 		old := "/" + v + "/"
@@ -105,5 +112,17 @@ func defaultResourceNamer(router *httptreemux.TreeMux, w http.ResponseWriter, re
 		new = "/:" + k
 		route = strings.Replace(route, old, new, 1)
 	}
+	return route, lr.Params, true
+}
+
+// defaultResourceNamer attempts to determine the resource name for an HTTP
+// request by performing a lookup using the path template associated with the
+// route from the request. If the lookup fails to find a match the route is set
+// to "unknown".
+func defaultResourceNamer(router *httptreemux.TreeMux, w http.ResponseWriter, req *http.Request) string {
+	route, _, found := lookupRoute(router, w, req)
+	if !found {
+		return req.Method + " unknown"
+	}
 	return req.Method + " " + route
 }
@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -165,6 +166,7 @@ func TestDefaultResourceNamer(t *testing.T) {
 			s := spans[0]
 			resourceName := tc.method + " " + tc.path
 			assert.Equal(resourceName, s.Tag(ext.ResourceName))
+			assert.Equal(tc.path, s.Tag(ext.HTTPRoute))
 			assert.Equal("200", s.Tag(ext.HTTPCode))
 			assert.Equal(tc.method, s.Tag(ext.HTTPMethod))
 			assert.Equal("http://example.com"+tc.url, s.Tag(ext.HTTPURL))
@@ -232,3 +234,20 @@ func handler200(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 func handler500(w http.ResponseWriter, r *http.Request, _ map[string]string) {
 	http.Error(w, "500!", http.StatusInternalServerError)
 }
+
+func TestAppSec(t *testing.T) {
+	t.Run("Router", func(t *testing.T) {
+		router := New()
+		router.GET("/hello/:name", func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+			w.Write([]byte(appsectest.Body))
+		})
+		appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(router)})
+	})
+	t.Run("ContextRouter", func(t *testing.T) {
+		router := NewWithContext()
+		router.GET("/hello/:name", func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(appsectest.Body))
+		})
+		appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(router)})
+	})
+}
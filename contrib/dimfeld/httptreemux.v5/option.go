@@ -19,6 +19,7 @@ type routerConfig struct {
 	serviceName   string
 	spanOpts      []ddtrace.StartSpanOption
 	resourceNamer func(*httptreemux.TreeMux, http.ResponseWriter, *http.Request) string
+	isStatusError func(statusCode int) bool
 }
 
 // RouterOption represents an option that can be passed to New.
@@ -53,3 +54,11 @@ func WithResourceNamer(namer func(router *httptreemux.TreeMux, w http.ResponseWr
 		cfg.resourceNamer = namer
 	}
 }
+
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error.
+func WithStatusCheck(fn func(statusCode int) bool) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.isStatusError = fn
+	}
+}
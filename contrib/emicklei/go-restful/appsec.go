@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package restful
+
+import (
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+
+	"github.com/emicklei/go-restful"
+)
+
+// processFilterWithAppSec monitors the processing of the request by the rest of the filter chain with AppSec.
+func processFilterWithAppSec(req *restful.Request, resp *restful.Response, chain *restful.FilterChain, span tracer.Span) {
+	h := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		req.Request = r
+		chain.ProcessFilter(req, resp)
+	})
+	// Wrap the restful response to allow monitoring of the response status code in httpsec.WrapHandler()
+	httpsec.WrapHandler(h, span, req.PathParameters(), req.SelectedRoutePath()).ServeHTTP(&statusResponseWriter{resp}, req.Request)
+}
+
+// statusResponseWriter wraps a restful response to allow retrieving its status code through a Status() method.
+type statusResponseWriter struct {
+	*restful.Response
+}
+
+// Status returns the status code of the response.
+func (w *statusResponseWriter) Status() int {
+	return w.Response.StatusCode()
+}
@@ -15,6 +15,7 @@ import (
 type config struct {
 	serviceName   string
 	analyticsRate float64
+	isStatusError func(statusCode int) bool
 }
 
 func newConfig() *config {
@@ -60,3 +61,11 @@ func WithAnalyticsRate(rate float64) Option {
 		}
 	}
 }
+
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error.
+func WithStatusCheck(fn func(statusCode int) bool) Option {
+	return func(cfg *config) {
+		cfg.isStatusError = fn
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
 	"github.com/emicklei/go-restful"
@@ -36,11 +37,15 @@ func FilterFunc(configOpts ...Option) restful.FilterFunction {
 		}
 		span, ctx := httptrace.StartRequestSpan(req.Request, spanOpts...)
 		defer func() {
-			httptrace.FinishRequestSpan(span, resp.StatusCode(), tracer.WithError(resp.Error()))
+			httptrace.FinishRequestSpan(span, resp.StatusCode(), cfg.isStatusError, resp.Header(), tracer.WithError(resp.Error()))
 		}()
 
 		// pass the span through the request context
 		req.Request = req.Request.WithContext(ctx)
+		if appsec.Enabled() {
+			processFilterWithAppSec(req, resp, chain, span)
+			return
+		}
 		chain.ProcessFilter(req, resp)
 	}
 }
@@ -49,10 +54,14 @@ func FilterFunc(configOpts ...Option) restful.FilterFunction {
 func Filter(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 	span, ctx := httptrace.StartRequestSpan(req.Request, tracer.ResourceName(req.SelectedRoutePath()))
 	defer func() {
-		httptrace.FinishRequestSpan(span, resp.StatusCode(), tracer.WithError(resp.Error()))
+		httptrace.FinishRequestSpan(span, resp.StatusCode(), nil, resp.Header(), tracer.WithError(resp.Error()))
 	}()
 
 	// pass the span through the request context
 	req.Request = req.Request.WithContext(ctx)
+	if appsec.Enabled() {
+		processFilterWithAppSec(req, resp, chain, span)
+		return
+	}
 	chain.ProcessFilter(req, resp)
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/emicklei/go-restful"
 	"github.com/stretchr/testify/assert"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -180,3 +181,14 @@ func TestAnalyticsSettings(t *testing.T) {
 		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
 	})
 }
+
+func TestAppSec(t *testing.T) {
+	ws := new(restful.WebService)
+	ws.Filter(FilterFunc())
+	ws.Route(ws.GET(appsectest.Route).To(func(_ *restful.Request, response *restful.Response) {
+		response.Write([]byte(appsectest.Body))
+	}))
+	container := restful.NewContainer()
+	container.Add(ws)
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(container)})
+}
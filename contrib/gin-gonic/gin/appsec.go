@@ -28,5 +28,5 @@ func useAppSec(c *gin.Context, span tracer.Span) {
 		c.Request = r
 		c.Next()
 	})
-	httpsec.WrapHandler(h, span, params).ServeHTTP(c.Writer, c.Request)
+	httpsec.WrapHandler(h, span, params, c.FullPath()).ServeHTTP(c.Writer, c.Request)
 }
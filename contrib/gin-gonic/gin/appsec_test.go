@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	pappsec "gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
@@ -429,3 +430,13 @@ func TestBlocking(t *testing.T) {
 		}
 	})
 }
+
+func TestAppSecConformance(t *testing.T) {
+	r := gin.New()
+	r.Use(Middleware("appsec"))
+	r.GET("/hello/:name", func(c *gin.Context) {
+		c.String(http.StatusOK, appsectest.Body)
+	})
+	r.POST(appsectest.LoginRoute, gin.WrapH(appsectest.LoginHandler))
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(r), Login: true})
+}
@@ -44,7 +44,7 @@ func Middleware(service string, opts ...Option) gin.HandlerFunc {
 
 		span, ctx := httptrace.StartRequestSpan(c.Request, opts...)
 		defer func() {
-			httptrace.FinishRequestSpan(span, c.Writer.Status())
+			httptrace.FinishRequestSpan(span, c.Writer.Status(), cfg.isStatusError, c.Writer.Header())
 		}()
 
 		// pass the span through the request context
@@ -20,6 +20,7 @@ type config struct {
 	resourceNamer func(c *gin.Context) string
 	serviceName   string
 	ignoreRequest func(c *gin.Context) bool
+	isStatusError func(statusCode int) bool
 }
 
 func newConfig(service string) *config {
@@ -75,6 +76,14 @@ func WithResourceNamer(namer func(c *gin.Context) string) Option {
 	}
 }
 
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error.
+func WithStatusCheck(fn func(statusCode int) bool) Option {
+	return func(cfg *config) {
+		cfg.isStatusError = fn
+	}
+}
+
 // WithIgnoreRequest specifies a function to use for determining if the
 // incoming HTTP request tracing should be skipped.
 func WithIgnoreRequest(f func(c *gin.Context) bool) Option {
@@ -78,7 +78,7 @@ func (c *Collection) Indexes() (indexes []mgo.Index, err error) {
 
 // Insert invokes and traces Collectin.Insert
 func (c *Collection) Insert(docs ...interface{}) error {
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, bson.D{{Name: "documents", Value: capDocuments(c.cfg, docs)}}))
 	err := c.Collection.Insert(docs...)
 	span.Finish(tracer.WithError(err))
 	return err
@@ -89,7 +89,7 @@ func (c *Collection) Find(query interface{}) *Query {
 	return &Query{
 		Query: c.Collection.Find(query),
 		cfg:   c.cfg,
-		tags:  c.tags,
+		tags:  withQuery(c.cfg, c.tags, query),
 	}
 }
 
@@ -98,7 +98,7 @@ func (c *Collection) FindId(id interface{}) *Query { // nolint
 	return &Query{
 		Query: c.Collection.FindId(id),
 		cfg:   c.cfg,
-		tags:  c.tags,
+		tags:  withQuery(c.cfg, c.tags, bson.D{{Name: "_id", Value: id}}),
 	}
 }
 
@@ -132,13 +132,13 @@ func (c *Collection) Pipe(pipeline interface{}) *Pipe {
 	return &Pipe{
 		Pipe: c.Collection.Pipe(pipeline),
 		cfg:  c.cfg,
-		tags: c.tags,
+		tags: withQuery(c.cfg, c.tags, pipeline),
 	}
 }
 
 // Update invokes and traces Collection.Update
 func (c *Collection) Update(selector interface{}, update interface{}) error {
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, updateQuery(selector, update)))
 	err := c.Collection.Update(selector, update)
 	span.Finish(tracer.WithError(err))
 	return err
@@ -146,7 +146,7 @@ func (c *Collection) Update(selector interface{}, update interface{}) error {
 
 // UpdateId invokes and traces Collection.UpdateId
 func (c *Collection) UpdateId(id interface{}, update interface{}) error { // nolint
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, updateQuery(bson.D{{Name: "_id", Value: id}}, update)))
 	err := c.Collection.UpdateId(id, update)
 	span.Finish(tracer.WithError(err))
 	return err
@@ -154,7 +154,7 @@ func (c *Collection) UpdateId(id interface{}, update interface{}) error { // nol
 
 // UpdateAll invokes and traces Collection.UpdateAll
 func (c *Collection) UpdateAll(selector interface{}, update interface{}) (info *mgo.ChangeInfo, err error) {
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, updateQuery(selector, update)))
 	info, err = c.Collection.UpdateAll(selector, update)
 	span.Finish(tracer.WithError(err))
 	return info, err
@@ -162,7 +162,7 @@ func (c *Collection) UpdateAll(selector interface{}, update interface{}) (info *
 
 // Upsert invokes and traces Collection.Upsert
 func (c *Collection) Upsert(selector interface{}, update interface{}) (info *mgo.ChangeInfo, err error) {
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, updateQuery(selector, update)))
 	info, err = c.Collection.Upsert(selector, update)
 	span.Finish(tracer.WithError(err))
 	return info, err
@@ -170,7 +170,7 @@ func (c *Collection) Upsert(selector interface{}, update interface{}) (info *mgo
 
 // UpsertId invokes and traces Collection.UpsertId
 func (c *Collection) UpsertId(id interface{}, update interface{}) (info *mgo.ChangeInfo, err error) { // nolint
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, updateQuery(bson.D{{Name: "_id", Value: id}}, update)))
 	info, err = c.Collection.UpsertId(id, update)
 	span.Finish(tracer.WithError(err))
 	return info, err
@@ -178,7 +178,7 @@ func (c *Collection) UpsertId(id interface{}, update interface{}) (info *mgo.Cha
 
 // Remove invokes and traces Collection.Remove
 func (c *Collection) Remove(selector interface{}) error {
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, selector))
 	err := c.Collection.Remove(selector)
 	span.Finish(tracer.WithError(err))
 	return err
@@ -186,7 +186,7 @@ func (c *Collection) Remove(selector interface{}) error {
 
 // RemoveId invokes and traces Collection.RemoveId
 func (c *Collection) RemoveId(id interface{}) error { // nolint
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, bson.D{{Name: "_id", Value: id}}))
 	err := c.Collection.RemoveId(id)
 	span.Finish(tracer.WithError(err))
 	return err
@@ -194,7 +194,7 @@ func (c *Collection) RemoveId(id interface{}) error { // nolint
 
 // RemoveAll invokes and traces Collection.RemoveAll
 func (c *Collection) RemoveAll(selector interface{}) (info *mgo.ChangeInfo, err error) {
-	span := newChildSpanFromContext(c.cfg, c.tags)
+	span := newChildSpanFromContext(c.cfg, withQuery(c.cfg, c.tags, selector))
 	info, err = c.Collection.RemoveAll(selector)
 	span.Finish(tracer.WithError(err))
 	return info, err
@@ -212,3 +212,23 @@ func (c *Collection) Repair() *Iter {
 		cfg:  c.cfg,
 	}
 }
+
+// capDocuments returns the documents of docs which can appear in the query
+// reported in the spans, so that bulk inserts aren't entirely marshalled to be
+// truncated right after. Every document takes at least 3 bytes of the query,
+// i.e. `{},`.
+func capDocuments(cfg *mongoConfig, docs []interface{}) []interface{} {
+	if !cfg.queryCapture || cfg.maxQuerySize <= 0 {
+		return docs
+	}
+	if n := cfg.maxQuerySize/3 + 1; len(docs) > n {
+		return docs[:n]
+	}
+	return docs
+}
+
+// updateQuery returns the query of an update of the documents matching
+// selector, in the format of the statements of the update command.
+func updateQuery(selector interface{}, update interface{}) bson.D {
+	return bson.D{{Name: "q", Value: selector}, {Name: "u", Value: update}}
+}
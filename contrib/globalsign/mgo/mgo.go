@@ -10,12 +10,14 @@ import (
 	"math"
 	"strings"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/truncate"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 )
 
 // Dial opens a connection to a MongoDB server and configures it
@@ -44,6 +46,48 @@ func Dial(url string, opts ...DialOption) (*Session, error) {
 	return s, err
 }
 
+// queryTag is the tag holding the obfuscated query of a span.
+const queryTag = "mongodb.query"
+
+// withQuery returns a copy of tags holding the obfuscated extended JSON of
+// query, or tags when the query capture is disabled or there is no query.
+func withQuery(cfg *mongoConfig, tags map[string]string, query interface{}) map[string]string {
+	if !cfg.queryCapture || query == nil {
+		return tags
+	}
+	q, err := marshalQuery(query)
+	if err != nil {
+		log.Debug("contrib/globalsign/mgo: Unable to marshal the query: %v", err)
+		return tags
+	}
+	qtags := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		qtags[k] = v
+	}
+	qtags[queryTag] = truncate.String(tracer.ObfuscateMongoDBQuery(q), cfg.maxQuerySize)
+	return qtags
+}
+
+// marshalQuery returns query in extended JSON. The query goes through a BSON
+// round trip first, as the JSON encoding neither supports the bson.D documents
+// nor takes the bson field tags into account.
+func marshalQuery(query interface{}) (string, error) {
+	b, err := bson.Marshal(bson.M{"q": query})
+	if err != nil {
+		return "", err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		return "", err
+	}
+	j, err := bson.MarshalJSON(doc["q"])
+	if err != nil {
+		return "", err
+	}
+	// MarshalJSON terminates the JSON with a newline
+	return strings.TrimSpace(string(j)), nil
+}
+
 // Session is an mgo.Session instance that will be traced.
 type Session struct {
 	*mgo.Session
@@ -67,6 +111,12 @@ func newChildSpanFromContext(cfg *mongoConfig, tags map[string]string) ddtrace.S
 	if !math.IsNaN(cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, cfg.analyticsRate))
 	}
+	if query, ok := tags[queryTag]; ok {
+		// the obfuscated query is the shape of the operation
+		opts = append(opts, tracer.ResourceName(query))
+	} else if coll, ok := tags[ext.MongoDBCollection]; ok {
+		opts = append(opts, tracer.ResourceName("mongodb.query "+coll))
+	}
 	span, _ := tracer.StartSpanFromContext(cfg.ctx, "mongodb.query", opts...)
 	for key, value := range tags {
 		span.SetTag(key, value)
@@ -76,7 +126,7 @@ func newChildSpanFromContext(cfg *mongoConfig, tags map[string]string) ddtrace.S
 
 // Run invokes and traces Session.Run
 func (s *Session) Run(cmd interface{}, result interface{}) (err error) {
-	span := newChildSpanFromContext(s.cfg, s.tags)
+	span := newChildSpanFromContext(s.cfg, withQuery(s.cfg, s.tags, cmd))
 	err = s.Session.Run(cmd, result)
 	span.Finish(tracer.WithError(err))
 	return
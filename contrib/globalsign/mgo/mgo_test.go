@@ -522,3 +522,80 @@ func TestAnalyticsSettings(t *testing.T) {
 		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
 	})
 }
+
+func TestQueryCapture(t *testing.T) {
+	entity := bson.D{{Name: "name", Value: "secret"}}
+	remove := func(t *testing.T, opts ...DialOption) mocktracer.Span {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		session, err := Dial("localhost:27017", opts...)
+		require.NoError(t, err)
+		defer session.Close()
+		session.DB("my_db").C("MyCollection").Remove(entity)
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		return spans[0]
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		s := remove(t)
+		assert.Nil(t, s.Tag("mongodb.query"))
+		assert.Equal(t, "mongodb.query MyCollection", s.Tag(ext.ResourceName))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		s := remove(t, WithQueryCapture(true))
+		assert.Equal(t, `{"name":"?"}`, s.Tag("mongodb.query"))
+		assert.Equal(t, `{"name":"?"}`, s.Tag(ext.ResourceName))
+	})
+}
+
+func TestWithQuery(t *testing.T) {
+	tags := map[string]string{ext.MongoDBCollection: "MyCollection"}
+
+	t.Run("obfuscated", func(t *testing.T) {
+		cfg := newConfig()
+		WithQueryCapture(true)(cfg)
+		qtags := withQuery(cfg, tags, updateQuery(bson.M{"name": "John"}, bson.M{"$set": bson.M{"age": 42}}))
+		assert.Equal(t, map[string]string{
+			ext.MongoDBCollection: "MyCollection",
+			"mongodb.query":       `{"q":{"name":"?"},"u":{"$set":{"age":"?"}}}`,
+		}, qtags)
+		assert.Len(t, tags, 1)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		cfg := newConfig()
+		WithQueryCapture(true)(cfg)
+		WithMaxQuerySize(10)(cfg)
+		qtags := withQuery(cfg, tags, bson.M{"name": "John"})
+		assert.Equal(t, `{"name":"?...`, qtags["mongodb.query"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Equal(t, tags, withQuery(newConfig(), tags, bson.M{"name": "John"}))
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		cfg := newConfig()
+		WithQueryCapture(true)(cfg)
+		assert.Equal(t, tags, withQuery(cfg, tags, nil))
+	})
+
+	t.Run("documents", func(t *testing.T) {
+		cfg := newConfig()
+		WithQueryCapture(true)(cfg)
+		WithMaxQuerySize(30)(cfg)
+		docs := make([]interface{}, 100)
+		for i := range docs {
+			docs[i] = bson.M{}
+		}
+		// the documents left out would have been truncated anyway
+		capped := capDocuments(cfg, docs)
+		assert.Len(t, capped, 11)
+		full := withQuery(cfg, tags, bson.D{{Name: "documents", Value: docs}})
+		assert.Equal(t, full, withQuery(cfg, tags, bson.D{{Name: "documents", Value: capped}}))
+	})
+}
@@ -12,10 +12,16 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 )
 
+// defaultMaxQuerySize is the default maximum size of the queries reported in
+// the spans.
+const defaultMaxQuerySize = 1024
+
 type mongoConfig struct {
 	ctx           context.Context
 	serviceName   string
 	analyticsRate float64
+	queryCapture  bool
+	maxQuerySize  int
 }
 
 func newConfig() *mongoConfig {
@@ -28,6 +34,7 @@ func newConfig() *mongoConfig {
 		ctx:         context.Background(),
 		// analyticsRate: globalconfig.AnalyticsRate(),
 		analyticsRate: rate,
+		maxQuerySize:  defaultMaxQuerySize,
 	}
 }
 
@@ -70,3 +77,21 @@ func WithAnalyticsRate(rate float64) DialOption {
 		}
 	}
 }
+
+// WithQueryCapture specifies whether the obfuscated queries should be reported
+// in the mongodb.query tag of the spans, and used as their resource name.
+// Defaults to false.
+func WithQueryCapture(on bool) DialOption {
+	return func(cfg *mongoConfig) {
+		cfg.queryCapture = on
+	}
+}
+
+// WithMaxQuerySize sets the maximum size, in bytes, of the obfuscated queries
+// reported in the spans. Longer queries are truncated. A size of zero or less
+// disables the truncation. Defaults to 1024.
+func WithMaxQuerySize(size int) DialOption {
+	return func(cfg *mongoConfig) {
+		cfg.maxQuerySize = size
+	}
+}
@@ -17,16 +17,31 @@ import (
 func withAppsec(next http.Handler, r *http.Request, span tracer.Span) http.Handler {
 	rctx := chi.RouteContext(r.Context())
 	if rctx == nil {
-		return httpsec.WrapHandler(next, span, nil)
+		return httpsec.WrapHandler(next, span, nil, "")
 	}
 	var pathParams map[string]string
 	keys := rctx.URLParams.Keys
 	values := rctx.URLParams.Values
+	route := rctx.RoutePattern()
+	if len(keys) == 0 && rctx.Routes != nil {
+		// The middleware was registered with Use() and therefore runs before the routing. Match the route on a
+		// separate routing context in order to retrieve its path parameters without altering the actual routing.
+		path := r.URL.RawPath
+		if path == "" {
+			path = r.URL.Path
+		}
+		mctx := chi.NewRouteContext()
+		if rctx.Routes.Match(mctx, r.Method, path) {
+			keys = mctx.URLParams.Keys
+			values = mctx.URLParams.Values
+			route = mctx.RoutePattern()
+		}
+	}
 	if len(keys) > 0 && len(keys) == len(values) {
 		pathParams = make(map[string]string, len(keys))
 		for i, key := range keys {
 			pathParams[key] = values[i]
 		}
 	}
-	return httpsec.WrapHandler(next, span, pathParams)
+	return httpsec.WrapHandler(next, span, pathParams, route)
 }
@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	pappsec "gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -439,3 +440,13 @@ func TestAppSec(t *testing.T) {
 		require.True(t, strings.Contains(event.(string), "crs-933-130"))
 	})
 }
+
+func TestAppSecConformance(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware())
+	router.Get(appsectest.Route, func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(appsectest.Body))
+	})
+	router.Method(http.MethodPost, appsectest.LoginRoute, appsectest.LoginHandler)
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(router), Login: true})
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"net/http"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
@@ -36,7 +37,7 @@ func defaults(cfg *config) {
 	} else {
 		cfg.analyticsRate = globalconfig.AnalyticsRate()
 	}
-	cfg.isStatusError = isServerError
+	cfg.isStatusError = httptrace.IsServerError
 	cfg.ignoreRequest = func(_ *http.Request) bool { return false }
 	cfg.modifyResourceName = func(s string) string { return s }
 }
@@ -87,10 +88,6 @@ func WithStatusCheck(fn func(statusCode int) bool) Option {
 	}
 }
 
-func isServerError(statusCode int) bool {
-	return statusCode >= 500 && statusCode < 600
-}
-
 // WithIgnoreRequest specifies a function to use for determining if the
 // incoming HTTP request tracing should be skipped.
 func WithIgnoreRequest(fn func(r *http.Request) bool) Option {
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package redis
+
+import (
+	"math"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+)
+
+// defaultMaxResourceLength is the default maximum length of the resource of
+// the spans, above which it is truncated.
+const defaultMaxResourceLength = 100
+
+type clientConfig struct {
+	serviceName       string
+	analyticsRate     float64
+	skipRaw           bool
+	obfuscateArgs     bool
+	maxResourceLength int
+}
+
+// ClientOption represents an option that can be used to create or wrap a client.
+type ClientOption func(*clientConfig)
+
+func defaults(cfg *clientConfig) {
+	cfg.serviceName = "redis.client"
+	// cfg.analyticsRate = globalconfig.AnalyticsRate()
+	if internal.BoolEnv("DD_TRACE_REDIS_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	} else {
+		cfg.analyticsRate = math.NaN()
+	}
+	cfg.maxResourceLength = defaultMaxResourceLength
+}
+
+// WithSkipRawCommand reports whether to skip setting the "redis.raw_command" tag
+// on instrumenation spans. This may be useful if the Datadog Agent is not
+// set up to obfuscate this value and it could contain sensitive information.
+func WithSkipRawCommand(skip bool) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.skipRaw = skip
+	}
+}
+
+// WithObfuscatedArgs reports whether to replace the arguments of the commands
+// with "?" in the "redis.raw_command" tag, keeping only the command names
+// (e.g. "set ? ?"). Unlike WithSkipRawCommand, the commands remain visible
+// while their keys and values aren't sent to the agent.
+func WithObfuscatedArgs(on bool) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.obfuscateArgs = on
+	}
+}
+
+// WithMaxResourceLength sets the maximum length of the resource of the spans,
+// above which it is truncated. The resource of a command span is its name, and
+// the one of a pipeline span is the names of its commands. A length of 0 or
+// less disables the truncation. Defaults to 100.
+func WithMaxResourceLength(length int) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.maxResourceLength = length
+	}
+}
+
+// WithServiceName sets the given service name for the client.
+func WithServiceName(name string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.serviceName = name
+	}
+}
+
+// WithAnalytics enables Trace Analytics for all started spans.
+func WithAnalytics(on bool) ClientOption {
+	return func(cfg *clientConfig) {
+		if on {
+			cfg.analyticsRate = 1.0
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func WithAnalyticsRate(rate float64) ClientOption {
+	return func(cfg *clientConfig) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package redis provides tracing functions for tracing the redis/go-redis package (https://github.com/redis/go-redis).
+// This package supports go-redis v9, whose hooks replaced the ones of the previous versions.
+package redis
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const componentName = "go-redis/redis.v9"
+
+type datadogHook struct {
+	*params
+}
+
+// params holds the tracer and a set of parameters which are recorded with every trace.
+type params struct {
+	config         *clientConfig
+	additionalTags []ddtrace.StartSpanOption
+}
+
+// NewClient returns a new Client that is traced with the default tracer under
+// the service name "redis".
+func NewClient(opt *redis.Options, opts ...ClientOption) redis.UniversalClient {
+	client := redis.NewClient(opt)
+	WrapClient(client, opts...)
+	return client
+}
+
+// WrapClient adds a hook to the given client that traces with the default tracer under
+// the service name "redis".
+func WrapClient(client redis.UniversalClient, opts ...ClientOption) {
+	cfg := new(clientConfig)
+	defaults(cfg)
+	for _, fn := range opts {
+		fn(cfg)
+	}
+
+	hookParams := &params{
+		additionalTags: additionalTagOptions(client),
+		config:         cfg,
+	}
+
+	client.AddHook(&datadogHook{params: hookParams})
+}
+
+type clientOptions interface {
+	Options() *redis.Options
+}
+
+type clusterOptions interface {
+	Options() *redis.ClusterOptions
+}
+
+func additionalTagOptions(client redis.UniversalClient) []ddtrace.StartSpanOption {
+	additionalTags := []ddtrace.StartSpanOption{}
+	if clientOptions, ok := client.(clientOptions); ok {
+		opt := clientOptions.Options()
+		if opt.Addr == "FailoverClient" {
+			additionalTags = []ddtrace.StartSpanOption{
+				tracer.Tag("out.db", strconv.Itoa(opt.DB)),
+			}
+		} else {
+			host, port, err := net.SplitHostPort(opt.Addr)
+			if err != nil {
+				host = opt.Addr
+				port = "6379"
+			}
+			additionalTags = []ddtrace.StartSpanOption{
+				tracer.Tag(ext.TargetHost, host),
+				tracer.Tag(ext.TargetPort, port),
+				tracer.Tag("out.db", strconv.Itoa(opt.DB)),
+			}
+		}
+	} else if clientOptions, ok := client.(clusterOptions); ok {
+		addrs := []string{}
+		for _, addr := range clientOptions.Options().Addrs {
+			addrs = append(addrs, addr)
+		}
+		additionalTags = []ddtrace.StartSpanOption{
+			tracer.Tag("addrs", strings.Join(addrs, ", ")),
+		}
+	}
+	return additionalTags
+}
+
+// startOptions returns the options common to the spans started by the hook,
+// followed by the given ones so that they take precedence.
+func (p *params) startOptions(opts ...ddtrace.StartSpanOption) []ddtrace.StartSpanOption {
+	startOpts := make([]ddtrace.StartSpanOption, 0, 6+len(p.additionalTags)+len(opts))
+	startOpts = append(startOpts,
+		tracer.SpanType(ext.SpanTypeRedis),
+		tracer.ServiceName(p.config.serviceName),
+		tracer.Tag(ext.Component, componentName),
+		tracer.Tag(ext.SpanKind, ext.SpanKindClient),
+		tracer.Tag(ext.DBSystem, ext.DBSystemRedis),
+	)
+	startOpts = append(startOpts, p.additionalTags...)
+	if !math.IsNaN(p.config.analyticsRate) {
+		startOpts = append(startOpts, tracer.Tag(ext.EventSampleRate, p.config.analyticsRate))
+	}
+	return append(startOpts, opts...)
+}
+
+// DialHook traces the connections made to the redis servers.
+func (ddh *datadogHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		opts := []ddtrace.StartSpanOption{
+			tracer.ResourceName("dial"),
+			tracer.Tag("network", network),
+		}
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			// the dialed address may differ from the configured one
+			opts = append(opts, tracer.Tag(ext.TargetHost, host), tracer.Tag(ext.TargetPort, port))
+		}
+		span, ctx := tracer.StartSpanFromContext(ctx, "redis.dial", ddh.startOptions(opts...)...)
+		conn, err := next(ctx, network, addr)
+		span.Finish(tracer.WithError(err))
+		return conn, err
+	}
+}
+
+// ProcessHook traces the commands sent to the redis servers.
+func (ddh *datadogHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		p := ddh.params
+		opts := []ddtrace.StartSpanOption{
+			tracer.ResourceName(p.resource(cmd.Name())),
+			tracer.Tag("redis.args_length", strconv.Itoa(len(cmd.Args()))),
+		}
+		if !p.config.skipRaw {
+			opts = append(opts, tracer.Tag("redis.raw_command", p.rawCommand(cmd)))
+		}
+		span, ctx := tracer.StartSpanFromContext(ctx, "redis.command", p.startOptions(opts...)...)
+		err := next(ctx, cmd)
+		var finishOpts []ddtrace.FinishOption
+		if err != redis.Nil {
+			finishOpts = append(finishOpts, tracer.WithError(err))
+		}
+		span.Finish(finishOpts...)
+		return err
+	}
+}
+
+// ProcessPipelineHook traces the pipelines sent to the redis servers.
+func (ddh *datadogHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		p := ddh.params
+		names := make([]string, len(cmds))
+		var length int
+		for i, cmd := range cmds {
+			names[i] = cmd.Name()
+			length += len(cmd.Args())
+		}
+		opts := []ddtrace.StartSpanOption{
+			tracer.ResourceName(p.resource(strings.Join(names, " "))),
+			tracer.Tag("redis.args_length", strconv.Itoa(length)),
+			tracer.Tag("redis.pipeline_length", strconv.Itoa(len(cmds))),
+		}
+		if !p.config.skipRaw {
+			opts = append(opts, tracer.Tag("redis.raw_command", p.commandsToString(cmds)))
+		}
+		span, ctx := tracer.StartSpanFromContext(ctx, "redis.command", p.startOptions(opts...)...)
+		err := next(ctx, cmds)
+		span.Finish(tracer.WithError(pipelineError(err, cmds)))
+		return err
+	}
+}
+
+// pipelineError returns the error to report on the span of a pipeline, which is
+// the error returned by the pipeline or else the first error of its commands.
+// redis.Nil errors are ignored, as they only mean that keys were not found.
+func pipelineError(err error, cmds []redis.Cmder) error {
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resource returns the given resource, truncated to the configured maximum length.
+func (p *params) resource(resource string) string {
+	if max := p.config.maxResourceLength; max > 0 && len(resource) > max {
+		return resource[:max]
+	}
+	return resource
+}
+
+// rawCommand returns the string representation of the command, with its
+// arguments obfuscated when configured.
+func (p *params) rawCommand(cmd redis.Cmder) string {
+	if !p.config.obfuscateArgs {
+		return cmd.String()
+	}
+	return obfuscateArgs(cmd)
+}
+
+// obfuscateArgs returns the full name of the command, followed by a "?" for each
+// of its remaining arguments.
+func obfuscateArgs(cmd redis.Cmder) string {
+	name := cmd.FullName()
+	var b strings.Builder
+	b.WriteString(name)
+	for i := strings.Count(name, " ") + 1; i < len(cmd.Args()); i++ {
+		b.WriteString(" ?")
+	}
+	return b.String()
+}
+
+// commandsToString returns a string representation of a slice of redis Commands, separated by newlines.
+func (p *params) commandsToString(cmds []redis.Cmder) string {
+	var b bytes.Buffer
+	for _, cmd := range cmds {
+		b.WriteString(p.rawCommand(cmd))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
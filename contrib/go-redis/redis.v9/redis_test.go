@@ -0,0 +1,709 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+const debug = false
+
+// ensure it's a redis.Hook
+var _ redis.Hook = (*datadogHook)(nil)
+
+func TestMain(m *testing.M) {
+	_, ok := os.LookupEnv("INTEGRATION")
+	if !ok {
+		fmt.Println("--- SKIP: to enable integration test, set the INTEGRATION environment variable")
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// finishedSpans returns the spans finished by mt, leaving out the dial spans
+// of the connections made by the clients.
+func finishedSpans(mt mocktracer.Tracer) []mocktracer.Span {
+	var spans []mocktracer.Span
+	for _, s := range mt.FinishedSpans() {
+		if s.OperationName() != "redis.dial" {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestSkipRaw(t *testing.T) {
+	runCmds := func(t *testing.T, opts ...ClientOption) []mocktracer.Span {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		ctx := context.Background()
+		client := NewClient(&redis.Options{Addr: "127.0.0.1:6379"}, opts...)
+		client.Set(ctx, "test_key", "test_value", 0)
+		pipeline := client.Pipeline()
+		pipeline.Expire(ctx, "pipeline_counter", time.Hour)
+		pipeline.Exec(ctx)
+		spans := finishedSpans(mt)
+		assert.Len(t, spans, 2)
+		return spans
+	}
+
+	t.Run("true", func(t *testing.T) {
+		spans := runCmds(t, WithSkipRawCommand(true))
+		for _, span := range spans {
+			raw, ok := span.Tags()["redis.raw_command"]
+			assert.False(t, ok)
+			assert.Empty(t, raw)
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		spans := runCmds(t)
+		raw, ok := spans[0].Tags()["redis.raw_command"]
+		assert.True(t, ok)
+		assert.Equal(t, "set test_key test_value: ", raw)
+		raw, ok = spans[1].Tags()["redis.raw_command"]
+		assert.True(t, ok)
+		assert.Equal(t, "expire pipeline_counter 3600: false\n", raw)
+	})
+
+	t.Run("false", func(t *testing.T) {
+		spans := runCmds(t, WithSkipRawCommand(false))
+		raw, ok := spans[0].Tags()["redis.raw_command"]
+		assert.True(t, ok)
+		assert.Equal(t, "set test_key test_value: ", raw)
+		raw, ok = spans[1].Tags()["redis.raw_command"]
+		assert.True(t, ok)
+		assert.Equal(t, "expire pipeline_counter 3600: false\n", raw)
+	})
+}
+
+func TestClientEvalSha(t *testing.T) {
+	ctx := context.Background()
+	opts := &redis.Options{Addr: "127.0.0.1:6379"}
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	client := NewClient(opts, WithServiceName("my-redis"))
+
+	sha1 := client.ScriptLoad(ctx, "return {KEYS[1],KEYS[2],ARGV[1],ARGV[2]}").Val()
+	mt.Reset()
+
+	client.EvalSha(ctx, sha1, []string{"key1", "key2", "first", "second"})
+
+	spans := finishedSpans(mt)
+	assert.Len(spans, 1)
+
+	span := spans[0]
+	assert.Equal("redis.command", span.OperationName())
+	assert.Equal(ext.SpanTypeRedis, span.Tag(ext.SpanType))
+	assert.Equal("my-redis", span.Tag(ext.ServiceName))
+	assert.Equal("127.0.0.1", span.Tag(ext.TargetHost))
+	assert.Equal("6379", span.Tag(ext.TargetPort))
+	assert.Equal("evalsha", span.Tag(ext.ResourceName))
+	assert.Equal("go-redis/redis.v9", span.Tag(ext.Component))
+	assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+	assert.Equal("redis", span.Tag(ext.DBSystem))
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	opts := &redis.Options{Addr: "127.0.0.1:6379"}
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	client := NewClient(opts, WithServiceName("my-redis"))
+	client.Set(ctx, "test_key", "test_value", 0)
+
+	spans := finishedSpans(mt)
+	assert.Len(spans, 1)
+
+	span := spans[0]
+	assert.Equal("redis.command", span.OperationName())
+	assert.Equal(ext.SpanTypeRedis, span.Tag(ext.SpanType))
+	assert.Equal("my-redis", span.Tag(ext.ServiceName))
+	assert.Equal("127.0.0.1", span.Tag(ext.TargetHost))
+	assert.Equal("6379", span.Tag(ext.TargetPort))
+	assert.Equal("set test_key test_value: ", span.Tag("redis.raw_command"))
+	assert.Equal("3", span.Tag("redis.args_length"))
+	assert.Equal("go-redis/redis.v9", span.Tag(ext.Component))
+	assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+	assert.Equal("redis", span.Tag(ext.DBSystem))
+}
+
+func TestWrapClient(t *testing.T) {
+	simpleClientOpts := &redis.UniversalOptions{Addrs: []string{"127.0.0.1:6379"}}
+	simpleClient := redis.NewUniversalClient(simpleClientOpts)
+
+	failoverClientOpts := &redis.UniversalOptions{
+		MasterName: "leader.redis.host",
+		Addrs: []string{
+			"127.0.0.1:6379",
+			"127.0.0.2:6379",
+		}}
+	failoverClient := redis.NewUniversalClient(failoverClientOpts)
+
+	clusterClientOpts := &redis.UniversalOptions{
+		Addrs: []string{
+			"127.0.0.1:6379",
+			"127.0.0.2:6379",
+		},
+		DialTimeout: 1}
+	clusterClient := redis.NewUniversalClient(clusterClientOpts)
+
+	testCases := []struct {
+		name   string
+		client redis.UniversalClient
+	}{
+		{
+			name:   "simple-client",
+			client: simpleClient,
+		},
+		{
+			name:   "failover-client",
+			client: failoverClient,
+		},
+		{
+			name:   "cluster-client",
+			client: clusterClient,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			assert := assert.New(t)
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			WrapClient(tc.client, WithServiceName("my-redis"))
+			tc.client.Set(ctx, "test_key", "test_value", 0)
+
+			spans := finishedSpans(mt)
+			assert.Len(spans, 1)
+
+			span := spans[0]
+			assert.Equal("redis.command", span.OperationName())
+			assert.Equal(ext.SpanTypeRedis, span.Tag(ext.SpanType))
+			assert.Equal("my-redis", span.Tag(ext.ServiceName))
+			assert.Equal("set test_key test_value: ", span.Tag("redis.raw_command"))
+			assert.Equal("3", span.Tag("redis.args_length"))
+			assert.Equal("go-redis/redis.v9", span.Tag(ext.Component))
+			assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+			assert.Equal("redis", span.Tag(ext.DBSystem))
+		})
+	}
+}
+
+func TestAdditionalTagsFromClient(t *testing.T) {
+	t.Run("simple-client", func(t *testing.T) {
+		simpleClientOpts := &redis.UniversalOptions{Addrs: []string{"127.0.0.1:6379"}}
+		simpleClient := redis.NewUniversalClient(simpleClientOpts)
+		config := &ddtrace.StartSpanConfig{}
+		expectedTags := map[string]interface{}{
+			"out.db":   "0",
+			"out.host": "127.0.0.1",
+			"out.port": "6379",
+		}
+
+		additionalTagOptions := additionalTagOptions(simpleClient)
+		for _, t := range additionalTagOptions {
+			t(config)
+		}
+		assert.Equal(t, expectedTags, config.Tags)
+	})
+
+	t.Run("failover-client", func(t *testing.T) {
+		failoverClientOpts := &redis.UniversalOptions{
+			MasterName: "leader.redis.host",
+			Addrs: []string{
+				"127.0.0.1:6379",
+				"127.0.0.2:6379",
+			}}
+		failoverClient := redis.NewUniversalClient(failoverClientOpts)
+		config := &ddtrace.StartSpanConfig{}
+		expectedTags := map[string]interface{}{
+			"out.db": "0",
+		}
+
+		additionalTagOptions := additionalTagOptions(failoverClient)
+		for _, t := range additionalTagOptions {
+			t(config)
+		}
+		assert.Equal(t, expectedTags, config.Tags)
+	})
+
+	t.Run("cluster-client", func(t *testing.T) {
+		clusterClientOpts := &redis.UniversalOptions{
+			Addrs: []string{
+				"127.0.0.1:6379",
+				"127.0.0.2:6379",
+			},
+			DialTimeout: 1}
+		clusterClient := redis.NewUniversalClient(clusterClientOpts)
+		config := &ddtrace.StartSpanConfig{}
+		expectedTags := map[string]interface{}{
+			"addrs": "127.0.0.1:6379, 127.0.0.2:6379",
+		}
+
+		additionalTagOptions := additionalTagOptions(clusterClient)
+		for _, t := range additionalTagOptions {
+			t(config)
+		}
+		assert.Equal(t, expectedTags, config.Tags)
+	})
+}
+
+func TestPipeline(t *testing.T) {
+	ctx := context.Background()
+	opts := &redis.Options{Addr: "127.0.0.1:6379"}
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	client := NewClient(opts, WithServiceName("my-redis"))
+	pipeline := client.Pipeline()
+	pipeline.Expire(ctx, "pipeline_counter", time.Hour)
+
+	// Exec with context test
+	pipeline.Exec(ctx)
+
+	spans := finishedSpans(mt)
+	assert.Len(spans, 1)
+
+	span := spans[0]
+	assert.Equal("redis.command", span.OperationName())
+	assert.Equal(ext.SpanTypeRedis, span.Tag(ext.SpanType))
+	assert.Equal("my-redis", span.Tag(ext.ServiceName))
+	assert.Equal("expire", span.Tag(ext.ResourceName))
+	assert.Equal("127.0.0.1", span.Tag(ext.TargetHost))
+	assert.Equal("6379", span.Tag(ext.TargetPort))
+	assert.Equal("1", span.Tag("redis.pipeline_length"))
+	assert.Equal("go-redis/redis.v9", span.Tag(ext.Component))
+	assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+	assert.Equal("redis", span.Tag(ext.DBSystem))
+
+	mt.Reset()
+	pipeline.Expire(ctx, "pipeline_counter", time.Hour)
+	pipeline.Expire(ctx, "pipeline_counter_1", time.Minute)
+
+	// Rewriting Exec
+	pipeline.Exec(ctx)
+
+	spans = finishedSpans(mt)
+	assert.Len(spans, 1)
+
+	span = spans[0]
+	assert.Equal("redis.command", span.OperationName())
+	assert.Equal(ext.SpanTypeRedis, span.Tag(ext.SpanType))
+	assert.Equal("my-redis", span.Tag(ext.ServiceName))
+	assert.Equal("expire expire", span.Tag(ext.ResourceName))
+	assert.Equal("2", span.Tag("redis.pipeline_length"))
+	assert.Equal("go-redis/redis.v9", span.Tag(ext.Component))
+	assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+	assert.Equal("redis", span.Tag(ext.DBSystem))
+}
+
+func TestChildSpan(t *testing.T) {
+	ctx := context.Background()
+	opts := &redis.Options{Addr: "127.0.0.1:6379"}
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	// Parent span
+	client := NewClient(opts, WithServiceName("my-redis"))
+	root, ctx := tracer.StartSpanFromContext(ctx, "parent.span")
+
+	client.Set(ctx, "test_key", "test_value", 0)
+	root.Finish()
+
+	spans := finishedSpans(mt)
+	assert.Len(spans, 2)
+
+	var child, parent mocktracer.Span
+	for _, s := range spans {
+		// order of traces in buffer is not garanteed
+		switch s.OperationName() {
+		case "redis.command":
+			child = s
+		case "parent.span":
+			parent = s
+		}
+	}
+	assert.NotNil(parent)
+	assert.NotNil(child)
+
+	assert.Equal(child.ParentID(), parent.SpanID())
+	assert.Equal(child.Tag(ext.TargetHost), "127.0.0.1")
+	assert.Equal(child.Tag(ext.TargetPort), "6379")
+}
+
+func TestMultipleCommands(t *testing.T) {
+	ctx := context.Background()
+	opts := &redis.Options{Addr: "127.0.0.1:6379"}
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	client := NewClient(opts, WithServiceName("my-redis"))
+	client.Set(ctx, "test_key", "test_value", 0)
+	client.Get(ctx, "test_key")
+	client.Incr(ctx, "int_key")
+	client.ClientList(ctx)
+
+	spans := finishedSpans(mt)
+	assert.Len(spans, 4)
+
+	// Checking all commands were recorded
+	var commands [4]string
+	for i := 0; i < 4; i++ {
+		commands[i] = spans[i].Tag("redis.raw_command").(string)
+	}
+	assert.Contains(commands, "set test_key test_value: ")
+	assert.Contains(commands, "get test_key: ")
+	assert.Contains(commands, "incr int_key: 0")
+	assert.Contains(commands, "client list: ")
+}
+
+func TestError(t *testing.T) {
+	t.Run("wrong-port", func(t *testing.T) {
+		ctx := context.Background()
+		opts := &redis.Options{Addr: "127.0.0.1:6378"} // wrong port
+		assert := assert.New(t)
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		client := NewClient(opts, WithServiceName("my-redis"))
+		_, err := client.Get(ctx, "key").Result()
+
+		spans := finishedSpans(mt)
+		assert.Len(spans, 1)
+		span := spans[0]
+
+		assert.Equal("redis.command", span.OperationName())
+		assert.NotNil(err)
+		assert.Equal(err, span.Tag(ext.Error))
+		assert.Equal("127.0.0.1", span.Tag(ext.TargetHost))
+		assert.Equal("6378", span.Tag(ext.TargetPort))
+		assert.Equal("get key: ", span.Tag("redis.raw_command"))
+		assert.Equal("go-redis/redis.v9", span.Tag(ext.Component))
+		assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+		assert.Equal("redis", span.Tag(ext.DBSystem))
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		ctx := context.Background()
+		opts := &redis.Options{Addr: "127.0.0.1:6379"}
+		assert := assert.New(t)
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		client := NewClient(opts, WithServiceName("my-redis"))
+		_, err := client.Get(ctx, "non_existent_key").Result()
+
+		spans := finishedSpans(mt)
+		assert.Len(spans, 1)
+		span := spans[0]
+
+		assert.Equal(redis.Nil, err)
+		assert.Equal("redis.command", span.OperationName())
+		assert.Empty(span.Tag(ext.Error))
+		assert.Equal("127.0.0.1", span.Tag(ext.TargetHost))
+		assert.Equal("6379", span.Tag(ext.TargetPort))
+		assert.Equal("get non_existent_key: ", span.Tag("redis.raw_command"))
+		assert.Equal("go-redis/redis.v9", span.Tag(ext.Component))
+		assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
+		assert.Equal("redis", span.Tag(ext.DBSystem))
+	})
+
+	t.Run("pipeline", func(t *testing.T) {
+		ctx := context.Background()
+		opts := &redis.Options{Addr: "127.0.0.1:6379"}
+		assert := assert.New(t)
+		client := redis.NewClient(opts)
+		defer client.Close()
+		client.Set(ctx, "pipeline_string_key", "value", 0)
+		WrapClient(client)
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		pipeline := client.Pipeline()
+		pipeline.Get(ctx, "non_existent_key")
+		incr := pipeline.Incr(ctx, "pipeline_string_key")
+		pipeline.LPush(ctx, "pipeline_string_key", "value")
+		pipeline.Exec(ctx)
+
+		spans := finishedSpans(mt)
+		assert.Len(spans, 1)
+		// only the first error which is not redis.Nil is reported
+		assert.NotNil(incr.Err())
+		assert.Equal(incr.Err(), spans[0].Tag(ext.Error))
+	})
+}
+func TestAnalyticsSettings(t *testing.T) {
+	assertRate := func(t *testing.T, mt mocktracer.Tracer, rate interface{}, opts ...ClientOption) {
+		ctx := context.Background()
+		client := NewClient(&redis.Options{Addr: "127.0.0.1:6379"}, opts...)
+		client.Set(ctx, "test_key", "test_value", 0)
+		pipeline := client.Pipeline()
+		pipeline.Expire(ctx, "pipeline_counter", time.Hour)
+		pipeline.Exec(ctx)
+
+		spans := finishedSpans(mt)
+		assert.Len(t, spans, 2)
+		for _, s := range spans {
+			assert.Equal(t, rate, s.Tag(ext.EventSampleRate))
+		}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		assertRate(t, mt, nil)
+	})
+
+	t.Run("global", func(t *testing.T) {
+		t.Skip("global flag disabled")
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		rate := globalconfig.AnalyticsRate()
+		defer globalconfig.SetAnalyticsRate(rate)
+		globalconfig.SetAnalyticsRate(0.4)
+
+		assertRate(t, mt, 0.4)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		assertRate(t, mt, 1.0, WithAnalytics(true))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		assertRate(t, mt, nil, WithAnalytics(false))
+	})
+
+	t.Run("override", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		rate := globalconfig.AnalyticsRate()
+		defer globalconfig.SetAnalyticsRate(rate)
+		globalconfig.SetAnalyticsRate(0.4)
+
+		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		assertRate(t, mt, 0.0, WithAnalyticsRate(0.0))
+	})
+}
+
+func TestWithContext(t *testing.T) {
+	opts := &redis.Options{Addr: "127.0.0.1:6379"}
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ctx1 := context.Background()
+	ctx2 := context.Background()
+	client1 := NewClient(opts, WithServiceName("my-redis"))
+	s1, ctx1 := tracer.StartSpanFromContext(ctx1, "span1.name")
+
+	s2, ctx2 := tracer.StartSpanFromContext(ctx2, "span2.name")
+	client2 := NewClient(opts, WithServiceName("my-redis"))
+	client1.Set(ctx1, "test_key", "test_value", 0)
+	client2.Get(ctx2, "test_key")
+	s1.Finish()
+	s2.Finish()
+
+	spans := finishedSpans(mt)
+	assert.Len(spans, 4)
+	var span1, span2, setSpan, getSpan mocktracer.Span
+	for _, s := range spans {
+		switch s.Tag(ext.ResourceName) {
+		case "span1.name":
+			span1 = s
+		case "span2.name":
+			span2 = s
+		case "set":
+			setSpan = s
+		case "get":
+			getSpan = s
+		}
+	}
+
+	assert.NotNil(span1)
+	assert.NotNil(span2)
+	assert.NotNil(setSpan)
+	assert.NotNil(getSpan)
+	assert.Equal(span1.SpanID(), setSpan.ParentID())
+	assert.Equal(span2.SpanID(), getSpan.ParentID())
+}
+
+func TestObfuscatedArgs(t *testing.T) {
+	ctx := context.Background()
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	client := NewClient(&redis.Options{Addr: "127.0.0.1:6379"}, WithObfuscatedArgs(true))
+	client.Set(ctx, "test_key", "test_value", 0)
+	client.ClusterInfo(ctx)
+	pipeline := client.Pipeline()
+	pipeline.Incr(ctx, "pipeline_counter")
+	pipeline.Expire(ctx, "pipeline_counter", time.Hour)
+	pipeline.Exec(ctx)
+
+	spans := finishedSpans(mt)
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "set ? ?", spans[0].Tag("redis.raw_command"))
+	assert.Equal(t, "set", spans[0].Tag(ext.ResourceName))
+	assert.Equal(t, "3", spans[0].Tag("redis.args_length"))
+	assert.Equal(t, "cluster info", spans[1].Tag("redis.raw_command"))
+	assert.Equal(t, "incr ?\nexpire ? ?\n", spans[2].Tag("redis.raw_command"))
+	assert.Equal(t, "incr expire", spans[2].Tag(ext.ResourceName))
+}
+
+func TestMaxResourceLength(t *testing.T) {
+	runPipeline := func(t *testing.T, opts ...ClientOption) mocktracer.Span {
+		ctx := context.Background()
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		client := NewClient(&redis.Options{Addr: "127.0.0.1:6379"}, opts...)
+		pipeline := client.Pipeline()
+		for i := 0; i < 30; i++ {
+			pipeline.Incr(ctx, "pipeline_counter")
+		}
+		pipeline.Exec(ctx)
+		spans := finishedSpans(mt)
+		assert.Len(t, spans, 1)
+		return spans[0]
+	}
+
+	t.Run("default", func(t *testing.T) {
+		span := runPipeline(t)
+		assert.Equal(t, strings.Repeat("incr ", 30)[:100], span.Tag(ext.ResourceName))
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		span := runPipeline(t, WithMaxResourceLength(9))
+		assert.Equal(t, "incr incr", span.Tag(ext.ResourceName))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		span := runPipeline(t, WithMaxResourceLength(0))
+		assert.Equal(t, strings.TrimSuffix(strings.Repeat("incr ", 30), " "), span.Tag(ext.ResourceName))
+	})
+}
+
+func TestDial(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		ctx := context.Background()
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		root, ctx := tracer.StartSpanFromContext(ctx, "parent.span")
+		client := NewClient(&redis.Options{Addr: "127.0.0.1:6379"}, WithServiceName("my-redis"))
+		client.Set(ctx, "test_key", "test_value", 0)
+		root.Finish()
+
+		var dial, cmd mocktracer.Span
+		for _, s := range mt.FinishedSpans() {
+			switch s.OperationName() {
+			case "redis.dial":
+				dial = s
+			case "redis.command":
+				cmd = s
+			}
+		}
+		if !assert.NotNil(t, dial) || !assert.NotNil(t, cmd) {
+			return
+		}
+		// the connection is made on behalf of the command
+		assert.Equal(t, cmd.SpanID(), dial.ParentID())
+		assert.Equal(t, "dial", dial.Tag(ext.ResourceName))
+		assert.Equal(t, ext.SpanTypeRedis, dial.Tag(ext.SpanType))
+		assert.Equal(t, "my-redis", dial.Tag(ext.ServiceName))
+		assert.Equal(t, "tcp", dial.Tag("network"))
+		assert.Equal(t, "127.0.0.1", dial.Tag(ext.TargetHost))
+		assert.Equal(t, "6379", dial.Tag(ext.TargetPort))
+		assert.Equal(t, "go-redis/redis.v9", dial.Tag(ext.Component))
+		assert.Equal(t, ext.SpanKindClient, dial.Tag(ext.SpanKind))
+		assert.Equal(t, "redis", dial.Tag(ext.DBSystem))
+		assert.Nil(t, dial.Tag(ext.Error))
+	})
+
+	t.Run("dialed-address", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		// the configured address is tagged on all the spans of the client, but
+		// the dial spans report the address actually dialed by the dialer
+		cfg := new(clientConfig)
+		defaults(cfg)
+		hook := &datadogHook{params: &params{
+			config:         cfg,
+			additionalTags: additionalTagOptions(redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})),
+		}}
+		dialer := func(context.Context, string, string) (net.Conn, error) {
+			conn, _ := net.Pipe()
+			return conn, nil
+		}
+		conn, err := hook.DialHook(dialer)(context.Background(), "tcp", "10.0.0.1:7000")
+		assert.NoError(t, err)
+		conn.Close()
+
+		spans := mt.FinishedSpans()
+		assert.Len(t, spans, 1)
+		assert.Equal(t, "10.0.0.1", spans[0].Tag(ext.TargetHost))
+		assert.Equal(t, "7000", spans[0].Tag(ext.TargetPort))
+		assert.Equal(t, "0", spans[0].Tag("out.db"))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		ctx := context.Background()
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		client := NewClient(&redis.Options{Addr: "127.0.0.1:6378", MaxRetries: -1}) // wrong port
+		client.Get(ctx, "key")
+
+		var dials []mocktracer.Span
+		for _, s := range mt.FinishedSpans() {
+			if s.OperationName() == "redis.dial" {
+				dials = append(dials, s)
+			}
+		}
+		assert.Len(t, dials, 1)
+		assert.NotNil(t, dials[0].Tag(ext.Error))
+		assert.Equal(t, "6378", dials[0].Tag(ext.TargetPort))
+	})
+}
@@ -16,12 +16,14 @@ import (
 	"strings"
 	"sync"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/truncate"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/event"
 )
 
@@ -38,13 +40,10 @@ type monitor struct {
 
 func (m *monitor) Started(ctx context.Context, evt *event.CommandStartedEvent) {
 	hostname, port := peerInfo(evt)
-	b, _ := bson.MarshalExtJSON(evt.Command, false, false)
 	opts := []ddtrace.StartSpanOption{
 		tracer.SpanType(ext.SpanTypeMongoDB),
 		tracer.ServiceName(m.cfg.serviceName),
-		tracer.ResourceName("mongo." + evt.CommandName),
 		tracer.Tag(ext.DBInstance, evt.DatabaseName),
-		tracer.Tag("mongodb.query", string(b)),
 		tracer.Tag(ext.DBType, "mongo"),
 		tracer.Tag(ext.PeerHostname, hostname),
 		tracer.Tag(ext.PeerPort, port),
@@ -52,6 +51,18 @@ func (m *monitor) Started(ctx context.Context, evt *event.CommandStartedEvent) {
 		tracer.Tag(ext.SpanKind, ext.SpanKindClient),
 		tracer.Tag(ext.DBSystem, ext.DBSystemMongoDB),
 	}
+	resource := "mongo." + evt.CommandName
+	if coll, ok := collection(evt); ok {
+		resource += " " + coll
+		opts = append(opts, tracer.Tag(ext.MongoDBCollection, coll))
+	}
+	if m.cfg.queryCapture {
+		if shape := commandShape(evt.Command, m.cfg.maxQuerySize); shape != "" {
+			resource += " " + shape
+		}
+		opts = append(opts, tracer.Tag("mongodb.query", obfuscateCommand(evt.Command, m.cfg.maxQuerySize)))
+	}
+	opts = append(opts, tracer.ResourceName(resource))
 	if !math.IsNaN(m.cfg.analyticsRate) {
 		opts = append(opts, tracer.Tag(ext.EventSampleRate, m.cfg.analyticsRate))
 	}
@@ -109,6 +120,124 @@ func NewMonitor(opts ...Option) *event.CommandMonitor {
 	}
 }
 
+// collection returns the name of the collection targeted by the command of
+// evt, which is the value of its first element for most commands.
+func collection(evt *event.CommandStartedEvent) (string, bool) {
+	elem, err := evt.Command.IndexErr(0)
+	if err != nil {
+		return "", false
+	}
+	if elem.Key() == "getMore" {
+		// the first element is the cursor ID
+		return evt.Command.Lookup("collection").StringValueOK()
+	}
+	return elem.Value().StringValueOK()
+}
+
+// obfuscateCommand returns the obfuscated extended JSON of cmd, truncated to
+// size bytes. Only the part of cmd which can fit in size bytes is marshalled,
+// so that large commands, such as bulk inserts, don't cost their full size.
+func obfuscateCommand(cmd bson.Raw, size int) string {
+	var doc interface{} = cmd
+	if size > 0 {
+		if elems, err := cmd.Elements(); err == nil {
+			remaining := size - 2 // {}
+			doc = capElements(elems, &remaining, false)
+		}
+	}
+	b, _ := bson.MarshalExtJSON(doc, false, false)
+	return truncate.String(tracer.ObfuscateMongoDBQuery(string(b)), size)
+}
+
+// shapeExcludedFields are the fields of the commands set by the driver, which
+// aren't part of their shape.
+var shapeExcludedFields = map[string]bool{
+	"lsid":         true,
+	"$db":          true,
+	"$clusterTime": true,
+}
+
+// commandShape returns the obfuscated extended JSON of the fields of cmd
+// following the command name and collection, truncated to size bytes, with its
+// arrays collapsed to their first element and without the fields set by the
+// driver, so that the commands of a same query share the same shape, e.g. the
+// inserts of any number of documents. It returns an empty string when cmd has
+// no such fields.
+func commandShape(cmd bson.Raw, size int) string {
+	elems, err := cmd.Elements()
+	if err != nil || len(elems) < 2 {
+		return ""
+	}
+	remaining := size - 2 // {}
+	if size <= 0 {
+		remaining = math.MaxInt
+	}
+	var shape []bson.RawElement
+	for _, elem := range elems[1:] {
+		if !shapeExcludedFields[elem.Key()] {
+			shape = append(shape, elem)
+		}
+	}
+	if len(shape) == 0 {
+		return ""
+	}
+	b, _ := bson.MarshalExtJSON(capElements(shape, &remaining, true), false, false)
+	return truncate.String(tracer.ObfuscateMongoDBQuery(string(b)), size)
+}
+
+// capElements returns the elements of a document until the size of their
+// obfuscated extended JSON exceeds the given size, which is decreased
+// accordingly. The size of the elements is estimated from their smallest
+// obfuscated form, e.g. `"key":"?"`, so that the elements left out would have
+// been truncated from the query anyway. The arrays are collapsed to their first
+// element when collapse is true.
+func capElements(elems []bson.RawElement, size *int, collapse bool) bson.D {
+	var d bson.D
+	for _, elem := range elems {
+		if *size < 0 {
+			break
+		}
+		key := elem.Key()
+		*size -= len(key) + 3 // "key":
+		d = append(d, bson.E{Key: key, Value: capValue(elem.Value(), size, collapse)})
+	}
+	return d
+}
+
+// capValue returns v, or its elements which fit in size for documents and
+// arrays, see capElements.
+func capValue(v bson.RawValue, size *int, collapse bool) interface{} {
+	switch v.Type {
+	case bsontype.EmbeddedDocument:
+		*size -= 2 // {}
+		elems, err := v.Document().Elements()
+		if err != nil {
+			return v
+		}
+		return capElements(elems, size, collapse)
+	case bsontype.Array:
+		*size -= 2 // []
+		values, err := v.Array().Values()
+		if err != nil {
+			return v
+		}
+		if collapse && len(values) > 1 {
+			values = values[:1]
+		}
+		var a bson.A
+		for _, v := range values {
+			if *size < 0 {
+				break
+			}
+			a = append(a, capValue(v, size, collapse))
+		}
+		return a
+	default:
+		*size -= 3 // "?"
+		return v
+	}
+}
+
 func peerInfo(evt *event.CommandStartedEvent) (hostname, port string) {
 	hostname = evt.ConnectionID
 	port = "27017"
@@ -9,9 +9,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/truncate"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -22,6 +24,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMain(m *testing.M) {
@@ -70,10 +73,13 @@ func Test(t *testing.T) {
 	s := spans[0]
 	assert.Equal(t, ext.SpanTypeMongoDB, s.Tag(ext.SpanType))
 	assert.Equal(t, "mongo", s.Tag(ext.ServiceName))
-	assert.Equal(t, "mongo.insert", s.Tag(ext.ResourceName))
+	assert.True(t, strings.HasPrefix(s.Tag(ext.ResourceName).(string), "mongo.insert test-collection {"))
 	assert.Equal(t, hostname, s.Tag(ext.PeerHostname))
 	assert.Equal(t, port, s.Tag(ext.PeerPort))
-	assert.Contains(t, s.Tag("mongodb.query"), `"test-item":"test-value"`)
+	assert.Contains(t, s.Tag("mongodb.query"), `{"insert":"?",`)
+	assert.Contains(t, s.Tag("mongodb.query"), `"test-item":"?"`)
+	assert.NotContains(t, s.Tag("mongodb.query"), "test-value")
+	assert.Equal(t, "test-collection", s.Tag(ext.MongoDBCollection))
 	assert.Equal(t, "test-database", s.Tag(ext.DBInstance))
 	assert.Equal(t, "mongo", s.Tag(ext.DBType))
 	assert.Equal(t, "go.mongodb.org/mongo-driver/mongo", s.Tag(ext.Component))
@@ -137,3 +143,119 @@ func TestAnalyticsSettings(t *testing.T) {
 		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
 	})
 }
+
+func TestQueryCapture(t *testing.T) {
+	insert := func(t *testing.T, mt mocktracer.Tracer, doc bson.D, opts ...Option) mocktracer.Span {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+
+		mongopts := options.Client()
+		mongopts.Monitor = NewMonitor(opts...)
+		mongopts.ApplyURI("mongodb://localhost:27017/?connect=direct")
+		client, err := mongo.Connect(ctx, mongopts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.
+			Database("test-database").
+			Collection("test-collection").
+			InsertOne(ctx, doc)
+		assert.NoError(t, err)
+
+		spans := mt.FinishedSpans()
+		assert.Len(t, spans, 1)
+		return spans[0]
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		s := insert(t, mt, bson.D{{Key: "test-item", Value: "test-value"}}, WithQueryCapture(false))
+		assert.Equal(t, "mongo.insert test-collection", s.Tag(ext.ResourceName))
+		assert.Nil(t, s.Tag("mongodb.query"))
+		assert.Equal(t, "test-collection", s.Tag(ext.MongoDBCollection))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		s := insert(t, mt, bson.D{{Key: "test-item", Value: "test-value"}}, WithMaxQuerySize(20))
+		query := s.Tag("mongodb.query").(string)
+		assert.Len(t, query, 23)
+		assert.True(t, strings.HasPrefix(query, `{"insert":"?",`))
+		assert.True(t, strings.HasSuffix(query, "..."))
+		assert.True(t, strings.HasPrefix(s.Tag(ext.ResourceName).(string), "mongo.insert test-collection "))
+	})
+
+	t.Run("insert-many", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+		defer cancel()
+		mongopts := options.Client()
+		mongopts.Monitor = NewMonitor()
+		mongopts.ApplyURI("mongodb://localhost:27017/?connect=direct")
+		client, err := mongo.Connect(ctx, mongopts)
+		require.NoError(t, err)
+		coll := client.Database("test-database").Collection("test-collection")
+		doc := bson.D{{Key: "test-item", Value: "test-value"}}
+		_, err = coll.InsertMany(ctx, []interface{}{doc, doc})
+		require.NoError(t, err)
+		_, err = coll.InsertMany(ctx, []interface{}{doc, doc, doc})
+		require.NoError(t, err)
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 2)
+		// the inserts of any number of documents share the same resource
+		assert.Equal(t, spans[0].Tag(ext.ResourceName), spans[1].Tag(ext.ResourceName))
+		assert.NotEqual(t, spans[0].Tag("mongodb.query"), spans[1].Tag("mongodb.query"))
+	})
+}
+
+func TestCommandShape(t *testing.T) {
+	shape := func(n int) string {
+		docs := make(bson.A, n)
+		for i := range docs {
+			docs[i] = bson.D{{Key: "name", Value: "secret"}, {Key: "tags", Value: bson.A{"a", "b"}}}
+		}
+		cmd, err := bson.Marshal(bson.D{
+			{Key: "insert", Value: "test-collection"},
+			{Key: "documents", Value: docs},
+			{Key: "ordered", Value: true},
+			{Key: "lsid", Value: bson.D{{Key: "id", Value: "session"}}},
+			{Key: "$db", Value: "test-database"},
+		})
+		require.NoError(t, err)
+		return commandShape(cmd, 1024)
+	}
+	assert.Equal(t, shape(2), shape(3))
+	assert.NotContains(t, shape(2), "lsid")
+	assert.NotContains(t, shape(2), "$db")
+	assert.NotContains(t, shape(2), "secret")
+	assert.NotContains(t, shape(2), "test-collection")
+}
+
+func TestObfuscateCommand(t *testing.T) {
+	docs := make(bson.A, 100)
+	for i := range docs {
+		docs[i] = bson.D{{Key: "name", Value: "secret"}, {Key: "tags", Value: bson.A{"a", "b"}}}
+	}
+	cmd, err := bson.Marshal(bson.D{{Key: "insert", Value: "test-collection"}, {Key: "documents", Value: docs}})
+	require.NoError(t, err)
+	b, err := bson.MarshalExtJSON(bson.Raw(cmd), false, false)
+	require.NoError(t, err)
+	full := tracer.ObfuscateMongoDBQuery(string(b))
+
+	for _, size := range []int{0, 10, 30, 100, 1024, len(full), 10000} {
+		// only the part of the command which fits is marshalled, with the same result
+		assert.Equal(t, truncate.String(full, size), obfuscateCommand(cmd, size), "size %d", size)
+	}
+	elems, err := bson.Raw(cmd).Elements()
+	require.NoError(t, err)
+	size := 1024
+	capped := capElements(elems, &size, false)
+	assert.Less(t, len(capped[1].Value.(bson.A)), len(docs))
+}
@@ -11,9 +11,15 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 )
 
+// defaultMaxQuerySize is the default maximum size of the queries reported in
+// the spans.
+const defaultMaxQuerySize = 1024
+
 type config struct {
 	serviceName   string
 	analyticsRate float64
+	queryCapture  bool
+	maxQuerySize  int
 }
 
 // Option represents an option that can be passed to Dial.
@@ -27,6 +33,8 @@ func defaults(cfg *config) {
 	} else {
 		cfg.analyticsRate = math.NaN()
 	}
+	cfg.queryCapture = true
+	cfg.maxQuerySize = defaultMaxQuerySize
 }
 
 // WithServiceName sets the given service name for the dialled connection.
@@ -60,3 +68,22 @@ func WithAnalyticsRate(rate float64) Option {
 		}
 	}
 }
+
+// WithQueryCapture specifies whether the obfuscated commands should be reported
+// in the mongodb.query tag of the spans, and their shape, with their arrays
+// collapsed to their first element, added to their resource name. Defaults to
+// true.
+func WithQueryCapture(on bool) Option {
+	return func(cfg *config) {
+		cfg.queryCapture = on
+	}
+}
+
+// WithMaxQuerySize sets the maximum size, in bytes, of the obfuscated commands
+// reported in the spans. Longer commands are truncated. A size of zero or less
+// disables the truncation. Defaults to 1024.
+func WithMaxQuerySize(size int) Option {
+	return func(cfg *config) {
+		cfg.maxQuerySize = size
+	}
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package fiber
+
+import (
+	"net/http"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/sharedsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// nextWithAppSec monitors the execution of the next fiber handlers with AppSec. Fiber being based on fasthttp, the
+// request is converted into a net/http request, and the response is written through a net/http response writer
+// adapter. The route and its path parameters are known beforehand when the middleware is given along with the route
+// handlers. When the middleware is registered with Use instead, fiber only matches the route once the middleware calls
+// c.Next(), and the route and its path parameters are then monitored once the next handlers returned.
+func nextWithAppSec(c *fiber.Ctx, span tracer.Span) error {
+	var r http.Request
+	if err := fasthttpadaptor.ConvertRequest(c.Context(), &r, true); err != nil {
+		log.Debug("contrib/gofiber/fiber.v2: could not convert the request, appsec will be disabled for this request: %v", err)
+		return c.Next()
+	}
+	r = *r.WithContext(c.UserContext())
+	w := &responseWriter{c: c, header: http.Header{}}
+	rt := c.Route()
+	var err error
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.SetUserContext(r.Context())
+		err = c.Next()
+		if next := c.Route(); next != rt {
+			if monitoringErr := httpsec.MonitorPathParams(r.Context(), next.Path, pathParams(c, next)); monitoringErr != nil {
+				// Discard the response of the handlers so that the blocking response replaces it
+				c.Response().ResetBody()
+				err = monitoringErr
+			}
+		}
+		// Retrieve the response headers set by the fiber handlers for the monitoring of the response
+		c.Response().Header.VisitAll(func(k, v []byte) {
+			w.Header().Add(string(k), string(v))
+		})
+	})
+	httpsec.WrapHandler(handler, span, pathParams(c, rt), rt.Path).ServeHTTP(w, &r)
+	// If the error is a monitoring one, it means appsec actions took care of writing the response. Don't return it
+	// in this case so that the fiber error handler doesn't overwrite the response.
+	if isMonitoringError(err) {
+		return nil
+	}
+	return err
+}
+
+// pathParams returns the path parameters of the given route, which must be the route currently matched by fiber.
+func pathParams(c *fiber.Ctx, rt *fiber.Route) map[string]string {
+	if len(rt.Params) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(rt.Params))
+	for _, p := range rt.Params {
+		params[p] = c.Params(p)
+	}
+	return params
+}
+
+// isMonitoringError returns true if err was returned by an appsec SDK function to block the request.
+func isMonitoringError(err error) bool {
+	switch err.(type) {
+	case *sharedsec.UserMonitoringError, *sharedsec.MonitoringError:
+		return true
+	default:
+		return false
+	}
+}
+
+// responseWriter is a net/http response writer writing to the fiber response, allowing appsec to write the blocking
+// responses and to retrieve the response status code.
+type responseWriter struct {
+	c      *fiber.Ctx
+	header http.Header
+}
+
+// Header returns the response headers.
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader sets the response headers and status code of the fiber response.
+func (w *responseWriter) WriteHeader(status int) {
+	for k, v := range w.header {
+		for _, v := range v {
+			w.c.Response().Header.Set(k, v)
+		}
+	}
+	w.c.Status(status)
+}
+
+// Write appends the given bytes to the fiber response body.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.c.Write(b)
+}
+
+// Status returns the status code of the fiber response.
+func (w *responseWriter) Status() int {
+	return w.c.Response().StatusCode()
+}
@@ -14,9 +14,12 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
@@ -51,6 +54,7 @@ func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 		opts = append(opts, tracer.Tag(ext.Component, "gofiber/fiber.v2"))
 		opts = append(opts, tracer.Tag(ext.SpanKind, ext.SpanKindServer))
 		span, ctx := tracer.StartSpanFromContext(c.Context(), "http.request", opts...)
+		httptrace.SetRequestHeaderTags(span, h)
 
 		defer span.Finish()
 
@@ -58,7 +62,12 @@ func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 		c.SetUserContext(ctx)
 
 		// pass the execution down the line
-		err := c.Next()
+		var err error
+		if appsec.Enabled() {
+			err = nextWithAppSec(c, span)
+		} else {
+			err = c.Next()
+		}
 
 		span.SetTag(ext.ResourceName, cfg.resourceNamer(c))
 
@@ -70,6 +79,11 @@ func Middleware(opts ...Option) func(c *fiber.Ctx) error {
 			status = http.StatusOK
 		}
 		span.SetTag(ext.HTTPCode, strconv.Itoa(status))
+		for _, t := range globalconfig.HeaderTags() {
+			if v := c.GetRespHeader(t.Header); v != "" {
+				span.SetTag(t.ResponseTag, v)
+			}
+		}
 
 		if err != nil {
 			span.SetTag(ext.Error, err)
@@ -11,6 +11,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -96,6 +97,32 @@ func TestTrace200(t *testing.T) {
 	})
 }
 
+func TestHeaderTags(t *testing.T) {
+	assert := assert.New(t)
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	globalconfig.SetHeaderTags([]globalconfig.HeaderTag{
+		{Header: "X-Request-Id", RequestTag: "http.request.headers.x-request-id", ResponseTag: "http.response.headers.x-request-id"},
+	})
+	defer globalconfig.SetHeaderTags(nil)
+
+	router := fiber.New()
+	router.Use(Middleware())
+	router.Get("/", func(c *fiber.Ctx) error {
+		c.Set("X-Request-Id", "456")
+		return c.SendString("OK")
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-Id", "123")
+	_, err := router.Test(r)
+	assert.NoError(err)
+
+	spans := mt.FinishedSpans()
+	assert.Len(spans, 1)
+	assert.Equal("123", spans[0].Tag("http.request.headers.x-request-id"))
+	assert.Equal("456", spans[0].Tag("http.response.headers.x-request-id"))
+}
+
 func TestStatusError(t *testing.T) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
@@ -292,3 +319,29 @@ func TestAnalyticsSettings(t *testing.T) {
 		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
 	})
 }
+
+func TestAppSec(t *testing.T) {
+	serve := func(router *fiber.App) func(r *http.Request) *http.Response {
+		return func(r *http.Request) *http.Response {
+			res, err := router.Test(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return res
+		}
+	}
+	hello := func(c *fiber.Ctx) error {
+		return c.SendString(appsectest.Body)
+	}
+	t.Run("Use", func(t *testing.T) {
+		router := fiber.New()
+		router.Use(Middleware())
+		router.Get("/hello/:name", hello)
+		appsectest.RunAll(t, &appsectest.Config{Serve: serve(router)})
+	})
+	t.Run("Route", func(t *testing.T) {
+		router := fiber.New()
+		router.Get("/hello/:name", Middleware(), hello)
+		appsectest.RunAll(t, &appsectest.Config{Serve: serve(router)})
+	})
+}
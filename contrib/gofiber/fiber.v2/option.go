@@ -8,6 +8,7 @@ package fiber
 import (
 	"math"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
@@ -28,7 +29,7 @@ type Option func(*config)
 
 func defaults(cfg *config) {
 	cfg.serviceName = "fiber"
-	cfg.isStatusError = isServerError
+	cfg.isStatusError = httptrace.IsServerError
 	cfg.resourceNamer = defaultResourceNamer
 
 	if svc := globalconfig.ServiceName(); svc != "" {
@@ -99,7 +100,3 @@ func defaultResourceNamer(c *fiber.Ctx) string {
 	r := c.Route()
 	return r.Method + " " + r.Path
 }
-
-func isServerError(statusCode int) bool {
-	return statusCode >= 500 && statusCode < 600
-}
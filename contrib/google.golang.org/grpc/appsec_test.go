@@ -9,6 +9,8 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -96,7 +98,7 @@ func TestAppSec(t *testing.T) {
 		event, _ := finished[5].Tag("_dd.appsec.json").(string)
 		require.NotNil(t, event)
 		require.True(t, strings.Contains(event, "crs-941-110")) // XSS attack attempt
-		require.True(t, strings.Contains(event, "crs-942-100")) // SQL-injection attack attempt
+		require.True(t, strings.Contains(event, "crs-942-270")) // SQL-injection attack attempt
 		require.True(t, strings.Contains(event, "ua0-600-55x")) // canary rule attack attempt
 	})
 }
@@ -188,6 +190,56 @@ func TestBlocking(t *testing.T) {
 
 }
 
+// Test that the blocked RPCs use the status code and message of the block action defined by the rules
+func TestBlockingAction(t *testing.T) {
+	const rules = `{
+	"version": "2.2",
+	"metadata": {"rules_version": "1.0.0"},
+	"rules": [{
+		"id": "blk-grpc-001",
+		"name": "Block IP addresses",
+		"tags": {"type": "block_ip", "category": "security_response"},
+		"conditions": [{
+			"operator": "match_regex",
+			"parameters": {"inputs": [{"address": "http.client_ip"}], "regex": "^1\\.2\\.3\\.4$"}
+		}],
+		"transformers": [],
+		"on_match": ["block"]
+	}],
+	"actions": [{
+		"id": "block",
+		"type": "block_request",
+		"parameters": {"status_code": 403, "grpc_status_code": 7, "grpc_status_message": "Permission denied"}
+	}]
+}`
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(rules), 0644))
+	t.Setenv("DD_APPSEC_RULES", path)
+	appsec.Start()
+	defer appsec.Stop()
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+
+	rig, err := newRig(false)
+	require.NoError(t, err)
+	defer rig.Close()
+
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-client-ip", "1.2.3.4"))
+	reply, err := rig.client.Ping(ctx, &FixtureRequest{Name: "hello"})
+	require.Nil(t, reply)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Equal(t, "Permission denied", status.Convert(err).Message())
+
+	finished := mt.FinishedSpans()
+	require.Len(t, finished, 1)
+	event, _ := finished[0].Tag("_dd.appsec.json").(string)
+	require.Contains(t, event, "blk-grpc-001")
+}
+
 // Test that user blocking works by using custom rules/rules data
 func TestUserBlocking(t *testing.T) {
 	t.Setenv("DD_APPSEC_RULES", "../../../internal/appsec/testdata/blocking.json")
@@ -25,6 +25,7 @@ type routerConfig struct {
 	ignoreRequest func(*http.Request) bool
 	headerTags    bool
 	queryParams   bool
+	isStatusError func(statusCode int) bool
 }
 
 // RouterOption represents an option that can be passed to NewRouter.
@@ -137,3 +138,11 @@ func WithQueryParams() RouterOption {
 		cfg.queryParams = true
 	}
 }
+
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error.
+func WithStatusCheck(fn func(statusCode int) bool) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.isStatusError = fn
+	}
+}
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
+	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+)
+
+type appsecResolver struct {
+	resolved []string
+}
+
+func (r *appsecResolver) User(args struct{ Name string }) string {
+	r.resolved = append(r.resolved, args.Name)
+	return args.Name
+}
+
+func TestAppSec(t *testing.T) {
+	appsectest.Start(t, appsectest.GraphQLRules)
+
+	s := `
+		schema {
+			query: Query
+		}
+		type Query {
+			user(name: String!): String!
+		}
+	`
+	resolver := &appsecResolver{}
+	schema := graphql.MustParseSchema(s, resolver, graphql.Tracer(NewTracer()))
+	srv := httptest.NewServer(httptrace.WrapHandler(&relay.Handler{Schema: schema}, "graphql-server", "/query"))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name    string
+		user    string
+		blocked bool
+		event   string
+	}{
+		{name: "no-attack", user: "gopher"},
+		{name: "monitoring", user: "dd-scanner", event: "gql-002"},
+		{name: "blocking", user: "' UNION SELECT password FROM users --", blocked: true, event: "gql-001"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			resolver.resolved = nil
+
+			query, err := json.Marshal(map[string]interface{}{
+				"query":     `query Test($name: String!) { user(name: $name) }`,
+				"variables": map[string]string{"name": tc.user},
+			})
+			require.NoError(t, err)
+			res, err := srv.Client().Post(srv.URL, "application/json", strings.NewReader(string(query)))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			var body struct {
+				Data   json.RawMessage
+				Errors []struct{ Message string }
+			}
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+			// Blocking is reported as a GraphQL error instead of an HTTP blocking response.
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			var event interface{}
+			for _, s := range mt.FinishedSpans() {
+				if s.OperationName() == "http.request" {
+					event = s.Tag("_dd.appsec.json")
+				}
+			}
+			if tc.blocked {
+				require.Empty(t, resolver.resolved)
+				require.Len(t, body.Errors, 1)
+				require.Equal(t, "Request blocked", body.Errors[0].Message)
+			} else {
+				require.Equal(t, []string{tc.user}, resolver.resolved)
+				require.Empty(t, body.Errors)
+			}
+			if tc.event != "" {
+				require.Contains(t, event, tc.event)
+			} else {
+				require.Nil(t, event)
+			}
+		})
+	}
+
+	// Every resolver is evaluated on its own, so that the sibling resolvers of
+	// a blocked one carrying the same attack are blocked as well.
+	t.Run("blocking/siblings", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		resolver.resolved = nil
+
+		query, err := json.Marshal(map[string]interface{}{
+			"query":     `query Test($name: String!) { a: user(name: $name) b: user(name: $name) }`,
+			"variables": map[string]string{"name": "' UNION SELECT password FROM users --"},
+		})
+		require.NoError(t, err)
+		res, err := srv.Client().Post(srv.URL, "application/json", strings.NewReader(string(query)))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var body struct {
+			Errors []struct{ Message string }
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Empty(t, resolver.resolved)
+		require.Len(t, body.Errors, 2)
+		for _, err := range body.Errors {
+			require.Equal(t, "Request blocked", err.Message)
+		}
+	})
+}
@@ -19,6 +19,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/graphqlsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
 	"github.com/graph-gophers/graphql-go/errors"
@@ -55,7 +57,17 @@ func (t *Tracer) TraceQuery(ctx context.Context, queryString string, operationNa
 	}
 	span, ctx := tracer.StartSpanFromContext(ctx, "graphql.request", opts...)
 
+	var op *graphqlsec.RequestOperation
+	if appsec.Enabled() {
+		ctx, op = graphqlsec.StartRequestOperation(ctx, graphqlsec.RequestOperationArgs{
+			Query:         queryString,
+			OperationName: operationName,
+			Variables:     variables,
+		})
+	}
+
 	return ctx, func(errs []*errors.QueryError) {
+		op.Finish()
 		var err error
 		switch n := len(errs); n {
 		case 0:
@@ -71,6 +83,13 @@ func (t *Tracer) TraceQuery(ctx context.Context, queryString string, operationNa
 
 // TraceField traces a GraphQL field access.
 func (t *Tracer) TraceField(ctx context.Context, label string, typeName string, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	if appsec.Enabled() && len(args) > 0 {
+		if err := graphqlsec.ProtectResolver(ctx, fieldName, args); err != nil {
+			// graphql-go doesn't execute the resolver when the returned
+			// context is done, and reports its error as the field error.
+			ctx = blockedContext{Context: ctx, err: err}
+		}
+	}
 	if t.cfg.omitTrivial && trivial {
 		return ctx, func(queryError *errors.QueryError) {}
 	}
@@ -96,6 +115,23 @@ func (t *Tracer) TraceField(ctx context.Context, label string, typeName string,
 	}
 }
 
+// blockedContext is the context returned to graphql-go when the execution of
+// a resolver must be blocked.
+type blockedContext struct {
+	context.Context
+	err error
+}
+
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+func (c blockedContext) Done() <-chan struct{} { return closedChan }
+
+func (c blockedContext) Err() error { return c.err }
+
 // NewTracer creates a new Tracer.
 func NewTracer(opts ...Option) trace.Tracer {
 	cfg := new(config)
@@ -0,0 +1,290 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package appsectest provides the conformance test suite of the AppSec support
+// of the HTTP router integrations, along with the AppSec test helpers shared by
+// the integrations.
+package appsectest // import "gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// Route is the route the router under test must serve, using its own path
+	// parameter syntax, with a path parameter named after PathParam.
+	Route = "/hello/{name}"
+	// PathParam is the name of the path parameter of Route.
+	PathParam = "name"
+	// Body is the response body the handler of Route must write, along with
+	// the status code 200.
+	Body = "Hello World!\n"
+	// LoginRoute is the route the router under test must serve with
+	// LoginHandler when the login test cases are enabled.
+	LoginRoute = "/login"
+)
+
+// loginPassword is the password accepted by LoginHandler.
+const loginPassword = "secret"
+
+// LoginHandler is the login handler of LoginRoute. It authenticates the HTTP
+// basic authentication credentials of the request and responds with the
+// status code 200 on success, or 401 otherwise.
+var LoginHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if _, password, ok := r.BasicAuth(); !ok || password != loginPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.Write([]byte(Body))
+})
+
+// rules are the security rules the suite runs with. They only match the test
+// values, so that the default security rules don't interfere with the tests.
+const rules = `{
+	"version": "2.2",
+	"metadata": {"rules_version": "1.0.0"},
+	"rules": [
+		{
+			"id": "query-001",
+			"name": "Test attack in the query",
+			"tags": {"type": "test", "category": "attack_attempt"},
+			"conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.query"}], "regex": "dd-test-attack"}}],
+			"transformers": []
+		},
+		{
+			"id": "params-001",
+			"name": "Test attack in the path parameters",
+			"tags": {"type": "test", "category": "attack_attempt"},
+			"conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.path_params"}], "regex": "dd-test-attack"}}],
+			"transformers": []
+		},
+		{
+			"id": "ip-001",
+			"name": "Test blocked client IP",
+			"tags": {"type": "block_ip", "category": "security_response"},
+			"conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "http.client_ip"}], "regex": "^111\\.222\\.111\\.222$"}}],
+			"transformers": [],
+			"on_match": ["block"]
+		}
+	]
+}`
+
+// GraphQLRules are the security rules of the GraphQL integrations tests. They
+// block the resolvers called with a SQL injection, and monitor the resolvers
+// called with the arguments of a security scanner.
+const GraphQLRules = `{
+	"version": "2.2",
+	"metadata": {"rules_version": "1.0.0"},
+	"rules": [{
+		"id": "gql-001",
+		"name": "GraphQL resolver SQL injection",
+		"tags": {"type": "sql_injection", "category": "attack_attempt"},
+		"conditions": [{
+			"operator": "match_regex",
+			"parameters": {"inputs": [{"address": "graphql.server.resolver"}], "regex": "(?i)union\\s+select"}
+		}],
+		"transformers": [],
+		"on_match": ["block"]
+	}, {
+		"id": "gql-002",
+		"name": "GraphQL suspicious resolver arguments",
+		"tags": {"type": "security_scanner", "category": "attack_attempt"},
+		"conditions": [{
+			"operator": "match_regex",
+			"parameters": {"inputs": [{"address": "graphql.server.all_resolvers"}], "regex": "^dd-scanner$"}
+		}],
+		"transformers": []
+	}]
+}`
+
+// Start starts AppSec with the given security rules until the end of the test.
+// The test is skipped when AppSec is not enabled.
+func Start(t *testing.T, rules string) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(rules), 0644))
+	t.Setenv("DD_APPSEC_RULES", path)
+	appsec.Start()
+	t.Cleanup(appsec.Stop)
+	if !appsec.Enabled() {
+		t.Skip("appsec disabled")
+	}
+}
+
+// ServeFunc serves the given request with the router under test and returns
+// the resulting response.
+type ServeFunc func(*http.Request) *http.Response
+
+// Handler returns the ServeFunc serving the requests with the given
+// net/http handler.
+func Handler(h http.Handler) ServeFunc {
+	return func(r *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+}
+
+// Config is the configuration of the conformance test suite.
+type Config struct {
+	// Serve serves the requests with the router under test, which must serve
+	// Route with a handler writing Body.
+	Serve ServeFunc
+	// NoPathParams disables the path parameters test cases, for the
+	// integrations which have no access to the matched route.
+	NoPathParams bool
+	// Login enables the automated user login events test cases, for which
+	// the router under test must serve POST requests to LoginRoute with
+	// LoginHandler.
+	Login bool
+}
+
+// RunAll checks the router under test runs the AppSec monitoring and blocking
+// features with the request path parameters and client IP, along with the
+// automated user login events when enabled. The test is skipped when AppSec is
+// not enabled.
+func RunAll(t *testing.T, cfg *Config) {
+	t.Setenv("DD_APPSEC_AUTOMATED_USER_EVENTS_TRACKING", "extended")
+	Start(t, rules)
+
+	for _, tc := range []struct {
+		name       string
+		url        string
+		headers    map[string]string
+		status     int
+		ruleMatch  string
+		clientIP   string
+		pathParams bool
+	}{
+		{
+			name:   "no-attack",
+			url:    "/hello/gopher",
+			status: http.StatusOK,
+		},
+		{
+			name:      "monitoring/query",
+			url:       "/hello/gopher?q=dd-test-attack",
+			status:    http.StatusOK,
+			ruleMatch: "query-001",
+		},
+		{
+			name:       "monitoring/path-params",
+			url:        "/hello/dd-test-attack",
+			status:     http.StatusOK,
+			ruleMatch:  "params-001",
+			pathParams: true,
+		},
+		{
+			name:     "client-ip",
+			url:      "/hello/gopher",
+			headers:  map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			status:   http.StatusOK,
+			clientIP: "1.2.3.4",
+		},
+		{
+			name:      "blocking/client-ip",
+			url:       "/hello/gopher",
+			headers:   map[string]string{"X-Forwarded-For": "111.222.111.222"},
+			status:    http.StatusForbidden,
+			ruleMatch: "ip-001",
+			clientIP:  "111.222.111.222",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.pathParams && cfg.NoPathParams {
+				t.Skip("path parameters not supported")
+			}
+			mt := mocktracer.Start()
+			defer mt.Stop()
+
+			req := httptest.NewRequest("GET", tc.url, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			res := cfg.Serve(req)
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.Equal(t, tc.status, res.StatusCode)
+			if tc.status == http.StatusOK {
+				require.Equal(t, Body, string(body))
+			} else {
+				require.NotEqual(t, Body, string(body))
+			}
+
+			span := serviceEntrySpan(t, mt.FinishedSpans())
+			if tc.ruleMatch != "" {
+				require.Contains(t, span.Tag("_dd.appsec.json"), tc.ruleMatch)
+			} else {
+				require.Nil(t, span.Tag("_dd.appsec.json"))
+			}
+			if tc.status == http.StatusForbidden {
+				require.Equal(t, true, span.Tag("appsec.blocked"))
+			}
+			if tc.clientIP != "" {
+				require.Equal(t, tc.clientIP, span.Tag("http.client_ip"))
+			}
+		})
+	}
+
+	t.Run("login", func(t *testing.T) {
+		if !cfg.Login {
+			t.Skip("login route not served")
+		}
+		for _, tc := range []struct {
+			name     string
+			password string
+			status   int
+			success  bool
+		}{
+			{name: "success", password: loginPassword, status: http.StatusOK, success: true},
+			{name: "failure", password: "guess", status: http.StatusUnauthorized},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				mt := mocktracer.Start()
+				defer mt.Stop()
+
+				req := httptest.NewRequest("POST", LoginRoute, nil)
+				req.SetBasicAuth("gopher", tc.password)
+				res := cfg.Serve(req)
+				res.Body.Close()
+				require.Equal(t, tc.status, res.StatusCode)
+
+				span := serviceEntrySpan(t, mt.FinishedSpans())
+				if tc.success {
+					require.Equal(t, true, span.Tag("appsec.events.users.login.success.track"))
+					require.Equal(t, "extended", span.Tag("_dd.appsec.events.users.login.success.auto.mode"))
+					require.Equal(t, "gopher", span.Tag("usr.id"))
+					require.Nil(t, span.Tag("appsec.events.users.login.failure.track"))
+				} else {
+					require.Equal(t, true, span.Tag("appsec.events.users.login.failure.track"))
+					require.Equal(t, "extended", span.Tag("_dd.appsec.events.users.login.failure.auto.mode"))
+					require.Equal(t, "gopher", span.Tag("appsec.events.users.login.failure.usr.id"))
+					require.Nil(t, span.Tag("appsec.events.users.login.success.track"))
+				}
+			})
+		}
+	})
+}
+
+// serviceEntrySpan returns the span monitored by AppSec.
+func serviceEntrySpan(t *testing.T, spans []mocktracer.Span) mocktracer.Span {
+	for _, s := range spans {
+		if s.Tag("_dd.appsec.enabled") != nil {
+			return s
+		}
+	}
+	require.FailNow(t, "no span monitored by appsec")
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statusrange"
 )
 
 // The env vars described below are used to configure the http security tags collection.
@@ -22,6 +23,15 @@ const (
 	envQueryStringRegexp = "DD_TRACE_OBFUSCATION_QUERY_STRING_REGEXP"
 	// envTraceClientIPEnabled is the name of the env var used to specify whether or not to collect client ip in span tags
 	envTraceClientIPEnabled = "DD_TRACE_CLIENT_IP_ENABLED"
+	// envServerErrorStatuses is the name of the env var used to specify the status codes of the HTTP servers which
+	// should be considered errors, e.g. "500-599,429". Together with envClientErrorStatuses, it overrides the default
+	// status codes of the integrations, which are 5xx unless documented otherwise by the integration. The status
+	// check function given to the WithStatusCheck option of an integration takes precedence over both env vars, so
+	// that the applications needing their own error semantics keep them regardless of the global configuration.
+	envServerErrorStatuses = "DD_TRACE_HTTP_SERVER_ERROR_STATUSES"
+	// envClientErrorStatuses is the name of the env var used to specify the status codes received by the HTTP clients
+	// which should be considered errors, e.g. "400-599".
+	envClientErrorStatuses = "DD_TRACE_HTTP_CLIENT_ERROR_STATUSES"
 )
 
 // defaultQueryStringRegexp is the regexp used for query string obfuscation if `envQueryStringRegexp` is empty.
@@ -31,6 +41,8 @@ type config struct {
 	queryStringRegexp *regexp.Regexp // specifies the regexp to use for query string obfuscation.
 	queryString       bool           // reports whether the query string should be included in the URL span tag.
 	traceClientIP     bool
+	serverErrors      statusrange.List // status codes of the servers considered errors, nil when not configured.
+	clientErrors      statusrange.List // status codes received by the clients considered errors, nil when not configured.
 }
 
 func newConfig() config {
@@ -38,6 +50,8 @@ func newConfig() config {
 		queryString:       !internal.BoolEnv(envQueryStringDisabled, false),
 		queryStringRegexp: defaultQueryStringRegexp,
 		traceClientIP:     internal.BoolEnv(envTraceClientIPEnabled, false),
+		serverErrors:      statusRangesFromEnv(envServerErrorStatuses),
+		clientErrors:      statusRangesFromEnv(envClientErrorStatuses),
 	}
 	if s, ok := os.LookupEnv(envQueryStringRegexp); !ok {
 		return c
@@ -51,3 +65,17 @@ func newConfig() config {
 	}
 	return c
 }
+
+// statusRangesFromEnv returns the status ranges specified by the env var with the given name, or nil when it is not
+// set. Invalid entries are ignored, and an empty list means that no status code is an error.
+func statusRangesFromEnv(env string) statusrange.List {
+	s, ok := os.LookupEnv(env)
+	if !ok {
+		return nil
+	}
+	l, err := statusrange.Parse(s)
+	if err != nil {
+		log.Warn("Ignoring the %v of %s.", err, env)
+	}
+	return l
+}
@@ -61,17 +61,58 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestStatusCheck(t *testing.T) {
+	defer func(old config) { cfg = old }(cfg)
+	isTeapot := func(statusCode int) bool { return statusCode == 418 }
+
+	t.Run("defaults", func(t *testing.T) {
+		defer cleanEnv()()
+		cfg = newConfig()
+		require.True(t, IsServerError(500))
+		require.False(t, IsServerError(404))
+		require.True(t, ServerStatusCheck(isTeapot)(418))
+		require.True(t, ClientStatusCheck(isTeapot)(418))
+		require.False(t, ClientStatusCheck(isTeapot)(500))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		defer cleanEnv()()
+		os.Setenv(envServerErrorStatuses, "500-502,429")
+		os.Setenv(envClientErrorStatuses, "400-499")
+		cfg = newConfig()
+		require.True(t, IsServerError(429))
+		require.True(t, IsServerError(502))
+		require.False(t, IsServerError(503))
+		require.False(t, ServerStatusCheck(isTeapot)(418))
+		require.True(t, ClientStatusCheck(isTeapot)(404))
+		require.False(t, ClientStatusCheck(isTeapot)(500))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		defer cleanEnv()()
+		os.Setenv(envServerErrorStatuses, "")
+		cfg = newConfig()
+		require.False(t, IsServerError(500))
+	})
+}
+
 func cleanEnv() func() {
-	env := map[string]string{
-		envQueryStringDisabled: os.Getenv(envQueryStringDisabled),
-		envQueryStringRegexp:   os.Getenv(envQueryStringRegexp),
-	}
-	for k := range env {
+	env := make(map[string]*string)
+	for _, k := range []string{envQueryStringDisabled, envQueryStringRegexp, envServerErrorStatuses, envClientErrorStatuses} {
+		if v, ok := os.LookupEnv(k); ok {
+			env[k] = &v
+		} else {
+			env[k] = nil
+		}
 		os.Unsetenv(k)
 	}
 	return func() {
 		for k, v := range env {
-			os.Setenv(k, v)
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
 		}
 	}
 }
@@ -5,6 +5,12 @@
 
 // Package httptrace provides functionalities to trace HTTP requests that are commonly required and used across
 // contrib/** integrations.
+//
+// The status codes considered errors by the HTTP integrations can be configured globally using the
+// DD_TRACE_HTTP_SERVER_ERROR_STATUSES and DD_TRACE_HTTP_CLIENT_ERROR_STATUSES environment variables, for servers and
+// clients respectively, as comma-separated lists of status codes or inclusive ranges of status codes, e.g.
+// "500-599,429". They default to 5xx, unless documented otherwise by the integration. The status check function given
+// to the WithStatusCheck option of an integration takes precedence over both environment variables.
 package httptrace
 
 import (
@@ -19,6 +25,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 )
 
 var cfg = newConfig()
@@ -49,25 +56,89 @@ func StartRequestSpan(r *http.Request, opts ...ddtrace.StartSpanOption) (tracer.
 			opts = append(opts, tracer.Tag(k, v))
 		}
 	}
-	return tracer.StartSpanFromContext(r.Context(), "http.request", opts...)
+	span, ctx := tracer.StartSpanFromContext(r.Context(), "http.request", opts...)
+	SetRequestHeaderTags(span, r.Header)
+	return span, ctx
 }
 
 // FinishRequestSpan finishes the given HTTP request span and sets the expected response-related tags such as the status
-// code. Any further span finish option can be added with opts.
-func FinishRequestSpan(s tracer.Span, status int, opts ...tracer.FinishOption) {
-	var statusStr string
+// code and the configured response header tags. The span is marked as errored when isStatusError reports that the
+// status code is an error, or when IsServerError does if isStatusError is nil. Any further span finish option can be
+// added with opts.
+func FinishRequestSpan(s tracer.Span, status int, isStatusError func(statusCode int) bool, header http.Header, opts ...tracer.FinishOption) {
+	SetResponseHeaderTags(s, header)
 	if status == 0 {
-		statusStr = "200"
-	} else {
-		statusStr = strconv.Itoa(status)
+		status = http.StatusOK
 	}
+	statusStr := strconv.Itoa(status)
 	s.SetTag(ext.HTTPCode, statusStr)
-	if status >= 500 && status < 600 {
+	if isStatusError == nil {
+		isStatusError = IsServerError
+	}
+	if isStatusError(status) {
 		s.SetTag(ext.Error, fmt.Errorf("%s: %s", statusStr, http.StatusText(status)))
 	}
 	s.Finish(opts...)
 }
 
+// IsServerError reports whether the given status code, returned by an HTTP server, should be considered an error.
+// The status codes considered errors can be configured using the DD_TRACE_HTTP_SERVER_ERROR_STATUSES environment
+// variable (e.g. "500-599,429"), and default to 5xx.
+func IsServerError(statusCode int) bool {
+	if cfg.serverErrors != nil {
+		return cfg.serverErrors.Contains(statusCode)
+	}
+	return statusCode >= 500 && statusCode < 600
+}
+
+// ServerStatusCheck returns a function reporting whether a status code returned by an HTTP server should be considered
+// an error. It follows the DD_TRACE_HTTP_SERVER_ERROR_STATUSES environment variable when it is set, and def otherwise.
+// It allows integrations with a default other than IsServerError to honor the global configuration.
+func ServerStatusCheck(def func(statusCode int) bool) func(statusCode int) bool {
+	if cfg.serverErrors != nil {
+		return cfg.serverErrors.Contains
+	}
+	return def
+}
+
+// ClientStatusCheck returns a function reporting whether a status code received by an HTTP client should be
+// considered an error. It follows the DD_TRACE_HTTP_CLIENT_ERROR_STATUSES environment variable (e.g. "400-599") when
+// it is set, and def otherwise.
+func ClientStatusCheck(def func(statusCode int) bool) func(statusCode int) bool {
+	if cfg.clientErrors != nil {
+		return cfg.clientErrors.Contains
+	}
+	return def
+}
+
+// SetRequestHeaderTags sets on s the tags of the request headers configured globally using tracer.WithHeaderTags or
+// the DD_TRACE_HEADER_TAGS environment variable.
+func SetRequestHeaderTags(s tracer.Span, h http.Header) {
+	for _, t := range globalconfig.HeaderTags() {
+		if v := h[t.Header]; len(v) > 0 {
+			s.SetTag(t.RequestTag, headerValue(v))
+		}
+	}
+}
+
+// SetResponseHeaderTags sets on s the tags of the response headers configured globally using tracer.WithHeaderTags or
+// the DD_TRACE_HEADER_TAGS environment variable.
+func SetResponseHeaderTags(s tracer.Span, h http.Header) {
+	for _, t := range globalconfig.HeaderTags() {
+		if v := h[t.Header]; len(v) > 0 {
+			s.SetTag(t.ResponseTag, headerValue(v))
+		}
+	}
+}
+
+// headerValue returns the comma-separated values of a header.
+func headerValue(v []string) string {
+	if len(v) == 1 {
+		return v[0]
+	}
+	return strings.Join(v, ",")
+}
+
 // urlFromRequest returns the full URL from the HTTP request. If query params are collected, they are obfuscated granted
 // obfuscation is not disabled by the user (through DD_TRACE_OBFUSCATION_QUERY_STRING_REGEXP)
 // See https://docs.datadoghq.com/tracing/configure_data_security#redacting-the-query-in-the-url for more information.
@@ -18,6 +18,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
@@ -32,6 +33,70 @@ func TestStartRequestSpan(t *testing.T) {
 	assert.Equal(t, "example.com", spans[0].Tag("http.host"))
 }
 
+func TestHeaderTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	globalconfig.SetHeaderTags([]globalconfig.HeaderTag{
+		{Header: "X-Request-Id", RequestTag: "request.id", ResponseTag: "request.id"},
+		{Header: "Accept", RequestTag: "http.request.headers.accept", ResponseTag: "http.response.headers.accept"},
+		{Header: "Content-Type", RequestTag: "http.request.headers.content-type", ResponseTag: "http.response.headers.content-type"},
+	})
+	defer globalconfig.SetHeaderTags(nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/somePath", nil)
+	r.Header.Set("x-request-id", "123")
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+	r.Header.Set("User-Agent", "test")
+	s, _ := StartRequestSpan(r)
+	FinishRequestSpan(s, 200, nil, http.Header{"Content-Type": {"text/plain"}})
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	tags := spans[0].Tags()
+	assert.Equal(t, "123", tags["request.id"])
+	assert.Equal(t, "text/html,application/json", tags["http.request.headers.accept"])
+	assert.Equal(t, "text/plain", tags["http.response.headers.content-type"])
+	assert.NotContains(t, tags, "http.request.headers.content-type")
+	assert.NotContains(t, tags, "http.response.headers.accept")
+	assert.NotContains(t, tags, "http.request.headers.user-agent")
+}
+
+func TestFinishRequestSpanStatusCheck(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	isNotFound := func(statusCode int) bool { return statusCode == 404 }
+	for _, tc := range []struct {
+		status        int
+		isStatusError func(int) bool
+		err           bool
+	}{
+		{status: 0, err: false},
+		{status: 500, err: true},
+		{status: 404, err: false},
+		{status: 404, isStatusError: isNotFound, err: true},
+		{status: 500, isStatusError: isNotFound, err: false},
+	} {
+		mt.Reset()
+		s, _ := StartRequestSpan(httptest.NewRequest(http.MethodGet, "/somePath", nil))
+		FinishRequestSpan(s, tc.status, tc.isStatusError, nil)
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, tc.err, spans[0].Tag(ext.Error) != nil, "status %d", tc.status)
+	}
+}
+
+func BenchmarkHeaderTagsDisabled(b *testing.B) {
+	r := httptest.NewRequest(http.MethodGet, "/somePath", nil)
+	r.Header.Set("X-Request-Id", "123")
+	s, _ := StartRequestSpan(r)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SetRequestHeaderTags(s, r.Header)
+		SetResponseHeaderTags(s, r.Header)
+	}
+}
+
 // TestClientIP tests behavior of StartRequestSpan based on
 // the DD_TRACE_CLIENT_IP_ENABLED environment variable
 func TestTraceClientIPFlag(t *testing.T) {
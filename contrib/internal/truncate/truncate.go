@@ -0,0 +1,23 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package truncate provides the truncation of the values tagged by the
+// integrations, such as database queries.
+package truncate
+
+import "unicode/utf8"
+
+// String returns s truncated to at most size bytes, followed by an ellipsis
+// when it was truncated. A size of zero or less disables the truncation.
+func String(s string, size int) string {
+	if size <= 0 || len(s) <= size {
+		return s
+	}
+	// don't cut a multi-byte character in half
+	for size > 0 && !utf8.RuneStart(s[size]) {
+		size--
+	}
+	return s[:size] + "..."
+}
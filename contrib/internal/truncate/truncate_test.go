@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package truncate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		size int
+		want string
+	}{
+		{`{"find":"?"}`, 0, `{"find":"?"}`},
+		{`{"find":"?"}`, 12, `{"find":"?"}`},
+		{`{"find":"?"}`, 8, `{"find":...`},
+		{`{"é":"?"}`, 3, `{"...`},
+		{`{"é":"?"}`, 4, `{"é...`},
+	} {
+		assert.Equal(t, tt.want, String(tt.s, tt.size))
+	}
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package pgx
+
+import (
+	"math"
+	"os"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal"
+)
+
+type config struct {
+	serviceName        string
+	analyticsRate      float64
+	childSpansOnly     bool
+	errCheck           func(err error) bool
+	tags               map[string]interface{}
+	dbmPropagationMode tracer.DBMPropagationMode
+}
+
+// Option represents an option that can be passed to Connect, ConnectConfig,
+// NewPool or NewPoolWithConfig.
+type Option func(*config)
+
+func defaults(cfg *config) {
+	cfg.serviceName = "pgx.db"
+	// cfg.analyticsRate = globalconfig.AnalyticsRate()
+	if internal.BoolEnv("DD_TRACE_PGX_ANALYTICS_ENABLED", false) {
+		cfg.analyticsRate = 1.0
+	} else {
+		cfg.analyticsRate = math.NaN()
+	}
+	cfg.dbmPropagationMode = tracer.DBMPropagationMode(os.Getenv("DD_DBM_PROPAGATION_MODE"))
+}
+
+// dbmPropagationEnabled reports whether the Database Monitoring comments are
+// injected in the queries.
+func (cfg *config) dbmPropagationEnabled() bool {
+	return cfg.dbmPropagationMode == tracer.DBMPropagationModeService || cfg.dbmPropagationMode == tracer.DBMPropagationModeFull
+}
+
+// WithServiceName sets the given service name for the connections.
+// Defaults to "pgx.db".
+func WithServiceName(name string) Option {
+	return func(cfg *config) {
+		cfg.serviceName = name
+	}
+}
+
+// WithAnalytics enables Trace Analytics for all started spans.
+func WithAnalytics(on bool) Option {
+	return func(cfg *config) {
+		if on {
+			cfg.analyticsRate = 1.0
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithAnalyticsRate sets the sampling rate for Trace Analytics events
+// correlated to started spans.
+func WithAnalyticsRate(rate float64) Option {
+	return func(cfg *config) {
+		if rate >= 0.0 && rate <= 1.0 {
+			cfg.analyticsRate = rate
+		} else {
+			cfg.analyticsRate = math.NaN()
+		}
+	}
+}
+
+// WithChildSpansOnly causes spans to be created only when
+// there is an existing parent span in the Context.
+func WithChildSpansOnly() Option {
+	return func(cfg *config) {
+		cfg.childSpansOnly = true
+	}
+}
+
+// WithErrorCheck specifies a function fn which determines whether the passed
+// error should be marked as an error. The fn is called whenever a pgx operation
+// finishes with an error.
+func WithErrorCheck(fn func(err error) bool) Option {
+	return func(cfg *config) {
+		cfg.errCheck = fn
+	}
+}
+
+// WithCustomTag will attach the value to the span tagged by the key.
+func WithCustomTag(key string, value interface{}) Option {
+	return func(cfg *config) {
+		if cfg.tags == nil {
+			cfg.tags = make(map[string]interface{})
+		}
+		cfg.tags[key] = value
+	}
+}
+
+// WithDBMPropagation enables injection of tags as sql comments on traced queries.
+// This includes dynamic values like span id, trace id and the sampled flag which can make queries
+// unique for some cache implementations. Use DBMPropagationModeService if this is a concern.
+//
+// In particular, the statements of the queries commented in DBMPropagationModeFull can't be reused.
+// The queries and executions of the connections, pools and transactions returned by this package are
+// then run with pgx.QueryExecModeExec rather than prepared, which costs the server a planning of every
+// query, and requires the types of their arguments to be registered in the type map of the connection.
+// The queries given the DBMPropagation query rewriter directly and the queries of the batches are still
+// run with the execution mode of the connection, and prepared on every execution unless it is
+// pgx.QueryExecModeExec or pgx.QueryExecModeSimpleProtocol.
+//
+// The comments are injected in the queries and executions of the connections,
+// pools and transactions returned by this package, and in the queries given the
+// DBMPropagation query rewriter. See DBMPropagation for more details.
+//
+// Note that enabling sql comment propagation results in potentially confidential data (service names)
+// being stored in the databases which can then be accessed by other 3rd parties that have been granted
+// access to the database.
+func WithDBMPropagation(mode tracer.DBMPropagationMode) Option {
+	return func(cfg *config) {
+		cfg.dbmPropagationMode = mode
+	}
+}
@@ -0,0 +1,799 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package pgx
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pgServer is a minimal PostgreSQL server, answering the queries over the
+// simple and the extended protocols, and the copies, with canned results.
+// Queries starting with SELECT return a single text column "name" with a single
+// row, and queries containing "error" fail.
+type pgServer struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu      sync.Mutex // guards queries
+	queries []string
+}
+
+func newPGServer(t *testing.T) *pgServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &pgServer{ln: ln}
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			srv.wg.Add(1)
+			go func() {
+				defer srv.wg.Done()
+				defer conn.Close()
+				srv.serve(conn)
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		srv.wg.Wait()
+	})
+	return srv
+}
+
+// connString returns the connection string to connect to the server.
+func (s *pgServer) connString() string {
+	return fmt.Sprintf("postgres://postgres@%s/postgres?sslmode=disable&application_name=test-app", s.ln.Addr())
+}
+
+// received returns the text of the queries received by the server.
+func (s *pgServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+func (s *pgServer) record(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, query)
+}
+
+var (
+	commentRegexp     = regexp.MustCompile(`^\s*/\*.*?\*/\s*`)
+	placeholderRegexp = regexp.MustCompile(`\$\d+`)
+)
+
+// command returns the upper cased first word of the query, ignoring comments.
+func command(query string) string {
+	fields := strings.Fields(commentRegexp.ReplaceAllString(query, ""))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// rowDescription returns the description of the rows returned by the query.
+func rowDescription(query string) pgproto3.BackendMessage {
+	if command(query) != "SELECT" {
+		return &pgproto3.NoData{}
+	}
+	return &pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{{
+		Name:         []byte("name"),
+		DataTypeOID:  25, // text
+		DataTypeSize: -1,
+		TypeModifier: -1,
+	}}}
+}
+
+// result sends the result of the query, returning false if it failed.
+func result(b *pgproto3.Backend, query string) bool {
+	if strings.Contains(query, "error") {
+		b.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42601", Message: "syntax error"})
+		return false
+	}
+	switch cmd := command(query); cmd {
+	case "SELECT":
+		b.Send(&pgproto3.DataRow{Values: [][]byte{[]byte("paris")}})
+		b.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+	case "INSERT":
+		b.Send(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 1")})
+	case "UPDATE", "DELETE":
+		b.Send(&pgproto3.CommandComplete{CommandTag: []byte(cmd + " 2")})
+	default:
+		b.Send(&pgproto3.CommandComplete{CommandTag: []byte(cmd)})
+	}
+	return true
+}
+
+func (s *pgServer) serve(conn net.Conn) {
+	b := pgproto3.NewBackend(conn, conn)
+	msg, err := b.ReceiveStartupMessage()
+	if err != nil {
+		return
+	}
+	if _, ok := msg.(*pgproto3.StartupMessage); !ok {
+		return
+	}
+	b.Send(&pgproto3.AuthenticationOk{})
+	b.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	b.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	b.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	b.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := b.Flush(); err != nil {
+		return
+	}
+	var (
+		statements = make(map[string]string)
+		portals    = make(map[string]string)
+		// failed is set when a message of the extended protocol failed, in
+		// which case the messages are ignored until the next Sync.
+		failed bool
+		copied int
+	)
+	for {
+		msg, err := b.Receive()
+		if err != nil {
+			return
+		}
+		if _, ok := msg.(*pgproto3.Sync); failed && !ok {
+			continue
+		}
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			s.record(msg.String)
+			if command(msg.String) == "COPY" {
+				b.Send(&pgproto3.CopyInResponse{OverallFormat: 1, ColumnFormatCodes: []uint16{1}})
+				copied = 0
+				break
+			}
+			if result(b, msg.String) {
+				if d, ok := rowDescription(msg.String).(*pgproto3.RowDescription); ok {
+					// the description precedes the rows
+					b.Send(d)
+					b.Send(&pgproto3.DataRow{Values: [][]byte{[]byte("paris")}})
+					b.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+				}
+			}
+			b.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Parse:
+			s.record(msg.Query)
+			if strings.Contains(msg.Query, "error") {
+				b.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "42601", Message: "syntax error"})
+				failed = true
+				break
+			}
+			statements[msg.Name] = msg.Query
+			b.Send(&pgproto3.ParseComplete{})
+		case *pgproto3.Describe:
+			if msg.ObjectType == 'S' {
+				query := statements[msg.Name]
+				params := make([]uint32, len(placeholderRegexp.FindAllString(query, -1)))
+				for i := range params {
+					params[i] = 25 // text
+				}
+				b.Send(&pgproto3.ParameterDescription{ParameterOIDs: params})
+				b.Send(rowDescription(query))
+			} else {
+				b.Send(rowDescription(portals[msg.Name]))
+			}
+		case *pgproto3.Bind:
+			portals[msg.DestinationPortal] = statements[msg.PreparedStatement]
+			b.Send(&pgproto3.BindComplete{})
+		case *pgproto3.Execute:
+			failed = !result(b, portals[msg.Portal])
+		case *pgproto3.Close:
+			b.Send(&pgproto3.CloseComplete{})
+		case *pgproto3.Sync:
+			failed = false
+			b.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.CopyData:
+			copied += copiedRows(msg.Data)
+		case *pgproto3.CopyDone:
+			b.Send(&pgproto3.CommandComplete{CommandTag: []byte(fmt.Sprintf("COPY %d", copied))})
+			b.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		case *pgproto3.Terminate:
+			return
+		}
+		if err := b.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// copiedRows returns the number of rows of a chunk of a binary copy, assuming
+// rows aren't split across chunks.
+func copiedRows(data []byte) int {
+	if strings.HasPrefix(string(data), "PGCOPY\n\377\r\n\000") {
+		// skip the signature, the flags and the header extension length
+		data = data[19:]
+	}
+	var n int
+	for len(data) >= 2 {
+		fields := int16(binary.BigEndian.Uint16(data))
+		data = data[2:]
+		if fields < 0 {
+			// trailer
+			break
+		}
+		for i := 0; i < int(fields); i++ {
+			size := int32(binary.BigEndian.Uint32(data))
+			data = data[4:]
+			if size > 0 {
+				data = data[size:]
+			}
+		}
+		n++
+	}
+	return n
+}
+
+// spansOfType returns the spans of the given query type.
+func spansOfType(spans []mocktracer.Span, qtype queryType) []mocktracer.Span {
+	var ret []mocktracer.Span
+	for _, s := range spans {
+		if s.Tag(keyQueryType) == string(qtype) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func TestConnect(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+
+	root, ctx := tracer.StartSpanFromContext(context.Background(), "test.root")
+	conn, err := Connect(ctx, srv.connString())
+	require.NoError(t, err)
+	require.NoError(t, conn.Close(ctx))
+	root.Finish()
+
+	spans := spansOfType(mt.FinishedSpans(), queryTypeConnect)
+	require.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, root.Context().SpanID(), s.ParentID())
+	assert.Equal(t, "pgx.query", s.OperationName())
+	host, port, _ := net.SplitHostPort(srv.ln.Addr().String())
+	assert.Equal(t, map[string]interface{}{
+		ext.ServiceName:   "pgx.db",
+		ext.SpanType:      ext.SpanTypeSQL,
+		ext.ResourceName:  "Connect",
+		keyQueryType:      "Connect",
+		ext.Component:     "jackc/pgx.v5",
+		ext.SpanKind:      ext.SpanKindClient,
+		ext.DBSystem:      ext.DBSystemPostgreSQL,
+		ext.TargetHost:    host,
+		ext.TargetPort:    port,
+		ext.DBUser:        "postgres",
+		ext.DBName:        "postgres",
+		ext.DBApplication: "test-app",
+	}, s.Tags())
+
+	mt.Reset()
+	_, err = Connect(ctx, "postgres://postgres@127.0.0.1:1/postgres?sslmode=disable&connect_timeout=1")
+	assert.Error(t, err)
+	spans = mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.NotNil(t, spans[0].Tag(ext.Error))
+}
+
+func TestQuery(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	conn, err := Connect(ctx, srv.connString(), WithServiceName("my-db"), WithCustomTag("custom", "tag"))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	mt.Reset()
+
+	root, ctx := tracer.StartSpanFromContext(ctx, "test.root")
+	var name string
+	err = conn.QueryRow(ctx, "SELECT name FROM city WHERE country = $1", "france").Scan(&name)
+	require.NoError(t, err)
+	assert.Equal(t, "paris", name)
+	tag, err := conn.Exec(ctx, "UPDATE city SET name = $1", "lyon")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, tag.RowsAffected())
+	root.Finish()
+
+	spans := spansOfType(mt.FinishedSpans(), queryTypeQuery)
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		assert.Equal(t, root.Context().SpanID(), s.ParentID())
+		assert.Equal(t, "pgx.query", s.OperationName())
+		assert.Equal(t, "my-db", s.Tag(ext.ServiceName))
+		assert.Equal(t, ext.SpanTypeSQL, s.Tag(ext.SpanType))
+		assert.Equal(t, "jackc/pgx.v5", s.Tag(ext.Component))
+		assert.Equal(t, ext.SpanKindClient, s.Tag(ext.SpanKind))
+		assert.Equal(t, ext.DBSystemPostgreSQL, s.Tag(ext.DBSystem))
+		assert.Equal(t, "tag", s.Tag("custom"))
+		assert.Nil(t, s.Tag(ext.Error))
+	}
+	assert.Equal(t, "SELECT name FROM city WHERE country = $1", spans[0].Tag(ext.ResourceName))
+	assert.Nil(t, spans[0].Tag(keyRowsAffected))
+	assert.Equal(t, "UPDATE city SET name = $1", spans[1].Tag(ext.ResourceName))
+	assert.EqualValues(t, 2, spans[1].Tag(keyRowsAffected))
+
+	// the statements are prepared on their first use
+	prepares := spansOfType(mt.FinishedSpans(), queryTypePrepare)
+	require.Len(t, prepares, 2)
+	assert.Equal(t, spans[0].SpanID(), prepares[0].ParentID())
+	assert.Equal(t, "SELECT name FROM city WHERE country = $1", prepares[0].Tag(ext.ResourceName))
+}
+
+func TestQueryError(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	conn, err := Connect(ctx, srv.connString())
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	mt.Reset()
+
+	_, err = conn.Exec(ctx, "SELECT error")
+	require.Error(t, err)
+
+	spans := spansOfType(mt.FinishedSpans(), queryTypeQuery)
+	require.Len(t, spans, 1)
+	assert.NotNil(t, spans[0].Tag(ext.Error))
+}
+
+func TestWithErrorCheck(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	conn, err := Connect(ctx, srv.connString(), WithErrorCheck(func(err error) bool {
+		var pgErr *pgconn.PgError
+		return !errors.As(err, &pgErr) || pgErr.Code != "42601"
+	}))
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	mt.Reset()
+
+	_, err = conn.Exec(ctx, "SELECT error")
+	require.Error(t, err)
+
+	spans := spansOfType(mt.FinishedSpans(), queryTypeQuery)
+	require.Len(t, spans, 1)
+	assert.Nil(t, spans[0].Tag(ext.Error))
+}
+
+func TestBatch(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	conn, err := Connect(ctx, srv.connString())
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	mt.Reset()
+
+	root, ctx := tracer.StartSpanFromContext(ctx, "test.root")
+	batch := &pgx.Batch{}
+	batch.Queue("SELECT name FROM city WHERE country = $1", "france")
+	batch.Queue("INSERT INTO city (name) VALUES ($1)", "lyon")
+	br := conn.SendBatch(ctx, batch)
+	var name string
+	require.NoError(t, br.QueryRow().Scan(&name))
+	assert.Equal(t, "paris", name)
+	_, err = br.Exec()
+	require.NoError(t, err)
+	require.NoError(t, br.Close())
+	root.Finish()
+
+	spans := mt.FinishedSpans()
+	batches := spansOfType(spans, queryTypeBatch)
+	require.Len(t, batches, 1)
+	b := batches[0]
+	assert.Equal(t, root.Context().SpanID(), b.ParentID())
+	assert.Equal(t, "Batch", b.Tag(ext.ResourceName))
+	assert.Equal(t, 2, b.Tag("sql.batch_size"))
+
+	queries := spansOfType(spans, queryTypeQuery)
+	require.Len(t, queries, 2)
+	assert.Equal(t, "SELECT name FROM city WHERE country = $1", queries[0].Tag(ext.ResourceName))
+	assert.Equal(t, "INSERT INTO city (name) VALUES ($1)", queries[1].Tag(ext.ResourceName))
+	assert.EqualValues(t, 1, queries[1].Tag(keyRowsAffected))
+	for _, q := range queries {
+		assert.Equal(t, b.SpanID(), q.ParentID())
+		assert.False(t, q.StartTime().Before(b.StartTime()))
+		assert.False(t, q.FinishTime().After(b.FinishTime()))
+	}
+	assert.False(t, queries[1].StartTime().Before(queries[0].FinishTime()))
+}
+
+func TestCopyFrom(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	conn, err := Connect(ctx, srv.connString())
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	mt.Reset()
+
+	root, ctx := tracer.StartSpanFromContext(ctx, "test.root")
+	n, err := conn.CopyFrom(ctx, pgx.Identifier{"city"}, []string{"name"}, pgx.CopyFromRows([][]any{{"paris"}, {"lyon"}, {"nice"}}))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+	root.Finish()
+
+	spans := spansOfType(mt.FinishedSpans(), queryTypeCopyFrom)
+	require.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, root.Context().SpanID(), s.ParentID())
+	assert.Equal(t, "CopyFrom", s.Tag(ext.ResourceName))
+	assert.Equal(t, `"city"`, s.Tag("sql.copy_from.table"))
+	assert.EqualValues(t, 3, s.Tag(keyRowsAffected))
+}
+
+func TestPrepare(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	conn, err := Connect(ctx, srv.connString())
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+	mt.Reset()
+
+	_, err = conn.Prepare(ctx, "city", "SELECT name FROM city WHERE country = $1")
+	require.NoError(t, err)
+	_, err = conn.Prepare(ctx, "error", "SELECT error")
+	require.Error(t, err)
+
+	spans := spansOfType(mt.FinishedSpans(), queryTypePrepare)
+	require.Len(t, spans, 2)
+	assert.Equal(t, "SELECT name FROM city WHERE country = $1", spans[0].Tag(ext.ResourceName))
+	assert.Nil(t, spans[0].Tag(ext.Error))
+	assert.Equal(t, "SELECT error", spans[1].Tag(ext.ResourceName))
+	assert.NotNil(t, spans[1].Tag(ext.Error))
+}
+
+func TestPool(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	pool, err := NewPool(ctx, srv.connString(), WithServiceName("my-pool"))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, "DELETE FROM city")
+	require.NoError(t, err)
+
+	spans := spansOfType(mt.FinishedSpans(), queryTypeQuery)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "my-pool", spans[0].Tag(ext.ServiceName))
+	assert.EqualValues(t, 2, spans[0].Tag(keyRowsAffected))
+	assert.Len(t, spansOfType(mt.FinishedSpans(), queryTypeConnect), 1)
+}
+
+func TestWithChildSpansOnly(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	conn, err := Connect(ctx, srv.connString(), WithChildSpansOnly())
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "DELETE FROM city")
+	require.NoError(t, err)
+	assert.Len(t, mt.FinishedSpans(), 0)
+
+	root, ctx := tracer.StartSpanFromContext(ctx, "test.root")
+	_, err = conn.Exec(ctx, "DELETE FROM city")
+	require.NoError(t, err)
+	root.Finish()
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, root.Context().SpanID(), spans[0].ParentID())
+	// the parent span must not be finished by the traced calls
+	assert.Equal(t, "test.root", spans[1].OperationName())
+}
+
+func TestAnalyticsSettings(t *testing.T) {
+	assertRate := func(t *testing.T, mt mocktracer.Tracer, rate interface{}, opts ...Option) {
+		srv := newPGServer(t)
+		ctx := context.Background()
+		conn, err := Connect(ctx, srv.connString(), opts...)
+		require.NoError(t, err)
+		defer conn.Close(ctx)
+
+		spans := mt.FinishedSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, rate, spans[0].Tag(ext.EventSampleRate))
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, nil)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		assertRate(t, mt, 1.0, WithAnalytics(true))
+	})
+
+	t.Run("env", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		t.Setenv("DD_TRACE_PGX_ANALYTICS_ENABLED", "true")
+		assertRate(t, mt, 1.0)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		t.Setenv("DD_TRACE_PGX_ANALYTICS_ENABLED", "true")
+		assertRate(t, mt, 0.23, WithAnalyticsRate(0.23))
+	})
+}
+
+func TestDBMPropagation(t *testing.T) {
+	const (
+		query   = "SELECT name FROM city WHERE country = $1"
+		service = `/\*dddbs='pgx.db',dde='test-env',ddps='test-service',ddpv='1.0.0'\*/ `
+		full    = `/\*dddbs='pgx.db',dde='test-env',ddps='test-service',ddpv='1.0.0',traceparent='00-0000000000000000000000000000002a-[\da-f]{16}-01'\*/ `
+	)
+	testCases := []struct {
+		name     string
+		mode     tracer.DBMPropagationMode
+		callDB   func(ctx context.Context, conn *Conn) error
+		executed *regexp.Regexp
+	}{
+		{
+			name: "query-disabled",
+			mode: tracer.DBMPropagationModeDisabled,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				rows, err := conn.Query(ctx, query, DBMPropagation, "france")
+				if err != nil {
+					return err
+				}
+				rows.Close()
+				return rows.Err()
+			},
+			executed: regexp.MustCompile(`^` + regexp.QuoteMeta(query) + `$`),
+		},
+		{
+			name: "query-service",
+			mode: tracer.DBMPropagationModeService,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				rows, err := conn.Query(ctx, query, DBMPropagation, "france")
+				if err != nil {
+					return err
+				}
+				rows.Close()
+				return rows.Err()
+			},
+			executed: regexp.MustCompile(`^` + service + regexp.QuoteMeta(query) + `$`),
+		},
+		{
+			name: "query-full",
+			mode: tracer.DBMPropagationModeFull,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				rows, err := conn.Query(ctx, query, DBMPropagation, "france")
+				if err != nil {
+					return err
+				}
+				rows.Close()
+				return rows.Err()
+			},
+			executed: regexp.MustCompile(`^` + full + regexp.QuoteMeta(query) + `$`),
+		},
+		{
+			name: "query-full-no-rewriter",
+			mode: tracer.DBMPropagationModeFull,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				rows, err := conn.Query(ctx, query, "france")
+				if err != nil {
+					return err
+				}
+				rows.Close()
+				return rows.Err()
+			},
+			executed: regexp.MustCompile(`^` + full + regexp.QuoteMeta(query) + `$`),
+		},
+		{
+			name: "query-full-named-args",
+			mode: tracer.DBMPropagationModeFull,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				rows, err := conn.Query(ctx, "SELECT name FROM city WHERE country = @country", pgx.NamedArgs{"country": "france"})
+				if err != nil {
+					return err
+				}
+				rows.Close()
+				return rows.Err()
+			},
+			executed: regexp.MustCompile(`^` + full + regexp.QuoteMeta(query) + `$`),
+		},
+		{
+			name: "query-full-rewriter-named-args",
+			mode: tracer.DBMPropagationModeFull,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				rows, err := conn.Query(ctx, "SELECT name FROM city WHERE country = @country", DBMPropagation, pgx.NamedArgs{"country": "france"})
+				if err != nil {
+					return err
+				}
+				rows.Close()
+				return rows.Err()
+			},
+			executed: regexp.MustCompile(`^` + full + regexp.QuoteMeta(query) + `$`),
+		},
+		{
+			name: "exec-full",
+			mode: tracer.DBMPropagationModeFull,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				_, err := conn.Exec(ctx, "DELETE FROM city", DBMPropagation)
+				return err
+			},
+			executed: regexp.MustCompile(`^` + full + `DELETE FROM city$`),
+		},
+		{
+			name: "batch-full",
+			mode: tracer.DBMPropagationModeFull,
+			callDB: func(ctx context.Context, conn *Conn) error {
+				batch := &pgx.Batch{}
+				batch.Queue(query, DBMPropagation, "france")
+				return conn.SendBatch(ctx, batch).Close()
+			},
+			executed: regexp.MustCompile(`^` + full + regexp.QuoteMeta(query) + `$`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer.Start(tracer.WithService("test-service"), tracer.WithEnv("test-env"), tracer.WithServiceVersion("1.0.0"), tracer.WithLogStartup(false))
+			defer tracer.Stop()
+			srv := newPGServer(t)
+			ctx := context.Background()
+			conn, err := Connect(ctx, srv.connString(), WithDBMPropagation(tc.mode))
+			require.NoError(t, err)
+			defer conn.Close(ctx)
+
+			s, ctx := tracer.StartSpanFromContext(ctx, "test.call", tracer.WithSpanID(42))
+			err = tc.callDB(ctx, conn)
+			s.Finish()
+			require.NoError(t, err)
+
+			received := srv.received()
+			require.Len(t, received, 1)
+			assert.Regexp(t, tc.executed, received[0])
+		})
+	}
+}
+
+func TestDBMTraceContextTagging(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mode     tracer.DBMPropagationMode
+		args     []any
+		injected bool
+	}{
+		{name: "disabled", mode: tracer.DBMPropagationModeDisabled, args: []any{DBMPropagation}},
+		{name: "service", mode: tracer.DBMPropagationModeService, args: []any{DBMPropagation}},
+		{name: "full", mode: tracer.DBMPropagationModeFull, args: []any{DBMPropagation}, injected: true},
+		{name: "full-no-rewriter", mode: tracer.DBMPropagationModeFull, injected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			srv := newPGServer(t)
+			ctx := context.Background()
+			conn, err := Connect(ctx, srv.connString(), WithDBMPropagation(tc.mode))
+			require.NoError(t, err)
+			defer conn.Close(ctx)
+
+			_, err = conn.Exec(ctx, "DELETE FROM city", tc.args...)
+			require.NoError(t, err)
+
+			spans := spansOfType(mt.FinishedSpans(), queryTypeQuery)
+			require.Len(t, spans, 1)
+			if tc.injected {
+				assert.Equal(t, true, spans[0].Tag(keyDBMTraceInjected))
+				// the span is the one whose ID was injected
+				assert.Contains(t, srv.received()[0], fmt.Sprintf("-%016x-", spans[0].SpanID()))
+			} else {
+				assert.Nil(t, spans[0].Tag(keyDBMTraceInjected))
+			}
+		})
+	}
+}
+
+func TestDBMPropagationPrepare(t *testing.T) {
+	const query = "SELECT name FROM city WHERE country = $1"
+	testCases := []struct {
+		name     string
+		mode     tracer.DBMPropagationMode
+		args     []any
+		prepares int
+		parses   int
+	}{
+		// the statement is prepared once and then reused from the cache of the connection
+		{name: "service", mode: tracer.DBMPropagationModeService, prepares: 1, parses: 1},
+		// the unique queries are executed without being prepared
+		{name: "full", mode: tracer.DBMPropagationModeFull, parses: 3},
+		{name: "full-describe-exec", mode: tracer.DBMPropagationModeFull, args: []any{pgx.QueryExecModeDescribeExec}, parses: 3},
+		{name: "full-rewriter", mode: tracer.DBMPropagationModeFull, args: []any{DBMPropagation}, parses: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mt := mocktracer.Start()
+			defer mt.Stop()
+			srv := newPGServer(t)
+			ctx := context.Background()
+			conn, err := Connect(ctx, srv.connString(), WithDBMPropagation(tc.mode))
+			require.NoError(t, err)
+			defer conn.Close(ctx)
+
+			for i := 0; i < 3; i++ {
+				var name string
+				require.NoError(t, conn.QueryRow(ctx, query, append(tc.args, "france")...).Scan(&name))
+			}
+
+			assert.Len(t, spansOfType(mt.FinishedSpans(), queryTypePrepare), tc.prepares)
+			assert.Len(t, srv.received(), tc.parses)
+		})
+	}
+}
+
+func TestDBMPropagationPoolAndTx(t *testing.T) {
+	tracer.Start(tracer.WithService("test-service"), tracer.WithEnv("test-env"), tracer.WithServiceVersion("1.0.0"), tracer.WithLogStartup(false))
+	defer tracer.Stop()
+	srv := newPGServer(t)
+	ctx := context.Background()
+	pool, err := NewPool(ctx, srv.connString(), WithDBMPropagation(tracer.DBMPropagationModeService))
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, "DELETE FROM city")
+	require.NoError(t, err)
+	tx, err := pool.Begin(ctx)
+	require.NoError(t, err)
+	_, err = tx.Exec(ctx, "UPDATE city SET name = 'paris'")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit(ctx))
+
+	// the calls have no parent span holding the environment and the version
+	const service = `/*dddbs='pgx.db',ddps='test-service'*/ `
+	received := srv.received()
+	assert.Contains(t, received, service+"DELETE FROM city")
+	assert.Contains(t, received, service+"UPDATE city SET name = 'paris'")
+}
@@ -0,0 +1,429 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package pgx
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const componentName = "jackc/pgx.v5"
+
+type queryType string
+
+const (
+	queryTypeConnect  queryType = "Connect"
+	queryTypeQuery    queryType = "Query"
+	queryTypeBatch    queryType = "Batch"
+	queryTypeCopyFrom queryType = "CopyFrom"
+	queryTypePrepare  queryType = "Prepare"
+)
+
+const (
+	keyQueryType        = "sql.query_type"
+	keyRowsAffected     = "sql.rows_affected"
+	keyDBMTraceInjected = "_dd.dbm_trace_injected"
+)
+
+type contextKey int
+
+const (
+	spanKey    contextKey = iota // ddtrace.Span
+	commentKey                   // *queryComment
+	batchKey                     // *batchState
+)
+
+// queryComment holds the query of a traced call along with the comment to be
+// prepended to it by DBMPropagation.
+type queryComment struct {
+	query   string
+	comment string
+	// injected reports whether the trace context was injected in the comment.
+	injected bool
+}
+
+// batchState holds the state of a traced batch, used to trace its queries.
+type batchState struct {
+	tracer *pgxTracer
+	span   ddtrace.Span
+	// parent holds the context of the parent of the batch span, if any.
+	parent ddtrace.SpanContext
+
+	mu sync.Mutex // guards below fields
+	// last holds the time at which the last query result of the batch was read.
+	last time.Time
+	// queries maps the queries commented by DBMPropagation to their original
+	// text and to the ID of the span injected in their comment, if any.
+	queries map[string]batchQuery
+}
+
+// batchQuery is a query of a batch commented by DBMPropagation.
+type batchQuery struct {
+	query  string
+	spanID uint64
+}
+
+// pgxTracer implements the tracer interfaces of pgx, tracing the calls made on
+// the connections it is configured on.
+type pgxTracer struct {
+	cfg  *config
+	meta map[string]string
+	// execMode is the default query execution mode of the traced connections.
+	execMode pgx.QueryExecMode
+}
+
+var (
+	_ pgx.QueryTracer    = (*pgxTracer)(nil)
+	_ pgx.BatchTracer    = (*pgxTracer)(nil)
+	_ pgx.CopyFromTracer = (*pgxTracer)(nil)
+	_ pgx.PrepareTracer  = (*pgxTracer)(nil)
+	_ pgx.ConnectTracer  = (*pgxTracer)(nil)
+)
+
+// connMeta returns the tags describing the database the connections are made
+// to, according to the given configuration.
+func connMeta(connConfig *pgx.ConnConfig) map[string]string {
+	meta := map[string]string{
+		ext.DBSystem: ext.DBSystemPostgreSQL,
+	}
+	if connConfig == nil {
+		return meta
+	}
+	if connConfig.Host != "" {
+		meta[ext.TargetHost] = connConfig.Host
+	}
+	if connConfig.Port != 0 {
+		meta[ext.TargetPort] = strconv.Itoa(int(connConfig.Port))
+	}
+	if connConfig.User != "" {
+		meta[ext.DBUser] = connConfig.User
+	}
+	if connConfig.Database != "" {
+		meta[ext.DBName] = connConfig.Database
+	}
+	if app := connConfig.RuntimeParams["application_name"]; app != "" {
+		meta[ext.DBApplication] = app
+	}
+	return meta
+}
+
+// startSpan starts a span for a call of the given type, returning a context
+// containing it. It returns a nil span, along with the given context, when no
+// span should be created.
+func (t *pgxTracer) startSpan(ctx context.Context, qtype queryType, resource string, opts ...ddtrace.StartSpanOption) (ddtrace.Span, context.Context) {
+	if _, ok := tracer.SpanFromContext(ctx); t.cfg.childSpansOnly && !ok {
+		return nil, ctx
+	}
+	if resource == "" {
+		resource = string(qtype)
+	}
+	opts = append(opts,
+		tracer.ServiceName(t.cfg.serviceName),
+		tracer.SpanType(ext.SpanTypeSQL),
+		tracer.ResourceName(resource),
+		tracer.Tag(keyQueryType, string(qtype)),
+		tracer.Tag(ext.Component, componentName),
+		tracer.Tag(ext.SpanKind, ext.SpanKindClient),
+	)
+	if !math.IsNaN(t.cfg.analyticsRate) {
+		opts = append(opts, tracer.Tag(ext.EventSampleRate, t.cfg.analyticsRate))
+	}
+	for k, v := range t.meta {
+		opts = append(opts, tracer.Tag(k, v))
+	}
+	for k, v := range t.cfg.tags {
+		opts = append(opts, tracer.Tag(k, v))
+	}
+	span, ctx := tracer.StartSpanFromContext(ctx, "pgx.query", opts...)
+	return span, context.WithValue(ctx, spanKey, span)
+}
+
+// finishSpan finishes the span started by startSpan stored in ctx, if any.
+func (t *pgxTracer) finishSpan(ctx context.Context, err error, commandTag *pgconn.CommandTag) {
+	span, ok := ctx.Value(spanKey).(ddtrace.Span)
+	if !ok {
+		return
+	}
+	t.finish(span, err, commandTag)
+}
+
+func (t *pgxTracer) finish(span ddtrace.Span, err error, commandTag *pgconn.CommandTag, opts ...ddtrace.FinishOption) {
+	if err == nil && commandTag != nil && !isSelect(*commandTag) {
+		span.SetTag(keyRowsAffected, commandTag.RowsAffected())
+	}
+	if err != nil && (t.cfg.errCheck == nil || t.cfg.errCheck(err)) {
+		opts = append(opts, tracer.WithError(err))
+	}
+	span.Finish(opts...)
+}
+
+// isSelect reports whether the command tag is the one of a query returning
+// rows, in which case the affected rows are the returned ones.
+func isSelect(commandTag pgconn.CommandTag) bool {
+	return commandTag.Select() || len(commandTag.String()) == 0
+}
+
+// TraceQueryStart starts the span of a query or of an execution.
+func (t *pgxTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	var (
+		opts    []ddtrace.StartSpanOption
+		comment *queryComment
+	)
+	if t.cfg.dbmPropagationEnabled() {
+		// the span ID is generated before the span is created, so that it can
+		// be part of the comment.
+		var spanCtx ddtrace.SpanContext
+		if span, ok := tracer.SpanFromContext(ctx); ok {
+			spanCtx = span.Context()
+		}
+		// with no query, the carrier only holds the comment, which is prepended
+		// to the query once rewritten by the other query rewriters, if any.
+		carrier := tracer.SQLCommentCarrier{Mode: t.cfg.dbmPropagationMode, DBServiceName: t.cfg.serviceName}
+		if err := carrier.Inject(spanCtx); err != nil {
+			// this should never happen
+			log.Warn("contrib/jackc/pgx.v5: failed to inject query comments: %v", err)
+		}
+		opts = append(opts, tracer.WithSpanID(carrier.SpanID))
+		comment = &queryComment{
+			query:    data.SQL,
+			comment:  carrier.Query,
+			injected: t.cfg.dbmPropagationMode == tracer.DBMPropagationModeFull,
+		}
+	}
+	span, ctx := t.startSpan(ctx, queryTypeQuery, data.SQL, opts...)
+	if span != nil && comment != nil {
+		ctx = context.WithValue(ctx, commentKey, comment)
+	}
+	return ctx
+}
+
+// TraceQueryEnd finishes the span of a query or of an execution. The span of a
+// query is finished once its rows are closed.
+func (t *pgxTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.finishSpan(ctx, data.Err, &data.CommandTag)
+}
+
+// TraceBatchStart starts the span of a batch. The queries of the batch are
+// traced as its children.
+func (t *pgxTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	var parent ddtrace.SpanContext
+	if span, ok := tracer.SpanFromContext(ctx); ok {
+		parent = span.Context()
+	}
+	span, ctx := t.startSpan(ctx, queryTypeBatch, "", tracer.Tag("sql.batch_size", data.Batch.Len()))
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, batchKey, &batchState{
+		tracer:  t,
+		span:    span,
+		parent:  parent,
+		last:    time.Now(),
+		queries: make(map[string]batchQuery),
+	})
+}
+
+// TraceBatchQuery traces a query of a batch, from the moment the result of the
+// previous query was read until its own result is read.
+func (t *pgxTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	b, ok := ctx.Value(batchKey).(*batchState)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	b.mu.Lock()
+	start := b.last
+	b.last = now
+	q, commented := b.queries[data.SQL]
+	b.mu.Unlock()
+	query := data.SQL
+	opts := []ddtrace.StartSpanOption{
+		tracer.ChildOf(b.span.Context()),
+		tracer.StartTime(start),
+	}
+	if commented {
+		query = q.query
+		if q.spanID != 0 {
+			opts = append(opts, tracer.WithSpanID(q.spanID), tracer.Tag(keyDBMTraceInjected, true))
+		}
+	}
+	span, _ := t.startSpan(ctx, queryTypeQuery, query, opts...)
+	t.finish(span, data.Err, &data.CommandTag, tracer.FinishTime(now))
+}
+
+// TraceBatchEnd finishes the span of a batch.
+func (t *pgxTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.finishSpan(ctx, data.Err, nil)
+}
+
+// TraceCopyFromStart starts the span of a copy.
+func (t *pgxTracer) TraceCopyFromStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	_, ctx = t.startSpan(ctx, queryTypeCopyFrom, "", tracer.Tag("sql.copy_from.table", data.TableName.Sanitize()))
+	return ctx
+}
+
+// TraceCopyFromEnd finishes the span of a copy.
+func (t *pgxTracer) TraceCopyFromEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	t.finishSpan(ctx, data.Err, &data.CommandTag)
+}
+
+// TracePrepareStart starts the span of the preparation of a statement.
+func (t *pgxTracer) TracePrepareStart(ctx context.Context, _ *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	_, ctx = t.startSpan(ctx, queryTypePrepare, data.SQL)
+	return ctx
+}
+
+// TracePrepareEnd finishes the span of the preparation of a statement.
+func (t *pgxTracer) TracePrepareEnd(ctx context.Context, _ *pgx.Conn, data pgx.TracePrepareEndData) {
+	t.finishSpan(ctx, data.Err, nil)
+}
+
+// TraceConnectStart starts the span of a connection.
+func (t *pgxTracer) TraceConnectStart(ctx context.Context, _ pgx.TraceConnectStartData) context.Context {
+	_, ctx = t.startSpan(ctx, queryTypeConnect, "")
+	return ctx
+}
+
+// TraceConnectEnd finishes the span of a connection.
+func (t *pgxTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	t.finishSpan(ctx, data.Err, nil)
+}
+
+// DBMPropagation is a query rewriter injecting the Database Monitoring comments
+// in the queries of connections traced with the WithDBMPropagation option, or
+// with the DD_DBM_PROPAGATION_MODE environment variable. It is automatically
+// given to the queries and executions of the Conn, Pool and transactions
+// returned by this package. As pgx doesn't allow its tracers to modify the
+// queries, it must otherwise be given as the first argument of the calls whose
+// queries should be commented, such as those of the connections acquired from a
+// Pool, or the queries of batches:
+//
+//	batch.Queue("SELECT name FROM city WHERE id = $1", pgxtrace.DBMPropagation, id)
+//
+// As pgx only uses the last query rewriter given to a call, DBMPropagation can't
+// be combined with other query rewriters, such as pgx.NamedArgs, in these calls.
+// The queries of untraced connections are left untouched.
+var DBMPropagation pgx.QueryRewriter = dbmPropagation{}
+
+// dbmPropagation is the DBMPropagation query rewriter, wrapping the query
+// rewriter given along with it, if any, such as pgx.NamedArgs. pgx only uses the
+// last query rewriter given to a call.
+type dbmPropagation struct {
+	next pgx.QueryRewriter
+}
+
+// RewriteQuery implements pgx.QueryRewriter.
+func (r dbmPropagation) RewriteQuery(ctx context.Context, conn *pgx.Conn, sql string, args []any) (string, []any, error) {
+	original := sql
+	if r.next != nil {
+		var err error
+		if sql, args, err = r.next.RewriteQuery(ctx, conn, sql, args); err != nil {
+			return sql, args, err
+		}
+	}
+	if c, ok := ctx.Value(commentKey).(*queryComment); ok && c.query == original {
+		if c.comment == "" {
+			return sql, args, nil
+		}
+		if span, ok := ctx.Value(spanKey).(ddtrace.Span); ok && c.injected {
+			span.SetTag(keyDBMTraceInjected, true)
+		}
+		return c.comment + " " + sql, args, nil
+	}
+	b, ok := ctx.Value(batchKey).(*batchState)
+	if !ok {
+		return sql, args, nil
+	}
+	t := b.tracer
+	if !t.cfg.dbmPropagationEnabled() {
+		return sql, args, nil
+	}
+	// the queries of a batch are traced as children of the batch span once
+	// their results are read, using the span ID injected in their comment. The
+	// parent of the batch span, if any, holds the environment and the version of
+	// the service.
+	spanCtx := b.parent
+	if spanCtx == nil {
+		spanCtx = b.span.Context()
+	}
+	carrier := tracer.SQLCommentCarrier{Query: sql, Mode: t.cfg.dbmPropagationMode, DBServiceName: t.cfg.serviceName}
+	if err := carrier.Inject(spanCtx); err != nil {
+		// this should never happen
+		log.Warn("contrib/jackc/pgx.v5: failed to inject query comments: %v", err)
+		return sql, args, nil
+	}
+	q := batchQuery{query: original}
+	if t.cfg.dbmPropagationMode == tracer.DBMPropagationModeFull {
+		q.spanID = carrier.SpanID
+	}
+	b.mu.Lock()
+	b.queries[carrier.Query] = q
+	b.mu.Unlock()
+	return carrier.Query, args, nil
+}
+
+// withDBMPropagation returns the arguments of a query or an execution with the
+// DBMPropagation query rewriter prepended, wrapping the query rewriter given by
+// the caller, if any. The arguments are returned as-is when the propagation is
+// disabled.
+//
+// In the full mode, every query is unique because of the trace context of its
+// comment. The queries which would be prepared by their execution mode are
+// then executed with pgx.QueryExecModeExec instead, since every execution
+// would otherwise prepare a statement that can't be reused, and evict the
+// reusable statements from the cache of the connection.
+func (t *pgxTracer) withDBMPropagation(args []any) []any {
+	if !t.cfg.dbmPropagationEnabled() {
+		return args
+	}
+	mode := t.execMode
+	var rewriter dbmPropagation
+	// the options of the call precede its arguments
+	options := make([]any, 1, len(args)+1)
+	i := 0
+loop:
+	for ; i < len(args); i++ {
+		switch arg := args[i].(type) {
+		case dbmPropagation:
+			if arg.next != nil {
+				rewriter.next = arg.next
+			}
+		case pgx.QueryRewriter:
+			rewriter.next = arg
+		case pgx.QueryExecMode:
+			mode = arg
+			options = append(options, arg)
+		case pgx.QueryResultFormats, pgx.QueryResultFormatsByOID:
+			options = append(options, arg)
+		default:
+			break loop
+		}
+	}
+	options[0] = rewriter
+	if t.cfg.dbmPropagationMode == tracer.DBMPropagationModeFull && preparesStatement(mode) {
+		// the last execution mode option prevails
+		options = append(options, pgx.QueryExecModeExec)
+	}
+	return append(options, args[i:]...)
+}
+
+// preparesStatement reports whether the queries executed with mode are
+// prepared, which is the case of all the modes but pgx.QueryExecModeExec and
+// pgx.QueryExecModeSimpleProtocol.
+func preparesStatement(mode pgx.QueryExecMode) bool {
+	return mode != pgx.QueryExecModeExec && mode != pgx.QueryExecModeSimpleProtocol
+}
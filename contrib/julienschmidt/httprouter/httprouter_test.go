@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -191,3 +192,12 @@ func handler200Parameter(w http.ResponseWriter, _ *http.Request, p httprouter.Pa
 func handler500(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	http.Error(w, "500!", http.StatusInternalServerError)
 }
+
+func TestAppSec(t *testing.T) {
+	router := New()
+	router.GET("/hello/:name", func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.Write([]byte(appsectest.Body))
+	})
+	router.Handler(http.MethodPost, appsectest.LoginRoute, appsectest.LoginHandler)
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(router), Login: true})
+}
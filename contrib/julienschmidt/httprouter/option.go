@@ -17,6 +17,7 @@ type routerConfig struct {
 	serviceName   string
 	spanOpts      []ddtrace.StartSpanOption
 	analyticsRate float64
+	isStatusError func(statusCode int) bool
 }
 
 // RouterOption represents an option that can be passed to New.
@@ -70,3 +71,11 @@ func WithAnalyticsRate(rate float64) RouterOption {
 		}
 	}
 }
+
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error.
+func WithStatusCheck(fn func(statusCode int) bool) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.isStatusError = fn
+	}
+}
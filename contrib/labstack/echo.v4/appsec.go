@@ -25,14 +25,14 @@ func withAppSec(next echo.HandlerFunc, span tracer.Span) echo.HandlerFunc {
 		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			c.SetRequest(r)
 			err = next(c)
-			// If the error is a user monitoring one, it means appsec actions will take care of writing the response
+			// If the error is a monitoring one, it means appsec actions will take care of writing the response
 			// and handling the error. Don't call the echo error handler in this case
-			if _, ok := err.(*sharedsec.UserMonitoringError); !ok && err != nil {
+			if err != nil && !isMonitoringError(err) {
 				c.Error(err)
 			}
 		})
 		// Wrap the echo response to allow monitoring of the response status code in httpsec.WrapHandler()
-		httpsec.WrapHandler(handler, span, params).ServeHTTP(&statusResponseWriter{Response: c.Response()}, c.Request())
+		httpsec.WrapHandler(handler, span, params, c.Path()).ServeHTTP(&statusResponseWriter{Response: c.Response()}, c.Request())
 		// If an error occurred, wrap it under an echo.HTTPError. We need to do this so that APM doesn't override
 		// the response code tag with 500 in case it doesn't recognize the error type.
 		if _, ok := err.(*echo.HTTPError); !ok && err != nil {
@@ -45,6 +45,16 @@ func withAppSec(next echo.HandlerFunc, span tracer.Span) echo.HandlerFunc {
 
 }
 
+// isMonitoringError returns true if err was returned by an appsec SDK function to block the request.
+func isMonitoringError(err error) bool {
+	switch err.(type) {
+	case *sharedsec.UserMonitoringError, *sharedsec.MonitoringError:
+		return true
+	default:
+		return false
+	}
+}
+
 // statusResponseWriter wraps an echo response to allow tracking/retrieving its status code through a Status() method
 // without having to rely on the echo error handlers
 type statusResponseWriter struct {
@@ -12,14 +12,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
 	pappsec "gopkg.in/DataDog/dd-trace-go.v1/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 
 	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -79,6 +82,32 @@ func TestAppSec(t *testing.T) {
 		require.True(t, strings.Contains(event, "server.request.uri.raw"))
 	})
 
+	// Test that each request is monitored once, with its own span, when requests are served concurrently
+	t.Run("concurrent-requests", func(t *testing.T) {
+		mt := mocktracer.Start()
+		defer mt.Stop()
+		const n = 10
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := srv.Client().Post(srv.URL+"/path0.0/param0/path0.1/param1/path0.2/appscan_fingerprint/path0.3/param3", "text/plain", nil)
+				if assert.NoError(t, err) {
+					res.Body.Close()
+					assert.Equal(t, http.StatusOK, res.StatusCode)
+				}
+			}()
+		}
+		wg.Wait()
+		finished := mt.FinishedSpans()
+		require.Len(t, finished, n)
+		for _, s := range finished {
+			event, _ := s.Tag("_dd.appsec.json").(string)
+			require.Equal(t, 1, strings.Count(event, "crs-913-120"))
+		}
+	})
+
 	// Test a security scanner attack via path parameters
 	t.Run("path-params", func(t *testing.T) {
 		t.Run("regular", func(t *testing.T) {
@@ -622,3 +651,13 @@ func TestBlocking(t *testing.T) {
 		})
 	}
 }
+
+func TestAppSecConformance(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/hello/:name", func(c echo.Context) error {
+		return c.String(http.StatusOK, appsectest.Body)
+	})
+	e.POST(appsectest.LoginRoute, echo.WrapHandler(appsectest.LoginHandler))
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(e), Login: true})
+}
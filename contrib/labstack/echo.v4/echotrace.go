@@ -63,17 +63,19 @@ func Middleware(opts ...Option) echo.MiddlewareFunc {
 
 			span, ctx := httptrace.StartRequestSpan(request, opts...)
 			defer func() {
+				httptrace.SetResponseHeaderTags(span, c.Response().Header())
 				span.Finish(finishOpts...)
 			}()
 
 			// pass the span through the request context
 			c.SetRequest(request.WithContext(ctx))
 
+			handler := next
 			if appsec.Enabled() {
-				next = withAppSec(next, span)
+				handler = withAppSec(next, span)
 			}
 			// serve the request to the next middleware
-			err := next(c)
+			err := handler(c)
 			if err != nil {
 				// invokes the registered HTTP error handler
 				c.Error(err)
@@ -15,6 +15,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -92,6 +93,30 @@ func TestTrace200(t *testing.T) {
 	assert.Equal("http://example.com/user/123", span.Tag(ext.HTTPURL))
 }
 
+func TestHeaderTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	globalconfig.SetHeaderTags([]globalconfig.HeaderTag{
+		{Header: "X-Request-Id", RequestTag: "http.request.headers.x-request-id", ResponseTag: "http.response.headers.x-request-id"},
+	})
+	defer globalconfig.SetHeaderTags(nil)
+
+	router := echo.New()
+	router.Use(Middleware())
+	router.GET("/", func(c echo.Context) error {
+		c.Response().Header().Set("X-Request-Id", "456")
+		return c.NoContent(200)
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-Id", "123")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "123", spans[0].Tag("http.request.headers.x-request-id"))
+	assert.Equal(t, "456", spans[0].Tag("http.response.headers.x-request-id"))
+}
+
 func TestTraceAnalytics(t *testing.T) {
 	assert := assert.New(t)
 	mt := mocktracer.Start()
@@ -10,6 +10,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 )
 
@@ -33,7 +34,7 @@ func defaults(cfg *config) {
 		cfg.serviceName = svc
 	}
 	cfg.analyticsRate = math.NaN()
-	cfg.isStatusError = isServerError
+	cfg.isStatusError = httptrace.IsServerError
 }
 
 // WithServiceName sets the given service name for the system.
@@ -90,7 +91,3 @@ func WithStatusCheck(fn func(statusCode int) bool) Option {
 		cfg.isStatusError = fn
 	}
 }
-
-func isServerError(statusCode int) bool {
-	return statusCode >= 500 && statusCode < 600
-}
@@ -17,6 +17,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
 	"github.com/labstack/echo"
@@ -52,15 +53,19 @@ func Middleware(opts ...Option) echo.MiddlewareFunc {
 
 			span, ctx := httptrace.StartRequestSpan(request, opts...)
 			defer func() {
-				//httptrace.FinishRequestSpan(span, c.Response().Status, finishOpts...)
+				httptrace.SetResponseHeaderTags(span, c.Response().Header())
 				span.Finish(finishOpts...)
 			}()
 
 			// pass the span through the request context
 			c.SetRequest(request.WithContext(ctx))
 
+			handler := next
+			if appsec.Enabled() {
+				handler = withAppSec(next, span)
+			}
 			// serve the request to the next middleware
-			err := next(c)
+			err := handler(c)
 			if err != nil {
 				// invokes the registered HTTP error handler
 				c.Error(err)
@@ -12,6 +12,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -390,3 +391,12 @@ func TestNoDebugStack(t *testing.T) {
 	assert.Equal("labstack/echo", span.Tag(ext.Component))
 	assert.Equal(ext.SpanKindServer, span.Tag(ext.SpanKind))
 }
+
+func TestAppSec(t *testing.T) {
+	router := echo.New()
+	router.Use(Middleware())
+	router.GET("/hello/:name", func(c echo.Context) error {
+		return c.String(http.StatusOK, appsectest.Body)
+	})
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(router)})
+}
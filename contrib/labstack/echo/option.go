@@ -8,6 +8,7 @@ package echo
 import (
 	"math"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 )
@@ -32,7 +33,7 @@ func defaults(cfg *config) {
 	} else {
 		cfg.analyticsRate = math.NaN()
 	}
-	cfg.isStatusError = isServerError
+	cfg.isStatusError = httptrace.IsServerError
 }
 
 // WithServiceName sets the given service name for the system.
@@ -81,7 +82,3 @@ func WithStatusCheck(fn func(statusCode int) bool) Option {
 		cfg.isStatusError = fn
 	}
 }
-
-func isServerError(statusCode int) bool {
-	return statusCode >= 500 && statusCode < 600
-}
@@ -54,10 +54,11 @@ func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	TraceAndServe(mux.ServeMux, w, r, &ServeConfig{
-		Service:  mux.cfg.serviceName,
-		Resource: resource,
-		SpanOpts: mux.cfg.spanOpts,
-		Route:    route,
+		Service:       mux.cfg.serviceName,
+		Resource:      resource,
+		SpanOpts:      mux.cfg.spanOpts,
+		Route:         route,
+		IsStatusError: mux.cfg.isStatusError,
 	})
 }
 
@@ -82,10 +83,11 @@ func WrapHandler(h http.Handler, service, resource string, opts ...Option) http.
 		}
 
 		TraceAndServe(h, w, req, &ServeConfig{
-			Service:    service,
-			Resource:   resource,
-			FinishOpts: cfg.finishOpts,
-			SpanOpts:   cfg.spanOpts,
+			Service:       service,
+			Resource:      resource,
+			FinishOpts:    cfg.finishOpts,
+			SpanOpts:      cfg.spanOpts,
+			IsStatusError: cfg.isStatusError,
 		})
 	})
 }
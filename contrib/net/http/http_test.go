@@ -12,6 +12,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -77,6 +78,48 @@ func TestHttpTracer500(t *testing.T) {
 	assert.Equal("net/http", s.Tag(ext.Component))
 }
 
+func TestHeaderTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	globalconfig.SetHeaderTags([]globalconfig.HeaderTag{
+		{Header: "X-Request-Id", RequestTag: "request.id", ResponseTag: "request.id"},
+		{Header: "Content-Type", RequestTag: "http.request.headers.content-type", ResponseTag: "http.response.headers.content-type"},
+	})
+	defer globalconfig.SetHeaderTags(nil)
+
+	handler := WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("OK"))
+	}), "my-service", "my-resource")
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Request-Id", "123")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "123", spans[0].Tag("request.id"))
+	assert.Equal(t, "text/plain", spans[0].Tag("http.response.headers.content-type"))
+	assert.Nil(t, spans[0].Tag("http.request.headers.content-type"))
+}
+
+func TestStatusCheck(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	mux := NewServeMux(WithStatusCheck(func(statusCode int) bool { return statusCode == 404 }))
+	mux.HandleFunc("/500", handler500)
+	mux.HandleFunc("/404", http.NotFound)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/500", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/404", nil))
+
+	spans := mt.FinishedSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "500", spans[0].Tag(ext.HTTPCode))
+	assert.Nil(t, spans[0].Tag(ext.Error))
+	assert.Equal(t, "404", spans[1].Tag(ext.HTTPCode))
+	assert.NotNil(t, spans[1].Tag(ext.Error))
+}
+
 func TestWrapHandler200(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
@@ -309,3 +352,13 @@ func handler200(w http.ResponseWriter, r *http.Request) {
 func handler500(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "500!", http.StatusInternalServerError)
 }
+
+func TestAppSec(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("/hello/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(appsectest.Body))
+	})
+	mux.Handle(appsectest.LoginRoute, appsectest.LoginHandler)
+	// The standard ServeMux has no path parameters
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(mux), NoPathParams: true, Login: true})
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"net/http"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -23,6 +24,7 @@ type config struct {
 	finishOpts    []ddtrace.FinishOption
 	ignoreRequest func(*http.Request) bool
 	resourceNamer func(*http.Request) string
+	isStatusError func(statusCode int) bool
 }
 
 // MuxOption has been deprecated in favor of Option.
@@ -47,6 +49,7 @@ func defaults(cfg *config) {
 	}
 	cfg.ignoreRequest = func(_ *http.Request) bool { return false }
 	cfg.resourceNamer = func(_ *http.Request) string { return "" }
+	cfg.isStatusError = httptrace.IsServerError
 }
 
 // WithIgnoreRequest holds the function to use for determining if the
@@ -57,6 +60,14 @@ func WithIgnoreRequest(f func(*http.Request) bool) MuxOption {
 	}
 }
 
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error.
+func WithStatusCheck(fn func(statusCode int) bool) Option {
+	return func(cfg *config) {
+		cfg.isStatusError = fn
+	}
+}
+
 // WithServiceName sets the given service name for the returned ServeMux.
 func WithServiceName(name string) MuxOption {
 	return func(cfg *config) {
@@ -130,6 +141,7 @@ type roundTripperConfig struct {
 	ignoreRequest func(*http.Request) bool
 	spanOpts      []ddtrace.StartSpanOption
 	errCheck      func(err error) bool
+	isStatusError func(statusCode int) bool
 }
 
 func newRoundTripperConfig() *roundTripperConfig {
@@ -137,6 +149,7 @@ func newRoundTripperConfig() *roundTripperConfig {
 		analyticsRate: globalconfig.AnalyticsRate(),
 		resourceNamer: defaultResourceNamer,
 		ignoreRequest: func(_ *http.Request) bool { return false },
+		isStatusError: httptrace.ClientStatusCheck(httptrace.IsServerError),
 	}
 }
 
@@ -226,3 +239,11 @@ func RTWithErrorCheck(fn func(err error) bool) RoundTripperOption {
 		cfg.errCheck = fn
 	}
 }
+
+// RTWithStatusCheck specifies a function fn which reports whether the passed
+// statusCode of a response should be considered an error.
+func RTWithStatusCheck(fn func(statusCode int) bool) RoundTripperOption {
+	return func(cfg *roundTripperConfig) {
+		cfg.isStatusError = fn
+	}
+}
@@ -12,9 +12,12 @@ import (
 	"os"
 	"strconv"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
 )
 
 type roundTripper struct {
@@ -48,6 +51,7 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err er
 		opts = append(opts, rt.cfg.spanOpts...)
 	}
 	span, ctx := tracer.StartSpanFromContext(req.Context(), "http.request", opts...)
+	httptrace.SetRequestHeaderTags(span, req.Header)
 	defer func() {
 		if rt.cfg.after != nil {
 			rt.cfg.after(res, span)
@@ -61,6 +65,21 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err er
 	if rt.cfg.before != nil {
 		rt.cfg.before(req, span)
 	}
+	if appsec.Enabled() {
+		// Let appsec block the outgoing request when its URL is controlled by
+		// the incoming request being handled, if any. The user credentials of
+		// the URL are left out, like in the span URL tag.
+		u := *req.URL
+		u.User = nil
+		if err = httpsec.ProtectRoundTrip(ctx, u.String()); err != nil {
+			// RoundTrip must always close the request body, even on errors
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			span.SetTag(ext.Error, err)
+			return nil, err
+		}
+	}
 	r2 := req.Clone(ctx)
 	// inject the span context into the http request copy
 	err = tracer.Inject(span.Context(), tracer.HTTPHeadersCarrier(r2.Header))
@@ -76,8 +95,8 @@ func (rt *roundTripper) RoundTrip(req *http.Request) (res *http.Response, err er
 		}
 	} else {
 		span.SetTag(ext.HTTPCode, strconv.Itoa(res.StatusCode))
-		// treat 5XX as errors
-		if res.StatusCode/100 == 5 {
+		httptrace.SetResponseHeaderTags(span, res.Header)
+		if rt.cfg.isStatusError(res.StatusCode) {
 			span.SetTag("http.errors", res.Status)
 			span.SetTag(ext.Error, fmt.Errorf("%d: %s", res.StatusCode, http.StatusText(res.StatusCode)))
 		}
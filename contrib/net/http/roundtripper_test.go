@@ -139,6 +139,66 @@ func TestRoundTripperServerError(t *testing.T) {
 	assert.Equal(t, "net/http", s1.Tag(ext.Component))
 }
 
+func TestRoundTripperStatusCheck(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/not-found" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	client := &http.Client{
+		Transport: WrapRoundTripper(http.DefaultTransport, RTWithStatusCheck(func(statusCode int) bool {
+			return statusCode >= 400 && statusCode < 500
+		})),
+	}
+	client.Get(s.URL + "/not-found")
+	client.Get(s.URL + "/unavailable")
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "404", spans[0].Tag(ext.HTTPCode))
+	assert.Equal(t, fmt.Errorf("404: Not Found"), spans[0].Tag(ext.Error))
+	assert.Equal(t, "503", spans[1].Tag(ext.HTTPCode))
+	assert.Nil(t, spans[1].Tag(ext.Error))
+}
+
+func TestRoundTripperHeaderTags(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+	globalconfig.SetHeaderTags([]globalconfig.HeaderTag{
+		{Header: "X-Request-Id", RequestTag: "http.request.headers.x-request-id", ResponseTag: "http.response.headers.x-request-id"},
+		{Header: "X-Datadog-Trace-Id", RequestTag: "trace.id", ResponseTag: "trace.id"},
+	})
+	defer globalconfig.SetHeaderTags(nil)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "456")
+		w.Write([]byte("Hello World"))
+	}))
+	defer s.Close()
+
+	req, err := http.NewRequest("GET", s.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "123")
+	client := &http.Client{Transport: WrapRoundTripper(http.DefaultTransport)}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	spans := mt.FinishedSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "123", spans[0].Tag("http.request.headers.x-request-id"))
+	assert.Equal(t, "456", spans[0].Tag("http.response.headers.x-request-id"))
+	// the propagation headers injected by the tracer are not reported
+	assert.Nil(t, spans[0].Tag("trace.id"))
+}
+
 func TestRoundTripperNetworkError(t *testing.T) {
 	mt := mocktracer.Start()
 	defer mt.Stop()
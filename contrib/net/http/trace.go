@@ -37,6 +37,8 @@ type ServeConfig struct {
 	FinishOpts []ddtrace.FinishOption
 	// SpanOpts specifies any options to be applied to the request starting span.
 	SpanOpts []ddtrace.StartSpanOption
+	// IsStatusError optionally reports whether the status code of the response should be considered an error.
+	IsStatusError func(statusCode int) bool
 }
 
 // TraceAndServe serves the handler h using the given ResponseWriter and Request, applying tracing
@@ -50,11 +52,11 @@ func TraceAndServe(h http.Handler, w http.ResponseWriter, r *http.Request, cfg *
 	span, ctx := httptrace.StartRequestSpan(r, opts...)
 	rw, ddrw := wrapResponseWriter(w)
 	defer func() {
-		httptrace.FinishRequestSpan(span, ddrw.status, cfg.FinishOpts...)
+		httptrace.FinishRequestSpan(span, ddrw.status, cfg.IsStatusError, w.Header(), cfg.FinishOpts...)
 	}()
 
 	if appsec.Enabled() {
-		h = httpsec.WrapHandler(h, span, cfg.RouteParams)
+		h = httpsec.WrapHandler(h, span, cfg.RouteParams, cfg.Route)
 	}
 	h.ServeHTTP(rw, r.WithContext(ctx))
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/segmentio/kafka-go"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -69,6 +70,8 @@ func (r *Reader) startSpan(ctx context.Context, msg *kafka.Message) ddtrace.Span
 	if err := tracer.Inject(span.Context(), carrier); err != nil {
 		log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier, %v", err)
 	}
+	setConsumeCheckpoint(r.Config().GroupID, msg)
+	tracer.TrackKafkaHighWatermarkOffset(msg.Topic, int32(msg.Partition), msg.HighWaterMark)
 	return span
 }
 
@@ -94,6 +97,10 @@ func (r *Reader) ReadMessage(ctx context.Context) (kafka.Message, error) {
 		return kafka.Message{}, err
 	}
 	r.prev = r.startSpan(ctx, &msg)
+	if groupID := r.Config().GroupID; groupID != "" {
+		// the message offset is automatically committed when a group is set
+		tracer.TrackKafkaCommitOffset(groupID, msg.Topic, int32(msg.Partition), msg.Offset+1)
+	}
 	return msg, nil
 }
 
@@ -111,6 +118,21 @@ func (r *Reader) FetchMessage(ctx context.Context) (kafka.Message, error) {
 	return msg, nil
 }
 
+// CommitMessages calls the underlying Reader.CommitMessages and tracks the
+// committed offsets.
+func (r *Reader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	err := r.Reader.CommitMessages(ctx, msgs...)
+	if err != nil {
+		return err
+	}
+	groupID := r.Config().GroupID
+	for _, msg := range msgs {
+		// the committed offset is the one of the next message to consume
+		tracer.TrackKafkaCommitOffset(groupID, msg.Topic, int32(msg.Partition), msg.Offset+1)
+	}
+	return nil
+}
+
 // WrapWriter wraps a kafka.Writer so requests are traced.
 func WrapWriter(w *kafka.Writer, opts ...Option) *Writer {
 	writer := &Writer{
@@ -147,9 +169,42 @@ func (w *Writer) startSpan(ctx context.Context, msg *kafka.Message) ddtrace.Span
 	span, _ := tracer.StartSpanFromContext(ctx, "kafka.produce", opts...)
 	err := tracer.Inject(span.Context(), carrier)
 	log.Debug("contrib/segmentio/kafka.go.v0: Failed to inject span context into carrier, %v", err)
+	setProduceCheckpoint(w.Writer.Topic, msg)
 	return span
 }
 
+// setConsumeCheckpoint sets a data streams consume checkpoint on the pathway
+// propagated in the message headers, and re-injects the resulting pathway so
+// that the messages produced from this one continue it.
+func setConsumeCheckpoint(groupID string, msg *kafka.Message) {
+	edges := []string{"direction:in", "topic:" + msg.Topic, "type:kafka"}
+	if groupID != "" {
+		edges = append(edges, "group:"+groupID)
+	}
+	carrier := messageCarrier{msg}
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromBase64Carrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToBase64Carrier(ctx, carrier)
+}
+
+// setProduceCheckpoint sets a data streams produce checkpoint on the pathway
+// found in the message headers, if any, and injects the resulting pathway.
+// The topic of the writer, when set, takes precedence over the message one.
+func setProduceCheckpoint(topic string, msg *kafka.Message) {
+	if topic == "" {
+		topic = msg.Topic
+	}
+	edges := []string{"direction:out", "topic:" + topic, "type:kafka"}
+	carrier := messageCarrier{msg}
+	ctx, ok := tracer.SetDataStreamsCheckpoint(datastreams.ExtractFromBase64Carrier(context.Background(), carrier), edges...)
+	if !ok {
+		return
+	}
+	datastreams.InjectToBase64Carrier(ctx, carrier)
+}
+
 func finishSpan(span ddtrace.Span, partition int, offset int64, err error) {
 	span.SetTag(ext.MessagingKafkaPartition, partition)
 	span.SetTag("offset", offset)
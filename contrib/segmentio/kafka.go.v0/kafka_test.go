@@ -7,15 +7,21 @@ package kafka
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 
 	kafka "github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -165,3 +171,37 @@ func TestFetchMessageFunctional(t *testing.T) {
 	assert.Equal(t, ext.SpanKindConsumer, s1.Tag(ext.SpanKind))
 	assert.Equal(t, "kafka", s1.Tag(ext.MessagingSystem))
 }
+
+func TestDataStreams(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/info" {
+			w.Write([]byte(`{"endpoints":["/v0.4/traces","/v0.1/pipeline_stats"]}`))
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+	tracer.Start(tracer.WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")), tracer.WithLogStartup(false))
+	defer tracer.Stop()
+
+	pathway := func(msg *kafka.Message) datastreams.Pathway {
+		for _, h := range msg.Headers {
+			if h.Key == datastreams.PropagationKeyBase64 {
+				p, err := datastreams.DecodeBase64(string(h.Value))
+				require.NoError(t, err)
+				return p
+			}
+		}
+		t.Fatal("pathway context not found in the message headers")
+		return datastreams.Pathway{}
+	}
+
+	msg := &kafka.Message{Topic: testTopic, Value: []byte("value")}
+	setProduceCheckpoint("", msg)
+	producer := pathway(msg)
+	setConsumeCheckpoint(testGroupID, msg)
+	consumer := pathway(msg)
+	assert.NotEqual(t, producer.GetHash(), consumer.GetHash())
+	assert.True(t, producer.PathwayStart().Equal(consumer.PathwayStart()))
+	// the pathway context is set only once in the headers
+	assert.Len(t, msg.Headers, 1)
+}
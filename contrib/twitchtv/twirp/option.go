@@ -8,6 +8,7 @@ package twirp
 import (
 	"math"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 )
@@ -15,6 +16,12 @@ import (
 type config struct {
 	serviceName   string
 	analyticsRate float64
+	// isClientError reports whether the status code of a response received by
+	// the client should be considered an error.
+	isClientError func(statusCode int) bool
+	// isServerError reports whether a twirp error returned by the server with
+	// the given status code should be considered an error.
+	isServerError func(statusCode int) bool
 }
 
 // Option represents an option that can be passed to Dial.
@@ -29,6 +36,10 @@ func defaults(cfg *config) {
 	if svc := globalconfig.ServiceName(); svc != "" {
 		cfg.serviceName = svc
 	}
+	// 4xx and 5xx status codes are errors on clients, and all the twirp errors
+	// are errors on servers, unless configured otherwise
+	cfg.isClientError = httptrace.ClientStatusCheck(func(statusCode int) bool { return statusCode >= 400 })
+	cfg.isServerError = httptrace.ServerStatusCheck(func(int) bool { return true })
 }
 
 func (cfg *config) serverServiceName() string {
@@ -73,3 +84,13 @@ func WithAnalyticsRate(rate float64) Option {
 		}
 	}
 }
+
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error. By default, 4xx and 5xx responses
+// are errors on clients, and all the twirp errors are errors on servers.
+func WithStatusCheck(fn func(statusCode int) bool) Option {
+	return func(cfg *config) {
+		cfg.isClientError = fn
+		cfg.isServerError = fn
+	}
+}
@@ -89,8 +89,7 @@ func (wc *wrappedClient) Do(req *http.Request) (*http.Response, error) {
 		span.SetTag(ext.Error, err)
 	} else {
 		span.SetTag(ext.HTTPCode, strconv.Itoa(res.StatusCode))
-		// treat 4XX and 5XX as errors for a client
-		if res.StatusCode >= 400 {
+		if wc.cfg.isClientError(res.StatusCode) {
 			span.SetTag(ext.Error, true)
 			span.SetTag(ext.ErrorMsg, fmt.Sprintf("%d: %s", res.StatusCode, http.StatusText(res.StatusCode)))
 		}
@@ -217,10 +216,16 @@ func responseSentHook(cfg *config) func(context.Context) {
 		if !ok {
 			return
 		}
-		if sc, ok := twirp.StatusCode(ctx); ok {
+		sc, ok := twirp.StatusCode(ctx)
+		if ok {
 			span.SetTag(ext.HTTPCode, sc)
 		}
 		err, _ := ctx.Value(twirpErrorKey{}).(twirp.Error)
+		if err != nil && ok {
+			if code, convErr := strconv.Atoi(sc); convErr == nil && !cfg.isServerError(code) {
+				err = nil
+			}
+		}
 		span.Finish(tracer.WithError(err))
 	}
 }
@@ -113,6 +113,24 @@ func TestClient(t *testing.T) {
 		assert.Equal(ext.SpanKindClient, span.Tag(ext.SpanKind))
 	})
 
+	t.Run("status-check", func(t *testing.T) {
+		defer mt.Reset()
+		assert := assert.New(t)
+
+		wc := WrapClient(&mockClient{code: 404}, WithStatusCheck(func(statusCode int) bool {
+			return statusCode >= 500
+		}))
+		req, err := http.NewRequest("POST", url, nil)
+		assert.NoError(err)
+		_, err = wc.Do(req.WithContext(ctx))
+		assert.NoError(err)
+
+		spans := mt.FinishedSpans()
+		assert.Len(spans, 1)
+		assert.Equal("404", spans[0].Tag(ext.HTTPCode))
+		assert.Nil(spans[0].Tag(ext.Error))
+	})
+
 	t.Run("timeout", func(t *testing.T) {
 		defer mt.Reset()
 		assert := assert.New(t)
@@ -208,6 +226,21 @@ func TestServerHooks(t *testing.T) {
 		assert.Equal("twitchtv/twirp", span.Tag(ext.Component))
 	})
 
+	t.Run("status-check", func(t *testing.T) {
+		defer mt.Reset()
+		assert := assert.New(t)
+
+		hooks := NewServerHooks(WithStatusCheck(func(statusCode int) bool {
+			return statusCode >= 500
+		}))
+		mockServer(hooks, assert, twirp.NotFoundError("not found"))
+
+		spans := mt.FinishedSpans()
+		assert.Len(spans, 1)
+		assert.Equal("404", spans[0].Tag(ext.HTTPCode))
+		assert.Nil(spans[0].Tag(ext.Error))
+	})
+
 	t.Run("chained", func(t *testing.T) {
 		defer mt.Reset()
 		assert := assert.New(t)
@@ -7,7 +7,6 @@
 package negroni
 
 import (
-	"fmt"
 	"math"
 	"net/http"
 
@@ -16,6 +15,8 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 )
 
@@ -33,21 +34,18 @@ func (m *DatadogMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, ne
 	span, ctx := httptrace.StartRequestSpan(r, opts...)
 	defer func() {
 		// check if the responseWriter is of type negroni.ResponseWriter
-		var (
-			status int
-			opts   []tracer.FinishOption
-		)
-		responseWriter, ok := w.(negroni.ResponseWriter)
-		if ok {
+		var status int
+		if responseWriter, ok := w.(negroni.ResponseWriter); ok {
 			status = responseWriter.Status()
-			if m.cfg.isStatusError(status) {
-				opts = []tracer.FinishOption{tracer.WithError(fmt.Errorf("%d: %s", status, http.StatusText(status)))}
-			}
 		}
-		httptrace.FinishRequestSpan(span, status, opts...)
+		httptrace.FinishRequestSpan(span, status, m.cfg.isStatusError, w.Header())
 	}()
 
-	next(w, r.WithContext(ctx))
+	var h http.Handler = next
+	if appsec.Enabled() {
+		h = httpsec.WrapHandler(h, span, nil, "")
+	}
+	h.ServeHTTP(w, r.WithContext(ctx))
 }
 
 // Middleware create the negroni middleware that will trace incoming requests
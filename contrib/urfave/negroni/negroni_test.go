@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -360,3 +361,16 @@ func TestServiceName(t *testing.T) {
 		assertServiceName(t, mt, router, "my-service")
 	})
 }
+
+func TestAppSec(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(appsectest.Body))
+	})
+	mux.Handle(appsectest.LoginRoute, appsectest.LoginHandler)
+	router := negroni.New()
+	router.Use(Middleware())
+	router.UseHandler(mux)
+	// The middleware has no access to the routes of the handler
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(router), NoPathParams: true, Login: true})
+}
@@ -10,6 +10,7 @@ import (
 	"math"
 	"net/http"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/httptrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
@@ -36,7 +37,7 @@ func defaults(cfg *config) {
 	} else {
 		cfg.analyticsRate = globalconfig.AnalyticsRate()
 	}
-	cfg.isStatusError = isServerError
+	cfg.isStatusError = httptrace.IsServerError
 	cfg.resourceNamer = defaultResourceNamer
 }
 
@@ -86,10 +87,6 @@ func WithStatusCheck(fn func(statusCode int) bool) Option {
 	}
 }
 
-func isServerError(statusCode int) bool {
-	return statusCode >= 500 && statusCode < 600
-}
-
 // WithResourceNamer specifies a function which will be used to obtain a resource name for a given
 // negroni request, using the request's context.
 func WithResourceNamer(namer func(r *http.Request) string) Option {
@@ -15,6 +15,7 @@ import (
 	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 
 	"github.com/zenazn/goji/web"
@@ -43,19 +44,33 @@ func Middleware(opts ...Option) func(*web.C, http.Handler) http.Handler {
 	return func(c *web.C, h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			resource := r.Method
-			p := web.GetMatch(*c).RawPattern()
+			var route string
+			match := web.GetMatch(*c)
+			p := match.RawPattern()
 			if p != nil {
-				resource += fmt.Sprintf(" %s", p)
+				route = fmt.Sprintf("%s", p)
+				resource += " " + route
 			} else {
 				warnonce.Do(func() {
 					log.Warn("contrib/zenazn/goji.v1/web: routes are unavailable. To enable them add the goji Router middleware before the tracer middleware.")
 				})
 			}
+			var params map[string]string
+			if appsec.Enabled() && match.Pattern != nil {
+				// The URL parameters are only bound to the context once the route is dispatched, so they are
+				// computed from a copy of the context instead.
+				var pc web.C
+				match.Pattern.Run(r, &pc)
+				params = pc.URLParams
+			}
 			httptrace.TraceAndServe(h, w, r, &httptrace.ServeConfig{
-				Service:    cfg.serviceName,
-				Resource:   resource,
-				FinishOpts: cfg.finishOpts,
-				SpanOpts:   cfg.spanOpts,
+				Service:       cfg.serviceName,
+				Resource:      resource,
+				FinishOpts:    cfg.finishOpts,
+				SpanOpts:      cfg.spanOpts,
+				Route:         route,
+				RouteParams:   params,
+				IsStatusError: cfg.isStatusError,
 			})
 		})
 	}
@@ -11,6 +11,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"gopkg.in/DataDog/dd-trace-go.v1/contrib/internal/appsectest"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
@@ -82,6 +83,7 @@ func TestTraceWithRouter(t *testing.T) {
 	assert.Equal(ext.SpanTypeWeb, span.Tag(ext.SpanType))
 	assert.Equal("my-router", span.Tag(ext.ServiceName))
 	assert.Equal("GET /user/:id", span.Tag(ext.ResourceName))
+	assert.Equal("/user/:id", span.Tag(ext.HTTPRoute))
 	assert.Equal("200", span.Tag(ext.HTTPCode))
 	assert.Equal("GET", span.Tag(ext.HTTPMethod))
 	assert.Equal("http://example.com/user/123", span.Tag(ext.HTTPURL))
@@ -227,3 +229,14 @@ func TestNoDebugStack(t *testing.T) {
 	assert.Equal(t, "zenazn/goji.v1/web", spans[0].Tag(ext.Component))
 	assert.Equal(t, ext.SpanKindServer, spans[0].Tag(ext.SpanKind))
 }
+
+func TestAppSec(t *testing.T) {
+	m := web.New()
+	m.Use(m.Router)
+	m.Use(Middleware())
+	m.Get("/hello/:name", func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(appsectest.Body))
+	})
+	m.Post(appsectest.LoginRoute, appsectest.LoginHandler)
+	appsectest.RunAll(t, &appsectest.Config{Serve: appsectest.Handler(m), Login: true})
+}
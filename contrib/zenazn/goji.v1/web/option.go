@@ -19,6 +19,7 @@ type config struct {
 	spanOpts      []ddtrace.StartSpanOption
 	finishOpts    []ddtrace.FinishOption
 	analyticsRate float64
+	isStatusError func(statusCode int) bool
 }
 
 // Option represents an option that can be passed to New.
@@ -78,3 +79,11 @@ func WithAnalyticsRate(rate float64) Option {
 		}
 	}
 }
+
+// WithStatusCheck specifies a function fn which reports whether the passed
+// statusCode should be considered an error.
+func WithStatusCheck(fn func(statusCode int) bool) Option {
+	return func(cfg *config) {
+		cfg.isStatusError = fn
+	}
+}
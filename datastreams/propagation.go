@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package datastreams provides functions to propagate the data streams
+// pathway context across services, so that end-to-end latencies of the
+// messages flowing through a pipeline can be computed.
+//
+// Checkpoints are set on the pathway using tracer.SetDataStreamsCheckpoint,
+// and data streams monitoring is enabled with the DD_DATA_STREAMS_ENABLED
+// environment variable.
+package datastreams // import "gopkg.in/DataDog/dd-trace-go.v1/datastreams"
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+)
+
+// TextMapWriter allows setting key/value pairs of strings on the underlying
+// data structure. Carriers such as message headers and attributes must
+// implement this interface to be used with InjectToBase64Carrier.
+type TextMapWriter interface {
+	// Set sets the given key/value pair.
+	Set(key, val string)
+}
+
+// TextMapReader allows iterating over sets of key/value pairs. Carriers such
+// as message headers and attributes must implement this interface to be used
+// with ExtractFromBase64Carrier.
+type TextMapReader interface {
+	// ForeachKey iterates over all keys that exist in the underlying
+	// carrier. It takes a callback function which will be called
+	// using all key/value pairs as arguments. ForeachKey will return
+	// the first error returned by the handler.
+	ForeachKey(handler func(key, val string) error) error
+}
+
+// ExtractFromBase64Carrier extracts the pathway context from the carrier and
+// returns a copy of ctx containing it. ctx is returned unchanged when the
+// carrier doesn't contain a valid pathway context.
+func ExtractFromBase64Carrier(ctx context.Context, carrier TextMapReader) context.Context {
+	var (
+		p     datastreams.Pathway
+		found bool
+	)
+	carrier.ForeachKey(func(key, val string) error {
+		if key != datastreams.PropagationKeyBase64 {
+			return nil
+		}
+		decoded, err := datastreams.DecodeBase64(val)
+		if err != nil {
+			return err
+		}
+		p, found = decoded, true
+		return nil
+	})
+	if !found {
+		return ctx
+	}
+	return datastreams.ContextWithPathway(ctx, p)
+}
+
+// InjectToBase64Carrier injects the pathway context found in ctx, if any,
+// into the carrier.
+func InjectToBase64Carrier(ctx context.Context, carrier TextMapWriter) {
+	p, ok := datastreams.PathwayFromContext(ctx)
+	if !ok {
+		return
+	}
+	carrier.Set(datastreams.PropagationKeyBase64, p.EncodeBase64())
+}
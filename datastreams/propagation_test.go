@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
+)
+
+type carrier map[string]string
+
+func (c carrier) Set(key, val string) { c[key] = val }
+
+func (c carrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		if err := handler(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestBase64Propagation(t *testing.T) {
+	p := datastreams.NewProcessor("service", "env", &url.URL{Scheme: "http", Host: "localhost:8126"}, http.DefaultClient)
+	ctx := p.SetCheckpoint(context.Background(), "direction:out", "topic:orders", "type:kafka")
+	pathway, _ := datastreams.PathwayFromContext(ctx)
+
+	c := carrier{}
+	InjectToBase64Carrier(ctx, c)
+	assert.Contains(t, c, datastreams.PropagationKeyBase64)
+
+	extracted, ok := datastreams.PathwayFromContext(ExtractFromBase64Carrier(context.Background(), c))
+	assert.True(t, ok)
+	assert.Equal(t, pathway.GetHash(), extracted.GetHash())
+
+	t.Run("no-pathway", func(t *testing.T) {
+		c := carrier{}
+		InjectToBase64Carrier(context.Background(), c)
+		assert.Empty(t, c)
+		_, ok := datastreams.PathwayFromContext(ExtractFromBase64Carrier(context.Background(), c))
+		assert.False(t, ok)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		c := carrier{datastreams.PropagationKeyBase64: "invalid"}
+		_, ok := datastreams.PathwayFromContext(ExtractFromBase64Carrier(context.Background(), c))
+		assert.False(t, ok)
+	})
+}
@@ -130,6 +130,10 @@ type StartSpanConfig struct {
 
 	// Context is the parent context where the span should be stored.
 	Context context.Context
+
+	// ProfilerLabels holds a set of key/value pairs that should be applied as
+	// pprof labels for the duration of the new span.
+	ProfilerLabels map[string]string
 }
 
 // Logger implementations are able to log given messages that the tracer or profiler might output.
@@ -47,6 +47,11 @@ const (
 	// See https://docs.datadoghq.com/tracing/trace_collection/tracing_naming_convention/#http-requests
 	HTTPRequestHeaders = "http.request.headers"
 
+	// HTTPResponseHeaders sets the HTTP response headers partial tag
+	// This tag is meant to be composed, i.e http.response.headers.headerX, http.response.headers.headerY, etc...
+	// See https://docs.datadoghq.com/tracing/trace_collection/tracing_naming_convention/#http-requests
+	HTTPResponseHeaders = "http.response.headers"
+
 	// SpanName is a pseudo-key for setting a span's operation name by means of
 	// a tag. It is mostly here to facilitate vendor-agnostic frameworks like Opentracing
 	// and OpenCensus.
@@ -115,4 +120,7 @@ const (
 const (
 	// MessagingKafkaPartition defines the Kafka partition the trace is associated with.
 	MessagingKafkaPartition = "messaging.kafka.partition"
+
+	// MessagingKafkaConsumerGroup defines the consumer group of the Kafka consumer the trace is associated with.
+	MessagingKafkaConsumerGroup = "messaging.kafka.consumer.group"
 )
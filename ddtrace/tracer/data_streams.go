@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package tracer
+
+import (
+	"context"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+// SetDataStreamsCheckpoint sets a produce or consume checkpoint on the data
+// streams pathway found in ctx, or on a new pathway if ctx doesn't contain
+// any. The edge tags identify the checkpoint, for example "direction:out",
+// "topic:orders" and "type:kafka". It returns a copy of ctx containing the
+// resulting pathway, which can be propagated with the datastreams package.
+// ok is false when data streams monitoring is disabled, in which case ctx is
+// returned unchanged.
+func SetDataStreamsCheckpoint(ctx context.Context, edgeTags ...string) (outCtx context.Context, ok bool) {
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok && t.dataStreams != nil {
+		return t.dataStreams.SetCheckpoint(ctx, edgeTags...), true
+	}
+	return ctx, false
+}
@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package tracer
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+// kafkaPartition identifies a partition of a kafka topic.
+type kafkaPartition struct {
+	topic     string
+	partition int32
+}
+
+// kafkaConsumerPartition identifies a partition of a kafka topic consumed by
+// a consumer group.
+type kafkaConsumerPartition struct {
+	group string
+	kafkaPartition
+}
+
+// kafkaOffsetsTTL is the duration after which the offsets of a partition
+// that are no longer updated stop being reported, such as when a topic got
+// deleted or a partition got reassigned to another consumer.
+const kafkaOffsetsTTL = 10 * time.Minute
+
+// kafkaOffset is an offset along with the last time it was tracked.
+type kafkaOffset struct {
+	offset   int64
+	lastSeen time.Time
+}
+
+// kafkaOffsets holds the latest offsets reported by the kafka integrations.
+// They are periodically reported as metrics, along with the resulting lag of
+// the consumer groups.
+type kafkaOffsets struct {
+	mu            sync.Mutex
+	produced      map[kafkaPartition]kafkaOffset
+	highWatermark map[kafkaPartition]kafkaOffset
+	committed     map[kafkaConsumerPartition]kafkaOffset
+}
+
+func newKafkaOffsets() *kafkaOffsets {
+	return &kafkaOffsets{
+		produced:      make(map[kafkaPartition]kafkaOffset),
+		highWatermark: make(map[kafkaPartition]kafkaOffset),
+		committed:     make(map[kafkaConsumerPartition]kafkaOffset),
+	}
+}
+
+func (k *kafkaOffsets) trackProduce(topic string, partition int32, offset int64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key := kafkaPartition{topic: topic, partition: partition}
+	// the produce calls may complete out of order
+	if prev, ok := k.produced[key]; ok && offset < prev.offset {
+		offset = prev.offset
+	}
+	k.produced[key] = kafkaOffset{offset: offset, lastSeen: nowTime()}
+}
+
+func (k *kafkaOffsets) trackHighWatermark(topic string, partition int32, offset int64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.highWatermark[kafkaPartition{topic: topic, partition: partition}] = kafkaOffset{offset: offset, lastSeen: nowTime()}
+}
+
+func (k *kafkaOffsets) trackCommit(group, topic string, partition int32, offset int64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key := kafkaConsumerPartition{group: group, kafkaPartition: kafkaPartition{topic: topic, partition: partition}}
+	k.committed[key] = kafkaOffset{offset: offset, lastSeen: nowTime()}
+}
+
+// report reports the latest known offsets and the lag of the consumer groups
+// using the given statsd client. The lag of a consumer group is the difference
+// between the high watermark of a partition and the offset committed by the group.
+// The offsets that weren't tracked for longer than kafkaOffsetsTTL are evicted
+// instead of being reported.
+func (k *kafkaOffsets) report(statsd statsdClient) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	expired := nowTime().Add(-kafkaOffsetsTTL)
+	for key, o := range k.produced {
+		if o.lastSeen.Before(expired) {
+			delete(k.produced, key)
+			continue
+		}
+		statsd.Gauge("datadog.tracer.kafka.produce_offset", float64(o.offset), key.tags(), 1)
+	}
+	for key, o := range k.highWatermark {
+		if o.lastSeen.Before(expired) {
+			delete(k.highWatermark, key)
+			continue
+		}
+		statsd.Gauge("datadog.tracer.kafka.high_watermark_offset", float64(o.offset), key.tags(), 1)
+	}
+	for key, o := range k.committed {
+		if o.lastSeen.Before(expired) {
+			delete(k.committed, key)
+			continue
+		}
+		tags := append(key.tags(), "consumer_group:"+key.group)
+		statsd.Gauge("datadog.tracer.kafka.commit_offset", float64(o.offset), tags, 1)
+		hwm, ok := k.highWatermark[key.kafkaPartition]
+		if !ok {
+			continue
+		}
+		lag := hwm.offset - o.offset
+		if lag < 0 {
+			// the high watermark is older than the commit
+			lag = 0
+		}
+		statsd.Gauge("datadog.tracer.kafka.consumer_lag", float64(lag), tags, 1)
+	}
+}
+
+func (p kafkaPartition) tags() []string {
+	return []string{"topic:" + p.topic, "partition:" + strconv.Itoa(int(p.partition))}
+}
+
+// TrackKafkaProduceOffset records the offset of a message successfully
+// produced to the given topic partition. The latest produced offsets are
+// periodically reported as metrics using the tracer's statsd client.
+func TrackKafkaProduceOffset(topic string, partition int32, offset int64) {
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
+		t.kafkaOffsets.trackProduce(topic, partition, offset)
+	}
+}
+
+// TrackKafkaHighWatermarkOffset records the high watermark offset of the
+// given topic partition, as seen by a consumer. The latest high watermark
+// offsets are periodically reported as metrics using the tracer's statsd client.
+func TrackKafkaHighWatermarkOffset(topic string, partition int32, offset int64) {
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
+		t.kafkaOffsets.trackHighWatermark(topic, partition, offset)
+	}
+}
+
+// TrackKafkaCommitOffset records the offset committed by the consumer group
+// on the given topic partition, which is the offset of the next message the
+// group will consume. The latest committed offsets are periodically reported
+// as metrics using the tracer's statsd client, along with the consumer lag
+// when the high watermark of the partition is known.
+func TrackKafkaCommitOffset(group, topic string, partition int32, offset int64) {
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
+		t.kafkaOffsets.trackCommit(group, topic, partition, offset)
+	}
+}
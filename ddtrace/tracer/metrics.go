@@ -103,7 +103,10 @@ func (t *tracer) reportHealthMetrics(interval time.Duration) {
 			t.statsd.Count("datadog.tracer.spans_started", int64(atomic.SwapUint32(&t.spansStarted, 0)), nil, 1)
 			t.statsd.Count("datadog.tracer.spans_finished", int64(atomic.SwapUint32(&t.spansFinished, 0)), nil, 1)
 			t.statsd.Count("datadog.tracer.traces_dropped", int64(atomic.SwapUint32(&t.tracesDropped, 0)), []string{"reason:trace_too_large"}, 1)
+			t.kafkaOffsets.report(t.statsd)
 		case <-t.stop:
+			// report the latest offsets before the statsd client is closed
+			t.kafkaOffsets.report(t.statsd)
 			return
 		}
 	}
@@ -6,8 +6,10 @@
 package tracer
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -292,6 +294,97 @@ func TestReportHealthMetrics(t *testing.T) {
 	assert.Equal(int64(0), counts["datadog.tracer.traces_dropped"])
 }
 
+func TestReportKafkaOffsets(t *testing.T) {
+	var tg testStatsdClient
+	_, _, _, stop := startTestTracer(t, withStatsdClient(&tg))
+
+	TrackKafkaProduceOffset("orders", 0, 10)
+	TrackKafkaProduceOffset("orders", 0, 8) // completed out of order
+	TrackKafkaHighWatermarkOffset("orders", 0, 11)
+	TrackKafkaCommitOffset("billing", "orders", 0, 7)
+	TrackKafkaCommitOffset("shipping", "orders", 1, 3) // unknown high watermark
+	// the latest offsets are reported when the tracer stops
+	stop()
+
+	gauges := make(map[string][]testStatsdCall)
+	for _, c := range tg.GaugeCalls() {
+		gauges[c.name] = append(gauges[c.name], c)
+	}
+	assert := assert.New(t)
+	if assert.Len(gauges["datadog.tracer.kafka.produce_offset"], 1) {
+		c := gauges["datadog.tracer.kafka.produce_offset"][0]
+		assert.Equal(float64(10), c.floatVal)
+		assert.Equal([]string{"topic:orders", "partition:0"}, c.tags)
+	}
+	if assert.Len(gauges["datadog.tracer.kafka.high_watermark_offset"], 1) {
+		assert.Equal(float64(11), gauges["datadog.tracer.kafka.high_watermark_offset"][0].floatVal)
+	}
+	assert.Len(gauges["datadog.tracer.kafka.commit_offset"], 2)
+	if assert.Len(gauges["datadog.tracer.kafka.consumer_lag"], 1) {
+		c := gauges["datadog.tracer.kafka.consumer_lag"][0]
+		assert.Equal(float64(4), c.floatVal)
+		assert.Equal([]string{"topic:orders", "partition:0", "consumer_group:billing"}, c.tags)
+	}
+}
+
+func TestReportKafkaOffsetsEviction(t *testing.T) {
+	current := time.Now()
+	nowTime = func() time.Time { return current }
+	defer func() { nowTime = func() time.Time { return time.Now() } }()
+
+	k := newKafkaOffsets()
+	k.trackProduce("orders", 0, 10)
+	k.trackHighWatermark("orders", 0, 11)
+	k.trackCommit("billing", "orders", 0, 7)
+	current = current.Add(kafkaOffsetsTTL / 2)
+	k.trackCommit("billing", "orders", 1, 3)
+
+	// the offsets of orders/0 are no longer updated
+	current = current.Add(kafkaOffsetsTTL/2 + time.Second)
+	var tg testStatsdClient
+	k.report(&tg)
+	calls := tg.GaugeCalls()
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "datadog.tracer.kafka.commit_offset", calls[0].name)
+		assert.Equal(t, []string{"topic:orders", "partition:1", "consumer_group:billing"}, calls[0].tags)
+	}
+	assert.Empty(t, k.produced)
+	assert.Empty(t, k.highWatermark)
+	assert.Len(t, k.committed, 1)
+}
+
+func TestReportDBStats(t *testing.T) {
+	var tg testStatsdClient
+	_, _, _, stop := startTestTracer(t, withStatsdClient(&tg))
+	defer stop()
+
+	tags := []string{"service:mysql.db", "db.type:mysql"}
+	ReportDBStats(sql.DBStats{
+		MaxOpenConnections: 10,
+		OpenConnections:    4,
+		InUse:              3,
+		Idle:               1,
+		WaitCount:          2,
+		WaitDuration:       5 * time.Millisecond,
+	}, tags)
+
+	gauges := make(map[string]testStatsdCall)
+	for _, c := range tg.GaugeCalls() {
+		if strings.HasPrefix(c.name, "datadog.tracer.sql.") {
+			gauges[c.name] = c
+		}
+	}
+	assert := assert.New(t)
+	assert.Len(gauges, 9)
+	assert.Equal(float64(10), gauges["datadog.tracer.sql.max_open_connections"].floatVal)
+	assert.Equal(float64(4), gauges["datadog.tracer.sql.open_connections"].floatVal)
+	assert.Equal(float64(3), gauges["datadog.tracer.sql.in_use"].floatVal)
+	assert.Equal(float64(1), gauges["datadog.tracer.sql.idle"].floatVal)
+	assert.Equal(float64(2), gauges["datadog.tracer.sql.wait_count"].floatVal)
+	assert.Equal(float64(5*time.Millisecond), gauges["datadog.tracer.sql.wait_duration"].floatVal)
+	assert.Equal(tags, gauges["datadog.tracer.sql.idle"].tags)
+}
+
 func TestTracerMetrics(t *testing.T) {
 	assert := assert.New(t)
 	var tg testStatsdClient
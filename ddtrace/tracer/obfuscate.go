@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package tracer
+
+import (
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/obfuscate"
+)
+
+// mongoObfuscators holds the obfuscators of the MongoDB queries. Each one is
+// used by a single goroutine at a time, as their JSON obfuscators are not safe
+// for concurrent use.
+var mongoObfuscators = sync.Pool{
+	New: func() interface{} {
+		return obfuscate.NewObfuscator(obfuscate.Config{
+			Mongo: obfuscate.JSONConfig{Enabled: true},
+		})
+	},
+}
+
+// ObfuscateMongoDBQuery returns the given MongoDB query, in extended JSON,
+// with all of its literal values replaced by "?".
+func ObfuscateMongoDBQuery(query string) string {
+	o := mongoObfuscators.Get().(*obfuscate.Obfuscator)
+	defer mongoObfuscators.Put(o)
+	return o.ObfuscateMongoDBString(query)
+}
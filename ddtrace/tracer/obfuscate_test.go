@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package tracer
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateMongoDBQuery(t *testing.T) {
+	query := `{"insert":"users","documents":[{"name":"John","age":{"$numberInt":"42"}}],"$db":"test"}`
+	want := `{"insert":"?","documents":[{"name":"?","age":{"$numberInt":"?"}}],"$db":"?"}`
+
+	t.Run("sequential", func(t *testing.T) {
+		assert.Equal(t, want, ObfuscateMongoDBQuery(query))
+		assert.Equal(t, want, ObfuscateMongoDBQuery(query))
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, want, ObfuscateMongoDBQuery(query))
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -150,6 +151,9 @@ type config struct {
 
 	// disableHostnameDetection specifies whether the tracer should disable hostname detection.
 	disableHostnameDetection bool
+
+	// dataStreamsMonitoringEnabled specifies whether the tracer should enable monitoring of data streams
+	dataStreamsMonitoringEnabled bool
 }
 
 // HasFeature reports whether feature f is enabled.
@@ -201,6 +205,11 @@ func newConfig(opts ...StartOption) *config {
 	if v := os.Getenv("DD_SERVICE_MAPPING"); v != "" {
 		internal.ForEachStringTag(v, func(key, val string) { WithServiceMapping(key, val)(c) })
 	}
+	if v := os.Getenv("DD_TRACE_HEADER_TAGS"); v != "" {
+		var tags []globalconfig.HeaderTag
+		internal.ForEachStringTag(v, func(header, tag string) { tags = append(tags, newHeaderTag(header, tag)) })
+		globalconfig.SetHeaderTags(tags)
+	}
 	if v := os.Getenv("DD_TAGS"); v != "" {
 		tags := internal.ParseTagString(v)
 		internal.CleanGitMetadataTags(tags)
@@ -219,6 +228,7 @@ func newConfig(opts ...StartOption) *config {
 	c.enabled = internal.BoolEnv("DD_TRACE_ENABLED", true)
 	c.profilerEndpoints = internal.BoolEnv(traceprof.EndpointEnvVar, true)
 	c.profilerHotspots = internal.BoolEnv(traceprof.CodeHotspotsEnvVar, true)
+	c.dataStreamsMonitoringEnabled = internal.BoolEnv("DD_DATA_STREAMS_ENABLED", false)
 
 	for _, fn := range opts {
 		fn(c)
@@ -387,6 +397,10 @@ type agentFeatures struct {
 	// the /v0.6/stats endpoint.
 	Stats bool
 
+	// DataStreams reports whether the agent can receive data streams stats on
+	// the /v0.1/pipeline_stats endpoint.
+	DataStreams bool
+
 	// StatsdPort specifies the Dogstatsd port as provided by the agent.
 	// If it's the default, it will be 0, which means 8125.
 	StatsdPort int
@@ -436,6 +450,8 @@ func (c *config) loadAgentFeatures() {
 		switch endpoint {
 		case "/v0.6/stats":
 			c.agent.Stats = true
+		case "/v0.1/pipeline_stats":
+			c.agent.DataStreams = true
 		}
 	}
 	c.agent.featureFlags = make(map[string]struct{}, len(info.FeatureFlags))
@@ -609,6 +625,55 @@ func WithServiceMapping(from, to string) StartOption {
 	}
 }
 
+// WithHeaderTags enables the reporting of the given HTTP headers as span tags by
+// the HTTP integrations, on both requests and responses. Each entry is either a
+// header name, such as "X-Request-Id", or a header name followed by the name of
+// the tag to use, such as "X-Request-Id:request.id". Header names are case
+// insensitive. When no tag name is given, the value of the header is reported
+// using the http.request.headers.<header> and http.response.headers.<header>
+// tags, where <header> is the normalized header name. This option replaces any
+// header tags set using the DD_TRACE_HEADER_TAGS environment variable.
+// Warning: using this feature can risk exposing sensitive data such as
+// authorisation tokens to Datadog.
+func WithHeaderTags(headerAsTags []string) StartOption {
+	return func(_ *config) {
+		tags := make([]globalconfig.HeaderTag, 0, len(headerAsTags))
+		for _, h := range headerAsTags {
+			header, tag, _ := strings.Cut(h, ":")
+			if header = strings.TrimSpace(header); header == "" {
+				continue
+			}
+			tags = append(tags, newHeaderTag(header, strings.TrimSpace(tag)))
+		}
+		globalconfig.SetHeaderTags(tags)
+	}
+}
+
+// newHeaderTag returns the header tag reporting the given HTTP header using the
+// given tag name, or using the default request and response tag names when tag
+// is empty.
+func newHeaderTag(header, tag string) globalconfig.HeaderTag {
+	if tag != "" {
+		return globalconfig.HeaderTag{
+			Header:      textproto.CanonicalMIMEHeaderKey(header),
+			RequestTag:  tag,
+			ResponseTag: tag,
+		}
+	}
+	// normalize the header name to a valid tag name
+	name := []byte(strings.ToLower(header))
+	for i, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			name[i] = '_'
+		}
+	}
+	return globalconfig.HeaderTag{
+		Header:      textproto.CanonicalMIMEHeaderKey(header),
+		RequestTag:  ext.HTTPRequestHeaders + "." + string(name),
+		ResponseTag: ext.HTTPResponseHeaders + "." + string(name),
+	}
+}
+
 // WithGlobalTag sets a key/value pair which will be set as a tag on all spans
 // created by tracer. This option may be used multiple times.
 func WithGlobalTag(k string, v interface{}) StartOption {
@@ -793,6 +858,19 @@ func Tag(k string, v interface{}) StartSpanOption {
 	}
 }
 
+// ProfilerLabel sets the given key/value pair as a pprof label for the
+// duration of the started span and its children, so that CPU and goroutine
+// profiles can be broken down by it. Only keys allowed by the profiler, see
+// profiler.WithCustomProfilerLabelKeys, are applied.
+func ProfilerLabel(k, v string) StartSpanOption {
+	return func(cfg *ddtrace.StartSpanConfig) {
+		if cfg.ProfilerLabels == nil {
+			cfg.ProfilerLabels = map[string]string{}
+		}
+		cfg.ProfilerLabels[k] = v
+	}
+}
+
 // ServiceName sets the given service name on the started span. For example "http.server".
 func ServiceName(name string) StartSpanOption {
 	return Tag(ext.ServiceName, name)
@@ -227,6 +227,17 @@ func TestLoadAgentFeatures(t *testing.T) {
 		assert.True(t, cfg.agent.Stats)
 		assert.Equal(t, 8999, cfg.agent.StatsdPort)
 	})
+
+	t.Run("data-streams", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{"endpoints":["/v0.6/stats","/v0.1/pipeline_stats"]}`))
+		}))
+		defer srv.Close()
+		t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+		cfg := newConfig(WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")))
+		assert.True(t, cfg.agent.DataStreams)
+		assert.True(t, cfg.dataStreamsMonitoringEnabled)
+	})
 }
 
 func TestTracerOptionsDefaults(t *testing.T) {
@@ -241,6 +252,29 @@ func TestTracerOptionsDefaults(t *testing.T) {
 		assert.Equal(defaultClient, c.httpClient)
 	})
 
+	t.Run("header-tags", func(t *testing.T) {
+		defer globalconfig.SetHeaderTags(nil)
+
+		t.Run("env", func(t *testing.T) {
+			t.Setenv("DD_TRACE_HEADER_TAGS", "x-request-id:request.id, Content-Type ,x_custom.header")
+			newConfig()
+			assert.Equal(t, []globalconfig.HeaderTag{
+				{Header: "X-Request-Id", RequestTag: "request.id", ResponseTag: "request.id"},
+				{Header: "Content-Type", RequestTag: "http.request.headers.content-type", ResponseTag: "http.response.headers.content-type"},
+				{Header: "X_custom.header", RequestTag: "http.request.headers.x_custom_header", ResponseTag: "http.response.headers.x_custom_header"},
+			}, globalconfig.HeaderTags())
+		})
+
+		t.Run("option", func(t *testing.T) {
+			t.Setenv("DD_TRACE_HEADER_TAGS", "x-request-id:request.id")
+			newConfig(WithHeaderTags([]string{"accept", " user-agent : ua ", ""}))
+			assert.Equal(t, []globalconfig.HeaderTag{
+				{Header: "Accept", RequestTag: "http.request.headers.accept", ResponseTag: "http.response.headers.accept"},
+				{Header: "User-Agent", RequestTag: "ua", ResponseTag: "ua"},
+			}, globalconfig.HeaderTags())
+		})
+	})
+
 	t.Run("http-client", func(t *testing.T) {
 		c := newConfig()
 		assert.Equal(t, defaultClient, c.httpClient)
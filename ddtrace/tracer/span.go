@@ -79,8 +79,10 @@ type span struct {
 	finished     bool         `msg:"-"` // true if the span has been submitted to a tracer.
 	context      *spanContext `msg:"-"` // span propagation context
 
-	pprofCtxActive  context.Context `msg:"-"` // contains pprof.WithLabel labels to tell the profiler more about this span
-	pprofCtxRestore context.Context `msg:"-"` // contains pprof.WithLabel labels of the parent span (if any) that need to be restored when this span finishes
+	pprofCtxActive  context.Context   `msg:"-"` // contains pprof.WithLabel labels to tell the profiler more about this span
+	pprofCtxRestore context.Context   `msg:"-"` // contains pprof.WithLabel labels of the parent span (if any) that need to be restored when this span finishes
+	pprofCtxStart   context.Context   `msg:"-"` // the pprof context the span was started from when no labels were applied at start, see setProfilerLabel
+	profilerLabels  map[string]string `msg:"-"` // custom pprof labels applied to this span, inherited by its children
 
 	taskEnd func() // ends execution tracer (runtime/trace) task, if started
 }
@@ -443,6 +445,103 @@ func (s *span) Finish(opts ...ddtrace.FinishOption) {
 	}
 }
 
+// setProfilerLabel applies the pprof label k=v to the span if k is allowed by
+// the profiler, see SetProfilerLabel. The label is also applied to the active
+// spans started from the span, so that it isn't lost when they finish and
+// restore the labels they were started with.
+func (s *span) setProfilerLabel(k, v string) {
+	labels := traceprof.GlobalCustomLabels().Append(nil, k, v)
+	if len(labels) == 0 {
+		return
+	}
+	active := s.applyProfilerLabel(labels, true)
+	if active == nil {
+		return
+	}
+	for _, d := range s.descendants() {
+		if ctx := d.applyProfilerLabel(labels, false); ctx != nil {
+			// the goroutine runs the innermost active span
+			active = ctx
+		}
+	}
+	pprof.SetGoroutineLabels(active)
+}
+
+// applyProfilerLabel adds the pprof labels to the active context of the span,
+// as well as to the context restored when it finishes unless own is true,
+// which is the case of the span the labels are set on. It returns the active
+// context of the span, or nil if the span is finished or has no pprof labels
+// and isn't the span the labels are set on.
+func (s *span) applyProfilerLabel(labels []string, own bool) context.Context {
+	s.Lock()
+	defer s.Unlock()
+	if s.finished || (!own && s.pprofCtxActive == nil) {
+		return nil
+	}
+	if s.pprofCtxActive == nil {
+		// No labels were applied when the span was started, so the labels
+		// to restore are the ones of the context the span was started from.
+		ctx := s.pprofCtxStart
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		s.pprofCtxRestore = ctx
+		s.pprofCtxActive = ctx
+		s.pprofCtxStart = nil
+	}
+	if s.profilerLabels == nil {
+		s.profilerLabels = make(map[string]string, 1)
+	}
+	s.profilerLabels[labels[0]] = labels[1]
+	s.pprofCtxActive = pprof.WithLabels(s.pprofCtxActive, pprof.Labels(labels...))
+	if !own && s.pprofCtxRestore != nil {
+		s.pprofCtxRestore = pprof.WithLabels(s.pprofCtxRestore, pprof.Labels(labels...))
+	}
+	return s.pprofCtxActive
+}
+
+// descendants returns the spans of the local trace started from s, directly
+// or not, in the order they were started.
+func (s *span) descendants() []*span {
+	if s.context == nil || s.context.trace == nil {
+		return nil
+	}
+	t := s.context.trace
+	t.mu.RLock()
+	spans := append([]*span(nil), t.spans...)
+	t.mu.RUnlock()
+	var (
+		ids         = map[uint64]bool{s.SpanID: true}
+		descendants []*span
+	)
+	for _, sp := range spans {
+		// children are always started after their parent
+		if sp != s && ids[sp.ParentID] {
+			ids[sp.SpanID] = true
+			descendants = append(descendants, sp)
+		}
+	}
+	return descendants
+}
+
+// inheritProfilerLabels returns the custom pprof labels of s merged with
+// labels. The values in labels take precedence.
+func (s *span) inheritProfilerLabels(labels map[string]string) map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.profilerLabels) == 0 {
+		return labels
+	}
+	merged := make(map[string]string, len(s.profilerLabels)+len(labels))
+	for k, v := range s.profilerLabels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return merged
+}
+
 // SetOperationName sets or changes the operation name.
 func (s *span) SetOperationName(operationName string) {
 	s.Lock()
@@ -474,6 +573,9 @@ func (s *span) finish(finishTime int64) {
 		s.Duration = 0
 	}
 	s.finished = true
+	// The start context is only needed by setProfilerLabel until the span
+	// finishes, don't keep it alive with the span.
+	s.pprofCtxStart = nil
 
 	keep := true
 	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
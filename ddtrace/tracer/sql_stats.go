@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package tracer
+
+import (
+	"database/sql"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
+)
+
+// reportDBStats reports the statistics of a database connection pool using
+// the given statsd client.
+func reportDBStats(statsd statsdClient, stats sql.DBStats, tags []string) {
+	statsd.Gauge("datadog.tracer.sql.max_open_connections", float64(stats.MaxOpenConnections), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.open_connections", float64(stats.OpenConnections), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.in_use", float64(stats.InUse), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.idle", float64(stats.Idle), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.wait_count", float64(stats.WaitCount), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.wait_duration", float64(stats.WaitDuration), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.max_idle_closed", float64(stats.MaxIdleClosed), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.max_idle_time_closed", float64(stats.MaxIdleTimeClosed), tags, 1)
+	statsd.Gauge("datadog.tracer.sql.max_lifetime_closed", float64(stats.MaxLifetimeClosed), tags, 1)
+}
+
+// ReportDBStats reports the given statistics of a database connection pool as
+// metrics, using the tracer's statsd client. The metrics are tagged with the
+// given tags, which should identify the database. The counters of the
+// statistics, such as the wait count and duration, are reported as they are,
+// i.e. as the totals since the database was opened. The wait duration is
+// reported in nanoseconds.
+func ReportDBStats(stats sql.DBStats, tags []string) {
+	if t, ok := internal.GetGlobalTracer().(*tracer); ok {
+		reportDBStats(t.statsd, stats, tags)
+	}
+}
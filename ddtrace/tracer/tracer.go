@@ -19,6 +19,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/hostname"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
@@ -92,6 +93,13 @@ type tracer struct {
 
 	// statsd is used for tracking metrics associated with the runtime and the tracer.
 	statsd statsdClient
+
+	// kafkaOffsets holds the latest offsets reported by the kafka integrations.
+	kafkaOffsets *kafkaOffsets
+
+	// dataStreams processes data streams monitoring information.
+	// dataStreams may be nil if data streams monitoring is disabled.
+	dataStreams *datastreams.Processor
 }
 
 const (
@@ -198,6 +206,26 @@ func SetUser(s Span, id string, opts ...UserMonitoringOption) {
 	sp.SetUser(id, opts...)
 }
 
+// SetProfilerLabel applies the given key/value pair as a pprof label to the
+// provided span for the rest of its duration. Child spans started afterwards
+// inherit the label, so setting it on the local root span applies it to the
+// rest of the request. Only keys allowed by the profiler, see
+// profiler.WithCustomProfilerLabelKeys, are applied. Like all pprof labels set
+// by the tracer, the label is applied to the calling goroutine, so this should
+// be called from the goroutine running the span.
+func SetProfilerLabel(s Span, k, v string) {
+	if s == nil {
+		return
+	}
+	sp, ok := s.(interface {
+		setProfilerLabel(k, v string)
+	})
+	if !ok {
+		return
+	}
+	sp.setProfilerLabel(k, v)
+}
+
 // payloadQueueSize is the buffer size of the trace channel.
 const payloadQueueSize = 1000
 
@@ -243,7 +271,15 @@ func newUnstartedTracer(opts ...StartOption) *tracer {
 				Cache:            c.agent.HasFlag("sql_cache"),
 			},
 		}),
-		statsd: statsd,
+		statsd:       statsd,
+		kafkaOffsets: newKafkaOffsets(),
+	}
+	if c.dataStreamsMonitoringEnabled {
+		if c.agent.DataStreams {
+			t.dataStreams = datastreams.NewProcessor(c.serviceName, c.env, c.agentURL, c.httpClient)
+		} else {
+			log.Warn("Data streams monitoring is enabled, but the agent does not support it (missing /v0.1/pipeline_stats endpoint). Upgrade the agent to enable it.")
+		}
 	}
 	return t
 }
@@ -277,6 +313,9 @@ func newTracer(opts ...StartOption) *tracer {
 		t.reportHealthMetrics(statsInterval)
 	}()
 	t.stats.Start()
+	if t.dataStreams != nil {
+		t.dataStreams.Start()
+	}
 	return t
 }
 
@@ -322,6 +361,9 @@ func (t *tracer) worker(tick <-chan time.Time) {
 			t.traceWriter.flush()
 			t.statsd.Flush()
 			t.stats.flushAndSend(time.Now(), withCurrentBucket)
+			if t.dataStreams != nil {
+				t.dataStreams.Flush()
+			}
 			// TODO(x): In reality, the traceWriter.flush() call is not synchronous
 			// when using the agent traceWriter. However, this functionnality is used
 			// in Lambda so for that purpose this mechanism should suffice.
@@ -505,8 +547,20 @@ func (t *tracer) StartSpan(operationName string, options ...ddtrace.StartSpanOpt
 		t.sample(span)
 	}
 	pprofContext, span.taskEnd = startExecutionTracerTask(pprofContext, span)
-	if t.config.profilerHotspots || t.config.profilerEndpoints {
-		t.applyPPROFLabels(pprofContext, span)
+	profilerLabels := opts.ProfilerLabels
+	if context != nil && context.span != nil {
+		// Custom profiler labels are inherited from the local parent, even
+		// if they were set after the parent's pprof context was derived.
+		profilerLabels = context.span.inheritProfilerLabels(profilerLabels)
+	}
+	if t.config.profilerHotspots || t.config.profilerEndpoints || len(profilerLabels) > 0 {
+		t.applyPPROFLabels(pprofContext, span, profilerLabels)
+	}
+	if span.pprofCtxActive == nil && traceprof.GlobalCustomLabels().Enabled() {
+		// Remember the pprof labels found in the start context, so they can
+		// be restored if profiler labels are set on the span later on. With
+		// code hotspots enabled, the labels are always applied at start.
+		span.pprofCtxStart = pprofContext
 	}
 	if t.config.serviceMappings != nil {
 		if newSvc, ok := t.config.serviceMappings[span.Service]; ok {
@@ -529,10 +583,11 @@ func generateSpanID(startTime int64) uint64 {
 }
 
 // applyPPROFLabels applies pprof labels for the profiler's code hotspots and
-// endpoint filtering feature to span. When span finishes, any pprof labels
-// found in ctx are restored. Additionally this func informs the profiler how
-// many times each endpoint is called.
-func (t *tracer) applyPPROFLabels(ctx gocontext.Context, span *span) {
+// endpoint filtering feature to span, as well as the custom labels allowed by
+// the profiler. When span finishes, any pprof labels found in ctx are
+// restored. Additionally this func informs the profiler how many times each
+// endpoint is called.
+func (t *tracer) applyPPROFLabels(ctx gocontext.Context, span *span, custom map[string]string) {
 	var labels []string
 	if t.config.profilerHotspots {
 		// allocate the max-length slice to avoid growing it later
@@ -554,6 +609,17 @@ func (t *tracer) applyPPROFLabels(ctx gocontext.Context, span *span) {
 			}
 		}
 	}
+	var customLabels []string
+	for k, v := range custom {
+		customLabels = traceprof.GlobalCustomLabels().Append(customLabels, k, v)
+	}
+	for i := 0; i+1 < len(customLabels); i += 2 {
+		if span.profilerLabels == nil {
+			span.profilerLabels = make(map[string]string, len(customLabels)/2)
+		}
+		span.profilerLabels[customLabels[i]] = customLabels[i+1]
+	}
+	labels = append(labels, customLabels...)
 	if len(labels) > 0 {
 		span.pprofCtxRestore = ctx
 		span.pprofCtxActive = pprof.WithLabels(ctx, pprof.Labels(labels...))
@@ -575,6 +641,9 @@ func (t *tracer) Stop() {
 		t.statsd.Incr("datadog.tracer.stopped", nil, 1)
 	})
 	t.stats.Stop()
+	if t.dataStreams != nil {
+		t.dataStreams.Stop()
+	}
 	t.wg.Wait()
 	t.traceWriter.stop()
 	t.statsd.Close()
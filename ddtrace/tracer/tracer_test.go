@@ -15,6 +15,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"runtime"
+	"runtime/pprof"
 	rt "runtime/trace"
 	"strconv"
 	"strings"
@@ -29,9 +30,11 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/internal"
 	maininternal "gopkg.in/DataDog/dd-trace-go.v1/internal"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/datastreams"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/globalconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/telemetry"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
 )
 
 func (t *tracer) newEnvSpan(service, env string) *span {
@@ -659,6 +662,99 @@ func TestTracerStartSpanOptions(t *testing.T) {
 	assert.Equal(1.0, span.Metrics[keyTopLevel])
 }
 
+func TestTracerProfilerLabels(t *testing.T) {
+	traceprof.GlobalCustomLabels().SetKeys(0, "tenant", "tier")
+	defer traceprof.GlobalCustomLabels().SetKeys(0)
+	_, _, _, stop := startTestTracer(t)
+	defer stop()
+	assert := assert.New(t)
+
+	label := func(s *span, key string) string {
+		v, _ := pprof.Label(s.pprofCtxActive, key)
+		return v
+	}
+	root, ctx := StartSpanFromContext(context.Background(), "web.request",
+		ProfilerLabel("tenant", "acme"),
+		ProfilerLabel("secret", "value"),
+	)
+	assert.Equal("acme", label(root.(*span), "tenant"))
+	assert.Empty(label(root.(*span), "secret"))
+
+	SetProfilerLabel(root, "tier", "gold")
+	SetProfilerLabel(root, "secret", "value")
+	assert.Equal("gold", label(root.(*span), "tier"))
+	assert.Empty(label(root.(*span), "secret"))
+
+	// the child is started from a ctx derived before the tier label was set
+	child, _ := StartSpanFromContext(ctx, "db.query", ProfilerLabel("tenant", "other"))
+	assert.Equal("other", label(child.(*span), "tenant"))
+	assert.Equal("gold", label(child.(*span), "tier"))
+	child.Finish()
+	root.Finish()
+}
+
+func TestTracerProfilerLabelsActiveChild(t *testing.T) {
+	traceprof.GlobalCustomLabels().SetKeys(0, "tier")
+	defer traceprof.GlobalCustomLabels().SetKeys(0)
+	_, _, _, stop := startTestTracer(t)
+	defer stop()
+	assert := assert.New(t)
+
+	label := func(ctx context.Context, key string) string {
+		v, _ := pprof.Label(ctx, key)
+		return v
+	}
+	root, ctx := StartSpanFromContext(context.Background(), "web.request")
+	child, _ := StartSpanFromContext(ctx, "db.query")
+	sp := child.(*span)
+
+	// the label set on the root while the child is active applies to the
+	// child, on top of its own labels
+	SetProfilerLabel(root, "tier", "gold")
+	assert.Equal("gold", label(sp.pprofCtxActive, "tier"))
+	assert.Equal(strconv.FormatUint(sp.SpanID, 10), label(sp.pprofCtxActive, traceprof.SpanID))
+
+	// the label is kept once the child finishes
+	child.Finish()
+	assert.Equal("gold", label(sp.pprofCtxRestore, "tier"))
+	assert.Equal(strconv.FormatUint(root.(*span).SpanID, 10), label(sp.pprofCtxRestore, traceprof.SpanID))
+	root.Finish()
+}
+
+func TestTracerProfilerLabelsRestore(t *testing.T) {
+	traceprof.GlobalCustomLabels().SetKeys(0, "tier")
+	defer traceprof.GlobalCustomLabels().SetKeys(0)
+	// no labels are applied when the span starts
+	_, _, _, stop := startTestTracer(t, WithProfilerCodeHotspots(false), WithProfilerEndpoints(false))
+	defer stop()
+	assert := assert.New(t)
+
+	pprof.Do(context.Background(), pprof.Labels("outer", "value"), func(ctx context.Context) {
+		s, _ := StartSpanFromContext(ctx, "web.request")
+		SetProfilerLabel(s, "tier", "gold")
+		sp := s.(*span)
+		v, _ := pprof.Label(sp.pprofCtxActive, "outer")
+		assert.Equal("value", v)
+		v, _ = pprof.Label(sp.pprofCtxActive, "tier")
+		assert.Equal("gold", v)
+		s.Finish()
+
+		// the outer label is restored when the span finishes
+		v, _ = pprof.Label(sp.pprofCtxRestore, "outer")
+		assert.Equal("value", v)
+		_, ok := pprof.Label(sp.pprofCtxRestore, "tier")
+		assert.False(ok)
+		// the start context isn't kept once the span is finished
+		assert.Nil(sp.pprofCtxStart)
+
+		// the start context isn't kept when custom labels are disabled
+		traceprof.GlobalCustomLabels().SetKeys(0)
+		s, _ = StartSpanFromContext(ctx, "web.request")
+		assert.Nil(s.(*span).pprofCtxStart)
+		s.Finish()
+	})
+}
+
 func TestTracerStartChildSpan(t *testing.T) {
 	t.Run("own-service", func(t *testing.T) {
 		assert := assert.New(t)
@@ -2274,3 +2370,42 @@ func TestExecutionTraceSpanTagged(t *testing.T) {
 	assert.Equal(t, tracedSpan.Meta["go_execution_traced"], "yes")
 	assert.NotContains(t, untracedSpan.Meta, "go_execution_traced")
 }
+
+func TestSetDataStreamsCheckpoint(t *testing.T) {
+	pipelineStats := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			w.Write([]byte(`{"endpoints":["/v0.4/traces","/v0.1/pipeline_stats"]}`))
+		case "/v0.1/pipeline_stats":
+			pipelineStats <- struct{}{}
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("disabled", func(t *testing.T) {
+		_, _, _, stop := startTestTracer(t, WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")))
+		defer stop()
+		ctx, ok := SetDataStreamsCheckpoint(context.Background(), "direction:out", "topic:orders", "type:kafka")
+		assert.False(t, ok)
+		assert.Equal(t, context.Background(), ctx)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Setenv("DD_DATA_STREAMS_ENABLED", "true")
+		tr, _, _, stop := startTestTracer(t, WithAgentAddr(strings.TrimPrefix(srv.URL, "http://")))
+		defer stop()
+		assert.NotNil(t, tr.dataStreams)
+		ctx, ok := SetDataStreamsCheckpoint(context.Background(), "direction:out", "topic:orders", "type:kafka")
+		assert.True(t, ok)
+		_, ok = datastreams.PathwayFromContext(ctx)
+		assert.True(t, ok)
+
+		tr.Stop()
+		select {
+		case <-pipelineStats:
+		case <-time.After(time.Second * timeMultiplicator):
+			t.Fatal("timed out waiting for the data streams stats payload")
+		}
+	})
+}
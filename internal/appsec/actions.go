@@ -0,0 +1,126 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/grpcsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// Action types of the action definitions
+const (
+	blockRequestActionType    = "block_request"
+	redirectRequestActionType = "redirect_request"
+)
+
+// actionDefinition is the definition of an action the rules refer to by ID in their on_match field, as found in the
+// actions field of the security rules.
+type actionDefinition struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Parameters actionParameters `json:"parameters"`
+}
+
+// actionParameters are the parameters of the block_request and redirect_request actions.
+type actionParameters struct {
+	// StatusCode is the HTTP status code of the blocking or redirection response.
+	StatusCode int `json:"status_code,omitempty"`
+	// Type is the type of the blocking response body: auto, html or json.
+	Type string `json:"type,omitempty"`
+	// Location is the location of the redirection response.
+	Location string `json:"location,omitempty"`
+	// GRPCStatusCode is the status code of the blocked RPCs.
+	GRPCStatusCode *int `json:"grpc_status_code,omitempty"`
+	// GRPCStatusMessage is the status message of the blocked RPCs.
+	GRPCStatusMessage string `json:"grpc_status_message,omitempty"`
+}
+
+// validate returns an error when the action definition can't be used.
+func (a *actionDefinition) validate() error {
+	if a.ID == "" {
+		return fmt.Errorf("action without id")
+	}
+	switch a.Type {
+	case blockRequestActionType:
+		switch a.Parameters.Type {
+		case "", "auto", "html", "json":
+		default:
+			return fmt.Errorf("action %q: unexpected block response type %q", a.ID, a.Parameters.Type)
+		}
+	case redirectRequestActionType:
+		if a.Parameters.Location == "" {
+			return fmt.Errorf("action %q: redirect action without location", a.ID)
+		}
+	}
+	// a zero status code selects the default one of the action
+	if s := a.Parameters.StatusCode; s != 0 && (s < 100 || s > 599) {
+		return fmt.Errorf("action %q: unexpected http status code %d", a.ID, s)
+	}
+	if c := a.Parameters.GRPCStatusCode; c != nil && (*c <= int(codes.OK) || *c > int(codes.Unauthenticated)) {
+		return fmt.Errorf("action %q: unexpected grpc status code %d", a.ID, *c)
+	}
+	return nil
+}
+
+// readActionDefinitions returns the action definitions of the given security rules.
+func readActionDefinitions(rules []byte) ([]actionDefinition, error) {
+	var ruleset struct {
+		Actions []actionDefinition `json:"actions"`
+	}
+	if err := json.Unmarshal(rules, &ruleset); err != nil {
+		return nil, err
+	}
+	for _, a := range ruleset.Actions {
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return ruleset.Actions, nil
+}
+
+// newActionsHandlers returns the HTTP and gRPC actions handlers holding the default actions, along with the given
+// action definitions which can redefine them. Actions of unknown types are ignored.
+func newActionsHandlers(actions []actionDefinition) (*httpsec.ActionsHandler, *grpcsec.ActionsHandler) {
+	httpActions, grpcActions := httpsec.NewActionsHandler(), grpcsec.NewActionsHandler()
+	for _, a := range actions {
+		grpcBlock := &grpcsec.BlockRequestAction{Status: codes.Aborted, Message: a.Parameters.GRPCStatusMessage}
+		if c := a.Parameters.GRPCStatusCode; c != nil {
+			grpcBlock.Status = codes.Code(*c)
+		}
+		switch a.Type {
+		case blockRequestActionType:
+			status := a.Parameters.StatusCode
+			if status == 0 {
+				status = http.StatusForbidden
+			}
+			template := a.Parameters.Type
+			if template == "" {
+				template = "auto"
+			}
+			block := httpsec.NewBlockRequestAction(status, template)
+			httpActions.RegisterAction(a.ID, &block)
+			grpcActions.RegisterAction(a.ID, grpcBlock)
+		case redirectRequestActionType:
+			redirect := httpsec.NewRedirectRequestAction(a.Parameters.StatusCode, a.Parameters.Location)
+			httpActions.RegisterAction(a.ID, &redirect)
+			// RPCs can't be redirected and are blocked instead
+			grpcActions.RegisterAction(a.ID, grpcBlock)
+		default:
+			log.Debug("appsec: ignoring the action %q of unknown type %q", a.ID, a.Type)
+		}
+	}
+	return httpActions, grpcActions
+}
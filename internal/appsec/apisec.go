@@ -0,0 +1,342 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// API security span tags holding the compressed schemas of the request and response data
+const (
+	reqHeadersSchemaTag = "_dd.appsec.s.req.headers"
+	reqQuerySchemaTag   = "_dd.appsec.s.req.query"
+	reqParamsSchemaTag  = "_dd.appsec.s.req.params"
+	reqBodySchemaTag    = "_dd.appsec.s.req.body"
+	resHeadersSchemaTag = "_dd.appsec.s.res.headers"
+	resBodySchemaTag    = "_dd.appsec.s.res.body"
+)
+
+// Schema scalar types
+const (
+	schemaUnknown = 0
+	schemaNull    = 1
+	schemaBool    = 2
+	schemaInt     = 4
+	schemaString  = 8
+	schemaFloat   = 16
+)
+
+// Limits bounding the cost of the schema extraction
+const (
+	schemaMaxDepth       = 18
+	schemaMaxKeys        = 256
+	schemaMaxArrayItems  = 10
+	schemaMaxArrayTypes  = 10
+	apiSecMaxEndpoints   = 4096
+	apiSecMaxStringValue = 4096
+	apiSecMaxPathSegment = 20
+)
+
+// newAPISecEventListener returns the HTTP event listener extracting the schemas of the request and response data of
+// the sampled requests, and adding them to the service entry span.
+func newAPISecEventListener(sampler *apiSecSampler) dyngo.EventListener {
+	return httpsec.OnHandlerOperationStart(func(op *httpsec.Operation, args httpsec.HandlerOperationArgs) {
+		// The bodies are only kept until the request finishes, so that the schemas are only computed for the sampled
+		// requests
+		var reqBody, resBody interface{}
+		// The route and its path parameters are replaced when the integration only matches the route after the
+		// handler operation started
+		op.On(httpsec.OnPathParamsOperationStart(func(_ *httpsec.PathParamsOperation, paramsArgs httpsec.PathParamsOperationArgs) {
+			args.Route, args.PathParams = paramsArgs.Route, paramsArgs.PathParams
+		}))
+		op.On(httpsec.OnSDKBodyOperationStart(func(_ *httpsec.SDKBodyOperation, args httpsec.SDKBodyOperationArgs) {
+			reqBody = args.Body
+		}))
+		op.On(httpsec.OnSDKResponseBodyOperationStart(func(_ *httpsec.SDKResponseBodyOperation, args httpsec.SDKResponseBodyOperationArgs) {
+			resBody = args.Body
+		}))
+		op.On(httpsec.OnHandlerOperationFinish(func(op *httpsec.Operation, res httpsec.HandlerOperationRes) {
+			if !sampler.sample(apiSecEndpoint(args, res.Status)) {
+				return
+			}
+			for tag, v := range map[string]interface{}{
+				reqHeadersSchemaTag: args.Headers,
+				reqQuerySchemaTag:   args.Query,
+				reqParamsSchemaTag:  args.PathParams,
+				reqBodySchemaTag:    reqBody,
+				resHeadersSchemaTag: res.Headers,
+				resBodySchemaTag:    resBody,
+			} {
+				if isEmptySchemaValue(v) {
+					continue
+				}
+				schema, err := encodeSchema(extractSchema(reflect.ValueOf(v), 0))
+				if err != nil {
+					log.Debug("appsec: could not encode the schema of %s: %v", tag, err)
+					continue
+				}
+				op.AddTag(tag, schema)
+			}
+		}))
+	})
+}
+
+// apiSecEndpoint returns the key identifying the endpoint of the request, made of the request method, the route
+// template and the response status code. When the integration doesn't know the route template, the normalized request
+// path is used instead, so that the requests to a same route are still likely to be sampled as a same endpoint.
+func apiSecEndpoint(args httpsec.HandlerOperationArgs, status int) string {
+	route := args.Route
+	if route == "" {
+		route = normalizePath(requestPath(args.RequestURI))
+	}
+	var b strings.Builder
+	b.WriteString(args.Method)
+	b.WriteByte(' ')
+	b.WriteString(route)
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(status))
+	return b.String()
+}
+
+// normalizePath returns the given request path where the segments looking like identifiers, which are those holding
+// digits or that are longer than apiSecMaxPathSegment, are replaced by a placeholder, such as `/users/{param}/posts`
+// for `/users/42/posts`.
+func normalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if len(seg) > apiSecMaxPathSegment || strings.ContainsAny(seg, "0123456789") {
+			segments[i] = "{param}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// apiSecSampler samples the requests of a same endpoint at most once per sampling delay.
+type apiSecSampler struct {
+	mu    sync.Mutex
+	delay time.Duration
+	last  map[string]time.Time
+	now   func() time.Time
+}
+
+func newAPISecSampler(delay time.Duration) *apiSecSampler {
+	return &apiSecSampler{
+		delay: delay,
+		last:  make(map[string]time.Time),
+		now:   time.Now,
+	}
+}
+
+// sample returns true when the schemas of the given endpoint should be extracted.
+func (s *apiSecSampler) sample(endpoint string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if last, ok := s.last[endpoint]; ok && now.Sub(last) < s.delay {
+		return false
+	}
+	if len(s.last) >= apiSecMaxEndpoints {
+		// Bound the memory usage by forgetting the endpoints whose delay expired
+		for e, last := range s.last {
+			if now.Sub(last) >= s.delay {
+				delete(s.last, e)
+			}
+		}
+		if len(s.last) >= apiSecMaxEndpoints {
+			return false
+		}
+	}
+	s.last[endpoint] = now
+	return true
+}
+
+// extractSchema returns the schema of the given value. Scalars are represented by an array holding their type, along
+// with their data classification if any, objects by an array holding the map of the schemas of their keys, and arrays
+// by an array holding the array of the distinct schemas of their items along with their length.
+func extractSchema(v reflect.Value, depth int) interface{} {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return []interface{}{schemaNull}
+		}
+		v = v.Elem()
+	}
+	if depth >= schemaMaxDepth {
+		return []interface{}{schemaUnknown}
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return []interface{}{schemaNull}
+	case reflect.Bool:
+		return []interface{}{schemaBool}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []interface{}{schemaInt}
+	case reflect.Float32, reflect.Float64:
+		return []interface{}{schemaFloat}
+	case reflect.String:
+		if class := classifyString(v.String()); class != nil {
+			return []interface{}{schemaString, class}
+		}
+		return []interface{}{schemaString}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return []interface{}{schemaUnknown}
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		if len(keys) > schemaMaxKeys {
+			keys = keys[:schemaMaxKeys]
+		}
+		obj := make(map[string]interface{}, len(keys))
+		for _, k := range keys {
+			obj[k.String()] = extractSchema(v.MapIndex(k), depth+1)
+		}
+		return []interface{}{obj}
+	case reflect.Struct:
+		obj := map[string]interface{}{}
+		t := v.Type()
+		for i := 0; i < t.NumField() && len(obj) < schemaMaxKeys; i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				// Unexported field
+				continue
+			}
+			name := f.Name
+			if tag, ok := f.Tag.Lookup("json"); ok {
+				tag = strings.Split(tag, ",")[0]
+				if tag == "-" {
+					continue
+				}
+				if tag != "" {
+					name = tag
+				}
+			}
+			obj[name] = extractSchema(v.Field(i), depth+1)
+		}
+		return []interface{}{obj}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return []interface{}{schemaNull}
+		}
+		l := v.Len()
+		items := make([]interface{}, 0, 1)
+		seen := make(map[string]struct{}, 1)
+		for i := 0; i < l && i < schemaMaxArrayItems && len(items) < schemaMaxArrayTypes; i++ {
+			schema := extractSchema(v.Index(i), depth+1)
+			key, err := json.Marshal(schema)
+			if err != nil {
+				continue
+			}
+			if _, ok := seen[string(key)]; ok {
+				continue
+			}
+			seen[string(key)] = struct{}{}
+			items = append(items, schema)
+		}
+		return []interface{}{items, map[string]int{"len": l}}
+	default:
+		return []interface{}{schemaUnknown}
+	}
+}
+
+// String data classification patterns
+var (
+	emailRE      = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)+$`)
+	jwtRE        = regexp.MustCompile(`^ey[I-L][\w=-]+\.ey[I-L][\w=-]+(?:\.[\w.+/=-]+)?$`)
+	bearerRE     = regexp.MustCompile(`(?i)^bearer\s+\S+$`)
+	basicAuthRE  = regexp.MustCompile(`(?i)^basic\s+\S+$`)
+	cardNumberRE = regexp.MustCompile(`^(?:\d[ -]?){12,18}\d$`)
+)
+
+// classifyString returns the data classification of the given string value, if any.
+func classifyString(s string) map[string]string {
+	if len(s) > apiSecMaxStringValue {
+		return nil
+	}
+	switch {
+	case emailRE.MatchString(s):
+		return map[string]string{"category": "pii", "type": "email"}
+	case cardNumberRE.MatchString(s) && luhn(s):
+		return map[string]string{"category": "payment", "type": "card"}
+	case jwtRE.MatchString(s):
+		return map[string]string{"category": "credentials", "type": "jwt"}
+	case bearerRE.MatchString(s):
+		return map[string]string{"category": "credentials", "type": "bearer_token"}
+	case basicAuthRE.MatchString(s):
+		return map[string]string{"category": "credentials", "type": "basic_auth"}
+	}
+	return nil
+}
+
+// luhn returns true when the digits of the given string pass the Luhn checksum.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// encodeSchema returns the base64 encoding of the gzip-compressed JSON schema.
+func encodeSchema(schema interface{}) (string, error) {
+	var buf bytes.Buffer
+	w := base64.NewEncoder(base64.StdEncoding, &buf)
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(schema); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// isEmptySchemaValue returns true when the given value has no schema worth reporting.
+func isEmptySchemaValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
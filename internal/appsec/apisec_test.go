@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractSchema(t *testing.T) {
+	type body struct {
+		Name    string   `json:"name"`
+		Email   string   `json:"email"`
+		Tags    []string `json:"tags,omitempty"`
+		Ignored string   `json:"-"`
+		private int
+	}
+	for _, tc := range []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{name: "nil", value: nil, expected: `[1]`},
+		{name: "bool", value: true, expected: `[2]`},
+		{name: "int", value: 42, expected: `[4]`},
+		{name: "float", value: 4.2, expected: `[16]`},
+		{name: "string", value: "gopher", expected: `[8]`},
+		{name: "email", value: "gopher@example.com", expected: `[8,{"category":"pii","type":"email"}]`},
+		{name: "card", value: "4111 1111 1111 1111", expected: `[8,{"category":"payment","type":"card"}]`},
+		{name: "not-a-card", value: "4111 1111 1111 1112", expected: `[8]`},
+		{name: "bearer", value: "Bearer abc.def", expected: `[8,{"category":"credentials","type":"bearer_token"}]`},
+		{
+			name:     "headers",
+			value:    map[string][]string{"accept": {"*/*"}, "x-forwarded-for": {"1.2.3.4", "5.6.7.8"}},
+			expected: `[{"accept":[[[8]],{"len":1}],"x-forwarded-for":[[[8]],{"len":2}]}]`,
+		},
+		{
+			name:     "mixed-array",
+			value:    []interface{}{1, "a", 2, nil},
+			expected: `[[[4],[8],[1]],{"len":4}]`,
+		},
+		{
+			name:     "struct",
+			value:    &body{Name: "gopher", Email: "gopher@example.com"},
+			expected: `[{"email":[8,{"category":"pii","type":"email"}],"name":[8],"tags":[1]}]`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			schema, err := json.Marshal(extractSchema(reflect.ValueOf(tc.value), 0))
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expected, string(schema))
+		})
+	}
+
+	t.Run("max-depth", func(t *testing.T) {
+		var v interface{} = "leaf"
+		for i := 0; i < 2*schemaMaxDepth; i++ {
+			v = []interface{}{v}
+		}
+		schema, err := json.Marshal(extractSchema(reflect.ValueOf(v), 0))
+		require.NoError(t, err)
+		require.Contains(t, string(schema), `[0]`)
+		require.NotContains(t, string(schema), `[8]`)
+	})
+}
+
+func TestEncodeSchema(t *testing.T) {
+	encoded, err := encodeSchema([]interface{}{map[string]interface{}{"key": []interface{}{schemaString}}})
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"key":[8]}]`, decodeSchema(t, encoded))
+}
+
+func TestAPISecSampler(t *testing.T) {
+	now := time.Now()
+	s := newAPISecSampler(30 * time.Second)
+	s.now = func() time.Time { return now }
+
+	require.True(t, s.sample("GET /users 200"))
+	require.False(t, s.sample("GET /users 200"))
+	require.True(t, s.sample("GET /users 404"))
+
+	now = now.Add(30 * time.Second)
+	require.True(t, s.sample("GET /users 200"))
+
+	t.Run("endpoint", func(t *testing.T) {
+		args := httpsec.HandlerOperationArgs{
+			Method:     "GET",
+			RequestURI: "/users/42/posts/1?q=1",
+			PathParams: map[string]string{"user": "42", "post": "1"},
+			Route:      "/users/{user}/posts/{post}",
+		}
+		require.Equal(t, "GET /users/{user}/posts/{post} 200", apiSecEndpoint(args, 200))
+
+		// Without the route template, the request path is normalized
+		args.Route = ""
+		require.Equal(t, "GET /users/{param}/posts/{param} 200", apiSecEndpoint(args, 200))
+		args.RequestURI = "/users/gopher/posts/" + strings.Repeat("a", apiSecMaxPathSegment+1)
+		require.Equal(t, "GET /users/gopher/posts/{param} 200", apiSecEndpoint(args, 200))
+	})
+}
+
+func decodeSchema(t *testing.T, encoded string) string {
+	gz, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, bytes.NewBufferString(encoded)))
+	require.NoError(t, err)
+	schema, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(schema)
+}
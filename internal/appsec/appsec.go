@@ -97,6 +97,12 @@ type appsec struct {
 	limiter       *TokenTicker
 	rc            *remoteconfig.Client
 	started       bool
+	// rules manages the security rules, which can be updated through remote config
+	rules *rulesManager
+	// mu guards wafHandles, which is set when the WAF gets registered and read by the remote config callbacks
+	mu sync.Mutex
+	// wafHandles holds the WAF handle in use while started
+	wafHandles *wafHandleSwapper
 }
 
 func newAppSec(cfg *Config) *appsec {
@@ -109,8 +115,9 @@ func newAppSec(cfg *Config) *appsec {
 		log.Error("appsec: Remote config: disabled due to a client creation error: %v", err)
 	}
 	return &appsec{
-		cfg: cfg,
-		rc:  client,
+		cfg:   cfg,
+		rc:    client,
+		rules: newRulesManager(cfg.rules),
 	}
 }
 
@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"strings"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/sharedsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
+)
+
+// Automated user login event span tags
+const (
+	loginSuccessTrackTag    = "appsec.events.users.login.success.track"
+	loginSuccessAutoModeTag = "_dd.appsec.events.users.login.success.auto.mode"
+	loginFailureTrackTag    = "appsec.events.users.login.failure.track"
+	loginFailureAutoModeTag = "_dd.appsec.events.users.login.failure.auto.mode"
+	loginFailureUserIDTag   = "appsec.events.users.login.failure.usr.id"
+	userIDTag               = "usr.id"
+)
+
+// loginUserIDKeys are the request body keys holding the user ID of login requests.
+var loginUserIDKeys = []string{"username", "user", "login", "email", "mail", "user_id", "userid"}
+
+// newAutoUserEventsListener returns the HTTP event listener detecting the user login successes and failures of the
+// requests to the configured login endpoints, according to their response status codes only. The user ID is the one
+// given to appsec.SetUser() while handling the request if any, or the login field of the request body given to
+// appsec.MonitorParsedHTTPBody(), or the HTTP basic authentication user. The manually tracked user login events take
+// precedence over the automated ones.
+func newAutoUserEventsListener(cfg AutoUserEventsConfig) dyngo.EventListener {
+	return httpsec.OnHandlerOperationStart(func(op *httpsec.Operation, args httpsec.HandlerOperationArgs) {
+		if !cfg.isLoginEndpoint(args.Method, requestPath(args.RequestURI)) {
+			return
+		}
+		var (
+			manual                bool
+			sdkUserID, bodyUserID string
+		)
+		op.On(sharedsec.OnUserLoginEventOperationStart(func(*sharedsec.UserLoginEventOperation, sharedsec.UserLoginEventOperationArgs) {
+			manual = true
+		}))
+		op.On(sharedsec.OnUserIDOperationStart(func(_ *sharedsec.UserIDOperation, args sharedsec.UserIDOperationArgs) {
+			sdkUserID = args.UserID
+		}))
+		op.On(httpsec.OnSDKBodyOperationStart(func(_ *httpsec.SDKBodyOperation, args httpsec.SDKBodyOperationArgs) {
+			bodyUserID = loginUserID(reflect.ValueOf(args.Body))
+		}))
+		op.On(httpsec.OnHandlerOperationFinish(func(op *httpsec.Operation, res httpsec.HandlerOperationRes) {
+			if manual {
+				return
+			}
+			var success bool
+			switch {
+			case cfg.SuccessStatus.Contains(res.Status):
+				success = true
+			case cfg.FailureStatus.Contains(res.Status):
+			default:
+				return
+			}
+			uid := sdkUserID
+			if uid == "" {
+				uid = bodyUserID
+			}
+			if uid == "" {
+				uid = basicAuthUser(args.Headers)
+			}
+			if uid != "" && cfg.Mode == AutoUserEventsSafe {
+				uid = anonymizeUserID(uid)
+			}
+			if success {
+				op.AddTag(loginSuccessTrackTag, true)
+				op.AddTag(loginSuccessAutoModeTag, string(cfg.Mode))
+				// The user ID given to appsec.SetUser() was already set on the span by the SDK
+				if sdkUserID == "" && uid != "" {
+					op.AddTag(userIDTag, uid)
+				}
+			} else {
+				op.AddTag(loginFailureTrackTag, true)
+				op.AddTag(loginFailureAutoModeTag, string(cfg.Mode))
+				if uid != "" {
+					op.AddTag(loginFailureUserIDTag, uid)
+				}
+			}
+			op.AddTag(ext.ManualKeep, samplernames.AppSec)
+		}))
+	})
+}
+
+// isLoginEndpoint returns true when the given request method and path match one of the login endpoints.
+func (cfg *AutoUserEventsConfig) isLoginEndpoint(method, path string) bool {
+	for _, e := range cfg.Endpoints {
+		if (e.Method == "" || e.Method == method) && e.Path.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// loginUserID returns the user ID of the given parsed login request body, if any.
+func loginUserID(v reflect.Value) string {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return ""
+		}
+		for _, key := range loginUserIDKeys {
+			for _, k := range v.MapKeys() {
+				if strings.EqualFold(k.String(), key) {
+					if uid := loginUserIDValue(v.MapIndex(k)); uid != "" {
+						return uid
+					}
+				}
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for _, key := range loginUserIDKeys {
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				if f.PkgPath != "" {
+					// Unexported field
+					continue
+				}
+				name := f.Name
+				if tag, ok := f.Tag.Lookup("json"); ok {
+					if tag = strings.Split(tag, ",")[0]; tag != "" {
+						name = tag
+					}
+				}
+				if strings.EqualFold(name, key) {
+					if uid := loginUserIDValue(v.Field(i)); uid != "" {
+						return uid
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// loginUserIDValue returns the given string value, or the first string of the given list of strings such as the
+// url.Values of a parsed form.
+func loginUserIDValue(v reflect.Value) string {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Len() > 0 {
+			return loginUserIDValue(v.Index(0))
+		}
+	}
+	return ""
+}
+
+// requestPath returns the path of the given request URI, without its query string.
+func requestPath(uri string) string {
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		return uri[:i]
+	}
+	return uri
+}
+
+// basicAuthUser returns the user of the HTTP basic authentication of the given request headers, if any.
+func basicAuthUser(headers map[string][]string) string {
+	for _, auth := range headers["authorization"] {
+		const prefix = "basic "
+		if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+			continue
+		}
+		creds, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[len(prefix):]))
+		if err != nil {
+			continue
+		}
+		if i := strings.IndexByte(string(creds), ':'); i > 0 {
+			return string(creds[:i])
+		}
+	}
+	return ""
+}
+
+// anonymizeUserID returns the anonymized user ID reported by the safe mode, so that the same user IDs can still be
+// correlated without being disclosed.
+func anonymizeUserID(uid string) string {
+	sum := sha256.Sum256([]byte(uid))
+	return "anon_" + hex.EncodeToString(sum[:16])
+}
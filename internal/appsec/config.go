@@ -10,12 +10,14 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statusrange"
 )
 
 const (
@@ -25,11 +27,21 @@ const (
 	traceRateLimitEnvVar  = "DD_APPSEC_TRACE_RATE_LIMIT"
 	obfuscatorKeyEnvVar   = "DD_APPSEC_OBFUSCATION_PARAMETER_KEY_REGEXP"
 	obfuscatorValueEnvVar = "DD_APPSEC_OBFUSCATION_PARAMETER_VALUE_REGEXP"
+	apiSecEnabledEnvVar   = "DD_API_SECURITY_ENABLED"
+	apiSecSampleDelayVar  = "DD_API_SECURITY_SAMPLE_DELAY"
+	autoUserEventsEnvVar  = "DD_APPSEC_AUTOMATED_USER_EVENTS_TRACKING"
+	autoLoginEndpointsVar = "DD_APPSEC_AUTO_LOGIN_ENDPOINTS"
+	autoLoginSuccessVar   = "DD_APPSEC_AUTO_LOGIN_SUCCESS_STATUS"
+	autoLoginFailureVar   = "DD_APPSEC_AUTO_LOGIN_FAILURE_STATUS"
 )
 
 const (
 	defaultWAFTimeout           = 4 * time.Millisecond
 	defaultTraceRate            = 100 // up to 100 appsec traces/s
+	defaultAPISecSampleDelay    = 30 * time.Second
+	defaultAutoLoginEndpoints   = `POST (?i)/(?:log[-_]?in|sign[-_]?in|sessions?|auth(?:enticate)?)/?$`
+	defaultAutoLoginSuccess     = "200-399"
+	defaultAutoLoginFailure     = "400-499"
 	defaultObfuscatorKeyRegex   = `(?i)(?:p(?:ass)?w(?:or)?d|pass(?:_?phrase)?|secret|(?:api_?|private_?|public_?)key)|token|consumer_?(?:id|key|secret)|sign(?:ed|ature)|bearer|authorization`
 	defaultObfuscatorValueRegex = `(?i)(?:p(?:ass)?w(?:or)?d|pass(?:_?phrase)?|secret|(?:api_?|private_?|public_?|access_?|secret_?)key(?:_?id)?|token|consumer_?(?:id|key|secret)|sign(?:ed|ature)?|auth(?:entication|orization)?)(?:\s*=[^;]|"\s*:\s*"[^"]+")|bearer\s+[a-z0-9\._\-]+|token:[a-z0-9]{13}|gh[opsu]_[0-9a-zA-Z]{36}|ey[I-L][\w=-]+\.ey[I-L][\w=-]+(?:\.[\w.+\/=-]+)?|[\-]{5}BEGIN[a-z\s]+PRIVATE\sKEY[\-]{5}[^\-]+[\-]{5}END[a-z\s]+PRIVATE\sKEY|ssh-rsa\s*[a-z0-9\/\.+]{100,}`
 )
@@ -47,6 +59,10 @@ type Config struct {
 	traceRateLimit uint
 	// Obfuscator configuration parameters
 	obfuscator ObfuscatorConfig
+	// API security configuration
+	apiSec APISecConfig
+	// Automated user events configuration
+	autoUserEvents AutoUserEventsConfig
 	// rc is the remote configuration client used to receive product configuration updates. Nil if rc is disabled (default)
 	rc *remoteconfig.ClientConfig
 }
@@ -64,6 +80,46 @@ type ObfuscatorConfig struct {
 	ValueRegex string
 }
 
+// APISecConfig holds the API security configuration.
+type APISecConfig struct {
+	// Enabled enables the extraction of the request and response schemas.
+	Enabled bool
+	// SampleDelay is the minimum delay between two extractions of the schemas of a same endpoint.
+	SampleDelay time.Duration
+}
+
+// AutoUserEventsMode is the mode of the automated user login events, controlling how the user IDs get reported.
+type AutoUserEventsMode string
+
+const (
+	// AutoUserEventsDisabled disables the automated user login events.
+	AutoUserEventsDisabled AutoUserEventsMode = "disabled"
+	// AutoUserEventsSafe reports the automated user login events with anonymized user IDs.
+	AutoUserEventsSafe AutoUserEventsMode = "safe"
+	// AutoUserEventsExtended reports the automated user login events with the actual user IDs.
+	AutoUserEventsExtended AutoUserEventsMode = "extended"
+)
+
+// AutoUserEventsConfig holds the configuration of the automated user login events.
+type AutoUserEventsConfig struct {
+	// Mode of the automated user login events.
+	Mode AutoUserEventsMode
+	// Endpoints are the login endpoints.
+	Endpoints []LoginEndpoint
+	// SuccessStatus are the response status codes of the successful logins.
+	SuccessStatus statusrange.List
+	// FailureStatus are the response status codes of the failed logins.
+	FailureStatus statusrange.List
+}
+
+// LoginEndpoint matches the requests to a login endpoint.
+type LoginEndpoint struct {
+	// Method is the request method, or empty for any method.
+	Method string
+	// Path is the regular expression the request path must match.
+	Path *regexp.Regexp
+}
+
 // isEnabled returns true when appsec is enabled when the environment variable
 // It also returns whether the env var is actually set in the env or not
 // DD_APPSEC_ENABLED is set to true.
@@ -88,6 +144,8 @@ func newConfig() (*Config, error) {
 		wafTimeout:     readWAFTimeoutConfig(),
 		traceRateLimit: readRateLimitConfig(),
 		obfuscator:     readObfuscatorConfig(),
+		apiSec:         readAPISecConfig(),
+		autoUserEvents: readAutoUserEventsConfig(),
 	}, nil
 }
 
@@ -155,6 +213,95 @@ func readObfuscatorConfigRegexp(name, defaultValue string) string {
 	return val
 }
 
+func readAPISecConfig() APISecConfig {
+	cfg := APISecConfig{SampleDelay: defaultAPISecSampleDelay}
+	if value := os.Getenv(apiSecEnabledEnvVar); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Error("appsec: could not parse the env var %s=%s as a boolean value: %v. API security will be disabled.", apiSecEnabledEnvVar, value, err)
+		}
+		cfg.Enabled = enabled
+	}
+	if value := os.Getenv(apiSecSampleDelayVar); value != "" {
+		// Default to seconds when no time duration unit is specified
+		if lastRune, _ := utf8.DecodeLastRuneInString(value); !unicode.IsLetter(lastRune) {
+			value += "s"
+		}
+		delay, err := time.ParseDuration(value)
+		if err != nil {
+			logEnvVarParsingError(apiSecSampleDelayVar, value, err, cfg.SampleDelay)
+		} else if delay < 0 {
+			logUnexpectedEnvVarValue(apiSecSampleDelayVar, delay, "expecting a positive duration", cfg.SampleDelay)
+		} else {
+			cfg.SampleDelay = delay
+		}
+	}
+	return cfg
+}
+
+func readAutoUserEventsConfig() AutoUserEventsConfig {
+	cfg := AutoUserEventsConfig{Mode: AutoUserEventsSafe}
+	if value := os.Getenv(autoUserEventsEnvVar); value != "" {
+		switch mode := AutoUserEventsMode(strings.ToLower(value)); mode {
+		case AutoUserEventsDisabled, AutoUserEventsSafe, AutoUserEventsExtended:
+			cfg.Mode = mode
+		default:
+			logUnexpectedEnvVarValue(autoUserEventsEnvVar, value, "expecting one of disabled, safe or extended", cfg.Mode)
+		}
+	}
+	cfg.Endpoints = readLoginEndpoints(autoLoginEndpointsVar, defaultAutoLoginEndpoints)
+	cfg.SuccessStatus = readStatusRanges(autoLoginSuccessVar, defaultAutoLoginSuccess)
+	cfg.FailureStatus = readStatusRanges(autoLoginFailureVar, defaultAutoLoginFailure)
+	return cfg
+}
+
+// readLoginEndpoints parses the newline-separated list of login endpoints of the given env var, where each endpoint is
+// an optional request method followed by the regular expression of the request path, such as `POST /login$`. Unlike
+// commas, newlines cannot be part of the regular expressions of request paths, such as the repetitions `{m,n}`.
+func readLoginEndpoints(name, defaultValue string) []LoginEndpoint {
+	value := os.Getenv(name)
+	if value == "" {
+		value = defaultValue
+	}
+	var endpoints []LoginEndpoint
+	for _, entry := range strings.Split(value, "\n") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var method string
+		if i := strings.IndexByte(entry, ' '); i > 0 {
+			method, entry = strings.ToUpper(entry[:i]), strings.TrimSpace(entry[i+1:])
+		}
+		re, err := regexp.Compile(entry)
+		if err != nil {
+			log.Error("appsec: could not compile the login endpoint regular expression `%s` of %s: %v. The endpoint will be ignored.", entry, name, err)
+			continue
+		}
+		endpoints = append(endpoints, LoginEndpoint{Method: method, Path: re})
+	}
+	return endpoints
+}
+
+// readStatusRanges parses the comma-separated list of status codes, or inclusive status code ranges such as
+// `200-299`, of the given env var. Invalid entries are ignored, and the default value is used when none is valid.
+func readStatusRanges(name, defaultValue string) statusrange.List {
+	defaultRanges, _ := statusrange.Parse(defaultValue)
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultRanges
+	}
+	ranges, err := statusrange.Parse(value)
+	if err != nil {
+		log.Error("appsec: ignoring the %v of the env var %s=%s", err, name, value)
+	}
+	if len(ranges) == 0 {
+		log.Error("appsec: no valid status code in the env var %s=%s. Using default value %s.", name, value, defaultValue)
+		return defaultRanges
+	}
+	return ranges
+}
+
 func readRulesConfig() (rules []byte, err error) {
 	rules = []byte(staticRecommendedRules)
 	filepath := os.Getenv(rulesEnvVar)
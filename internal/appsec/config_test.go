@@ -10,10 +10,13 @@ package appsec
 
 import (
 	"os"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/statusrange"
 )
 
 func TestConfig(t *testing.T) {
@@ -25,6 +28,13 @@ func TestConfig(t *testing.T) {
 			KeyRegex:   defaultObfuscatorKeyRegex,
 			ValueRegex: defaultObfuscatorValueRegex,
 		},
+		apiSec: APISecConfig{SampleDelay: defaultAPISecSampleDelay},
+		autoUserEvents: AutoUserEventsConfig{
+			Mode:          AutoUserEventsSafe,
+			Endpoints:     []LoginEndpoint{{Method: "POST", Path: regexp.MustCompile(`(?i)/(?:log[-_]?in|sign[-_]?in|sessions?|auth(?:enticate)?)/?$`)}},
+			SuccessStatus: statusrange.List{{Min: 200, Max: 399}},
+			FailureStatus: statusrange.List{{Min: 400, Max: 499}},
+		},
 	}
 
 	t.Run("default", func(t *testing.T) {
@@ -247,6 +257,64 @@ func TestConfig(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("api-security", func(t *testing.T) {
+		t.Run("enabled", func(t *testing.T) {
+			expCfg := *expectedDefaultConfig
+			expCfg.apiSec = APISecConfig{Enabled: true, SampleDelay: 10 * time.Second}
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(apiSecEnabledEnvVar, "true"))
+			require.NoError(t, os.Setenv(apiSecSampleDelayVar, "10"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, &expCfg, cfg)
+		})
+		t.Run("invalid", func(t *testing.T) {
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(apiSecEnabledEnvVar, "yes please"))
+			require.NoError(t, os.Setenv(apiSecSampleDelayVar, "-1m"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, expectedDefaultConfig, cfg)
+		})
+	})
+
+	t.Run("auto-user-events", func(t *testing.T) {
+		t.Run("configured", func(t *testing.T) {
+			expCfg := *expectedDefaultConfig
+			expCfg.autoUserEvents = AutoUserEventsConfig{
+				Mode: AutoUserEventsExtended,
+				Endpoints: []LoginEndpoint{
+					{Method: "POST", Path: regexp.MustCompile(`^/api/session$`)},
+					{Path: regexp.MustCompile(`^/signin$`)},
+					{Method: "PUT", Path: regexp.MustCompile(`^/api/v[0-9]{1,2}/(?:login|signin)$`)},
+				},
+				SuccessStatus: statusrange.List{{Min: 200, Max: 200}, {Min: 302, Max: 302}},
+				FailureStatus: statusrange.List{{Min: 401, Max: 403}},
+			}
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(autoUserEventsEnvVar, "Extended"))
+			require.NoError(t, os.Setenv(autoLoginEndpointsVar, "post ^/api/session$\n ^/signin$ \nPUT ^/api/v[0-9]{1,2}/(?:login|signin)$\n"))
+			require.NoError(t, os.Setenv(autoLoginSuccessVar, "200,302"))
+			require.NoError(t, os.Setenv(autoLoginFailureVar, "401-403"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, &expCfg, cfg)
+		})
+		t.Run("invalid", func(t *testing.T) {
+			restoreEnv := cleanEnv()
+			defer restoreEnv()
+			require.NoError(t, os.Setenv(autoUserEventsEnvVar, "everything"))
+			require.NoError(t, os.Setenv(autoLoginSuccessVar, "2xx"))
+			require.NoError(t, os.Setenv(autoLoginFailureVar, "499-400"))
+			cfg, err := newConfig()
+			require.NoError(t, err)
+			require.Equal(t, expectedDefaultConfig, cfg)
+		})
+	})
 }
 
 func cleanEnv() func() {
@@ -256,6 +324,12 @@ func cleanEnv() func() {
 		traceRateLimitEnvVar:  os.Getenv(traceRateLimitEnvVar),
 		obfuscatorKeyEnvVar:   os.Getenv(obfuscatorKeyEnvVar),
 		obfuscatorValueEnvVar: os.Getenv(obfuscatorValueEnvVar),
+		apiSecEnabledEnvVar:   os.Getenv(apiSecEnabledEnvVar),
+		apiSecSampleDelayVar:  os.Getenv(apiSecSampleDelayVar),
+		autoUserEventsEnvVar:  os.Getenv(autoUserEventsEnvVar),
+		autoLoginEndpointsVar: os.Getenv(autoLoginEndpointsVar),
+		autoLoginSuccessVar:   os.Getenv(autoLoginSuccessVar),
+		autoLoginFailureVar:   os.Getenv(autoLoginFailureVar),
 	}
 	for k, _ := range env {
 		if err := os.Unsetenv(k); err != nil {
@@ -0,0 +1,175 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package graphqlsec defines the GraphQL instrumentation API and contract for
+// AppSec. It defines an abstract representation of the execution of GraphQL
+// requests and of their resolvers, which GraphQL integrations must use to
+// enable the monitoring of the resolver arguments.
+package graphqlsec
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+)
+
+type (
+	// RequestOperation type representing the execution of a GraphQL request.
+	// It aggregates the arguments of the resolvers executed by the request.
+	RequestOperation struct {
+		dyngo.Operation
+
+		mu        sync.Mutex
+		resolvers map[string][]map[string]interface{}
+	}
+	// RequestOperationArgs is the GraphQL request operation arguments.
+	RequestOperationArgs struct {
+		// Query is the raw GraphQL query.
+		Query string
+		// OperationName is the name of the executed GraphQL operation, if any.
+		OperationName string
+		// Variables are the GraphQL query variables.
+		Variables map[string]interface{}
+	}
+	// RequestOperationRes is the GraphQL request operation results.
+	RequestOperationRes struct {
+		// Resolvers corresponds to the address `graphql.server.all_resolvers`.
+		// It holds the list of arguments of each executed resolver.
+		Resolvers map[string][]map[string]interface{}
+	}
+
+	// OnRequestOperationStart function type, called when a GraphQL request
+	// operation starts.
+	OnRequestOperationStart func(*RequestOperation, RequestOperationArgs)
+	// OnRequestOperationFinish function type, called when a GraphQL request
+	// operation finishes.
+	OnRequestOperationFinish func(*RequestOperation, RequestOperationRes)
+)
+
+type (
+	// ResolverOperation type representing the execution of a GraphQL
+	// resolver. It gets both created and destroyed in a single call to
+	// ProtectResolver.
+	ResolverOperation struct {
+		dyngo.Operation
+		// Error is set by the event listeners when the execution of the
+		// resolver must be blocked.
+		Error error
+	}
+	// ResolverOperationArgs is the GraphQL resolver operation arguments.
+	ResolverOperationArgs struct {
+		// FieldName is the name of the resolved field.
+		FieldName string
+		// Arguments are the resolver arguments, corresponding to the address
+		// `graphql.server.resolver` along with the field name.
+		Arguments map[string]interface{}
+	}
+	// ResolverOperationRes is the GraphQL resolver operation results.
+	ResolverOperationRes struct{}
+
+	// OnResolverOperationStart function type, called when a GraphQL resolver
+	// operation starts.
+	OnResolverOperationStart func(*ResolverOperation, ResolverOperationArgs)
+)
+
+// requestOperationKey is the context key of the current GraphQL request operation.
+type requestOperationKey struct{}
+
+// StartRequestOperation starts a GraphQL request operation, as a child of the
+// HTTP handler operation found in the given context. The returned context
+// holds the new operation so that the resolvers executed with it get
+// aggregated into it. The returned operation is nil when the request is not
+// executed in the context of a monitored request.
+func StartRequestOperation(ctx context.Context, args RequestOperationArgs) (context.Context, *RequestOperation) {
+	parent, ok := ctx.Value(instrumentation.ContextKey{}).(dyngo.Operation)
+	if !ok {
+		return ctx, nil
+	}
+	op := &RequestOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return context.WithValue(ctx, requestOperationKey{}, op), op
+}
+
+// Finish the GraphQL request operation, along with the arguments of the
+// resolvers it executed. It is a no-op on a nil operation.
+func (op *RequestOperation) Finish() {
+	if op == nil {
+		return
+	}
+	op.mu.Lock()
+	resolvers := op.resolvers
+	op.mu.Unlock()
+	dyngo.FinishOperation(op, RequestOperationRes{Resolvers: resolvers})
+}
+
+func (op *RequestOperation) addResolver(fieldName string, args map[string]interface{}) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	if op.resolvers == nil {
+		op.resolvers = make(map[string][]map[string]interface{})
+	}
+	op.resolvers[fieldName] = append(op.resolvers[fieldName], args)
+}
+
+// ProtectResolver starts and finishes a GraphQL resolver operation, as a child
+// of the GraphQL request operation, or of the HTTP handler operation, found in
+// the given context, before executing the resolver of the given field with the
+// given arguments. An error is returned if the execution of the resolver must
+// be blocked, which the caller must return as the GraphQL error of the field
+// instead of executing the resolver. The return value is nil otherwise, and in
+// particular when the resolver is not executed in the context of a monitored
+// request.
+func ProtectResolver(ctx context.Context, fieldName string, args map[string]interface{}) error {
+	var parent dyngo.Operation
+	if reqOp, ok := ctx.Value(requestOperationKey{}).(*RequestOperation); ok {
+		reqOp.addResolver(fieldName, args)
+		parent = reqOp
+	} else if parent, ok = ctx.Value(instrumentation.ContextKey{}).(dyngo.Operation); !ok {
+		return nil
+	}
+	op := &ResolverOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, ResolverOperationArgs{FieldName: fieldName, Arguments: args})
+	dyngo.FinishOperation(op, ResolverOperationRes{})
+	return op.Error
+}
+
+var (
+	requestOperationArgsType  = reflect.TypeOf((*RequestOperationArgs)(nil)).Elem()
+	requestOperationResType   = reflect.TypeOf((*RequestOperationRes)(nil)).Elem()
+	resolverOperationArgsType = reflect.TypeOf((*ResolverOperationArgs)(nil)).Elem()
+)
+
+// ListenedType returns the type a OnRequestOperationStart event listener
+// listens to, which is the RequestOperationArgs type.
+func (OnRequestOperationStart) ListenedType() reflect.Type { return requestOperationArgsType }
+
+// Call the underlying event listener function by performing the type-assertion
+// on v whose type is the one returned by ListenedType().
+func (f OnRequestOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*RequestOperation), v.(RequestOperationArgs))
+}
+
+// ListenedType returns the type a OnRequestOperationFinish event listener
+// listens to, which is the RequestOperationRes type.
+func (OnRequestOperationFinish) ListenedType() reflect.Type { return requestOperationResType }
+
+// Call the underlying event listener function by performing the type-assertion
+// on v whose type is the one returned by ListenedType().
+func (f OnRequestOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*RequestOperation), v.(RequestOperationRes))
+}
+
+// ListenedType returns the type a OnResolverOperationStart event listener
+// listens to, which is the ResolverOperationArgs type.
+func (OnResolverOperationStart) ListenedType() reflect.Type { return resolverOperationArgsType }
+
+// Call the underlying event listener function by performing the type-assertion
+// on v whose type is the one returned by ListenedType().
+func (f OnResolverOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*ResolverOperation), v.(ResolverOperationArgs))
+}
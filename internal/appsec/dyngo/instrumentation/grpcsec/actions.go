@@ -30,11 +30,11 @@ type ActionsHandler struct {
 
 // NewActionsHandler returns an action handler holding the default ASM actions.
 // Currently, only the default "block" action is supported
-func NewActionsHandler() ActionsHandler {
+func NewActionsHandler() *ActionsHandler {
 	// Register the default "block" action as specified in the blocking RFC
 	actions := map[string]Action{"block": &BlockRequestAction{Status: codes.Aborted}}
 
-	return ActionsHandler{
+	return &ActionsHandler{
 		actions: actions,
 	}
 }
@@ -57,7 +57,11 @@ func (h *ActionsHandler) Apply(id string, op *HandlerOperation) bool {
 	}
 	// Currently, only the "block_request" type is supported, so we only need to check for blockRequestParams
 	if p, ok := a.(*BlockRequestAction); ok {
-		op.Error = status.Error(p.Status, "Request blocked")
+		msg := p.Message
+		if msg == "" {
+			msg = "Request blocked"
+		}
+		op.Error = status.Error(p.Status, msg)
 		op.AddTag(instrumentation.BlockedRequestTag, true)
 		return true
 	}
@@ -68,6 +72,8 @@ func (h *ActionsHandler) Apply(id string, op *HandlerOperation) bool {
 type BlockRequestAction struct {
 	// Status is the return code to use when blocking the request
 	Status codes.Code
+	// Message is the status message to use when blocking the request. It defaults to "Request blocked" when empty.
+	Message string
 }
 
 func (*BlockRequestAction) isAction() {}
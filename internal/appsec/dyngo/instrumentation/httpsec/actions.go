@@ -56,6 +56,24 @@ func NewBlockRequestAction(status int, template string) BlockRequestAction {
 
 }
 
+// RedirectRequestAction is the action that holds the HTTP handler to use to redirect the request
+type RedirectRequestAction struct {
+	// handler is the http handler to use to redirect the request
+	handler http.Handler
+}
+
+func (*RedirectRequestAction) isAction() {}
+
+// NewRedirectRequestAction creates, initializes and returns a new RedirectRequestAction redirecting the request to
+// the given location with the given redirection status code. The status code defaults to 303 when it isn't a
+// redirection status code.
+func NewRedirectRequestAction(status int, location string) RedirectRequestAction {
+	if status < 300 || status > 399 {
+		status = http.StatusSeeOther
+	}
+	return RedirectRequestAction{handler: http.RedirectHandler(location, status)}
+}
+
 func newBlockRequestHandler(status int, ct string, payload []byte) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", ct)
@@ -102,9 +120,26 @@ func (h *ActionsHandler) Apply(id string, op *Operation) bool {
 		return false
 	}
 	op.AddAction(a)
+	return interrupts(a)
+}
+
+// Interrupts returns true if the action identified by `id` would interrupt the request flow, without applying it
+// to any operation. It allows protocols with their own way of reporting errors, such as GraphQL, to block the
+// request without the HTTP blocking response.
+func (h *ActionsHandler) Interrupts(id string) bool {
+	h.mu.RLock()
+	a, ok := h.actions[id]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	return interrupts(a)
+}
 
+// interrupts returns true if the given action interrupts the request flow.
+func interrupts(a Action) bool {
 	switch a.(type) {
-	case *BlockRequestAction:
+	case *BlockRequestAction, *RedirectRequestAction:
 		return true
 	default:
 		return false
@@ -153,3 +153,38 @@ func TestNewBlockRequestAction(t *testing.T) {
 		}
 	})
 }
+
+func TestNewRedirectRequestAction(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	mux.HandleFunc("/redirect-302", NewRedirectRequestAction(302, "/blocked").handler.ServeHTTP)
+	mux.HandleFunc("/redirect-default", NewRedirectRequestAction(403, "/blocked").handler.ServeHTTP)
+	defer srv.Close()
+	client := srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	for _, tc := range []struct {
+		path   string
+		status int
+	}{
+		{path: "/redirect-302", status: 302},
+		{path: "/redirect-default", status: 303},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			res, err := client.Get(srv.URL + tc.path)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.status, res.StatusCode)
+			require.Equal(t, "/blocked", res.Header.Get("Location"))
+		})
+	}
+}
+
+func TestActionsHandler(t *testing.T) {
+	h := NewActionsHandler()
+	redirect := NewRedirectRequestAction(302, "/blocked")
+	h.RegisterAction("redirect", &redirect)
+	require.True(t, h.Interrupts("block"))
+	require.True(t, h.Interrupts("redirect"))
+	require.False(t, h.Interrupts("unknown"))
+}
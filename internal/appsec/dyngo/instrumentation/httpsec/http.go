@@ -31,6 +31,8 @@ import (
 type (
 	// HandlerOperationArgs is the HTTP handler operation arguments.
 	HandlerOperationArgs struct {
+		// Method corresponds to the address `server.request.method`
+		Method string
 		// RequestURI corresponds to the address `server.request.uri.raw`
 		RequestURI string
 		// Headers corresponds to the address `server.request.headers.no_cookies`
@@ -43,12 +45,17 @@ type (
 		PathParams map[string]string
 		// ClientIP corresponds to the addres `http.client_ip`
 		ClientIP instrumentation.NetaddrIP
+		// Route is the route template of the request, such as `/users/{id}`, or empty when unknown. It is the value
+		// of the `http.route` span tag and has no rule address.
+		Route string
 	}
 
 	// HandlerOperationRes is the HTTP handler operation results.
 	HandlerOperationRes struct {
 		// Status corresponds to the address `server.response.status`.
 		Status int
+		// Headers corresponds to the address `server.response.headers.no_cookies`.
+		Headers map[string][]string
 	}
 
 	// SDKBodyOperationArgs is the SDK body operation arguments.
@@ -59,18 +66,72 @@ type (
 
 	// SDKBodyOperationRes is the SDK body operation results.
 	SDKBodyOperationRes struct{}
+
+	// SDKResponseBodyOperationArgs is the SDK response body operation arguments.
+	SDKResponseBodyOperationArgs struct {
+		// Body corresponds to the address `server.response.body`.
+		Body interface{}
+	}
+
+	// SDKResponseBodyOperationRes is the SDK response body operation results.
+	SDKResponseBodyOperationRes struct{}
+
+	// PathParamsOperationArgs is the path parameters operation arguments.
+	PathParamsOperationArgs struct {
+		// PathParams corresponds to the address `server.request.path_params`.
+		PathParams map[string]string
+		// Route is the route template the path parameters belong to. It
+		// replaces the route of the handler operation and has no rule address.
+		Route string
+	}
+
+	// PathParamsOperationRes is the path parameters operation results.
+	PathParamsOperationRes struct{}
 )
 
 // MonitorParsedBody starts and finishes the SDK body operation.
 // This function should not be called when AppSec is disabled in order to
 // get preciser error logs.
-func MonitorParsedBody(ctx context.Context, body interface{}) {
-	if parent := fromContext(ctx); parent != nil {
-		op := StartSDKBodyOperation(parent, SDKBodyOperationArgs{Body: body})
-		op.Finish()
-	} else {
+// An error is returned if the request must be blocked because of the body.
+func MonitorParsedBody(ctx context.Context, body interface{}) error {
+	parent := fromContext(ctx)
+	if parent == nil {
 		log.Error("appsec: parsed http body monitoring ignored: could not find the http handler instrumentation metadata in the request context: the request handler is not being monitored by a middleware function or the provided context is not the expected request context")
+		return nil
+	}
+	op := StartSDKBodyOperation(parent, SDKBodyOperationArgs{Body: body})
+	return op.Finish()
+}
+
+// MonitorParsedResponseBody starts and finishes the SDK response body
+// operation. This function should not be called when AppSec is disabled in
+// order to get preciser error logs.
+// An error is returned if the request must be blocked because of the response
+// body.
+func MonitorParsedResponseBody(ctx context.Context, body interface{}) error {
+	parent := fromContext(ctx)
+	if parent == nil {
+		log.Error("appsec: parsed http response body monitoring ignored: could not find the http handler instrumentation metadata in the request context: the request handler is not being monitored by a middleware function or the provided context is not the expected request context")
+		return nil
+	}
+	op := StartSDKResponseBodyOperation(parent, SDKResponseBodyOperationArgs{Body: body})
+	return op.Finish()
+}
+
+// MonitorPathParams starts and finishes the path parameters operation, for the
+// integrations which only know the route and the path parameters of the
+// request once the handler operation started, such as when the route is
+// matched after the middleware.
+// An error is returned if the request must be blocked because of the path
+// parameters.
+func MonitorPathParams(ctx context.Context, route string, pathParams map[string]string) error {
+	parent := fromContext(ctx)
+	if parent == nil {
+		log.Error("appsec: path parameters monitoring ignored: could not find the http handler instrumentation metadata in the request context: the request handler is not being monitored by a middleware function or the provided context is not the expected request context")
+		return nil
 	}
+	op := StartPathParamsOperation(parent, PathParamsOperationArgs{PathParams: pathParams, Route: route})
+	return op.Finish()
 }
 
 // applyActions executes the operation's actions and returns the resulting http handler
@@ -81,6 +142,9 @@ func applyActions(op *Operation) http.Handler {
 		case *BlockRequestAction:
 			op.AddTag(instrumentation.BlockedRequestTag, true)
 			return a.handler
+		case *RedirectRequestAction:
+			op.AddTag(instrumentation.BlockedRequestTag, true)
+			return a.handler
 		default:
 			log.Error("appsec: ignoring security action: unexpected action type %T", a)
 		}
@@ -89,14 +153,15 @@ func applyActions(op *Operation) http.Handler {
 }
 
 // WrapHandler wraps the given HTTP handler with the abstract HTTP operation defined by HandlerOperationArgs and
-// HandlerOperationRes.
-func WrapHandler(handler http.Handler, span ddtrace.Span, pathParams map[string]string) http.Handler {
+// HandlerOperationRes. The route is the route template of the request, or empty when unknown.
+func WrapHandler(handler http.Handler, span ddtrace.Span, pathParams map[string]string, route string) http.Handler {
 	instrumentation.SetAppSecEnabledTags(span)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ipTags, clientIP := ClientIPTags(r.Header, true, r.RemoteAddr)
 		instrumentation.SetStringTags(span, ipTags)
 
 		args := MakeHandlerOperationArgs(r, clientIP, pathParams)
+		args.Route = route
 		ctx, op := StartOperation(r.Context(), args)
 		r = r.WithContext(ctx)
 
@@ -109,7 +174,7 @@ func WrapHandler(handler http.Handler, span ddtrace.Span, pathParams map[string]
 				status = mw.Status()
 			}
 
-			events := op.Finish(HandlerOperationRes{Status: status})
+			events := op.Finish(HandlerOperationRes{Status: status, Headers: makeResponseHeaders(w.Header())})
 			if h := applyActions(op); h != nil {
 				h.ServeHTTP(w, r)
 			}
@@ -141,6 +206,7 @@ func MakeHandlerOperationArgs(r *http.Request, clientIP instrumentation.NetaddrI
 	cookies := makeCookies(r) // TODO(Julio-Guerra): avoid actively parsing the cookies thanks to dynamic instrumentation
 	headers["host"] = []string{r.Host}
 	return HandlerOperationArgs{
+		Method:     r.Method,
 		RequestURI: r.RequestURI,
 		Headers:    headers,
 		Cookies:    cookies,
@@ -150,6 +216,24 @@ func MakeHandlerOperationArgs(r *http.Request, clientIP instrumentation.NetaddrI
 	}
 }
 
+// makeResponseHeaders returns the response headers following the specification
+// of the rule address `server.response.headers.no_cookies`.
+func makeResponseHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	headers := make(map[string][]string, len(h))
+	for k, v := range h {
+		k := strings.ToLower(k)
+		if k == "set-cookie" {
+			// Do not include cookies in the response headers
+			continue
+		}
+		headers[k] = v
+	}
+	return headers
+}
+
 // Return the map of parsed cookies if any and following the specification of
 // the rule address `server.request.cookies`.
 func makeCookies(r *http.Request) map[string][]string {
@@ -181,6 +265,29 @@ type (
 	// StartSDKBodyOperation() and finished with its Finish() method.
 	SDKBodyOperation struct {
 		dyngo.Operation
+		// Error is set by the event listeners when the request must be
+		// blocked because of the body.
+		Error error
+	}
+
+	// SDKResponseBodyOperation type representing an SDK response body. It
+	// must be created with StartSDKResponseBodyOperation() and finished with
+	// its Finish() method.
+	SDKResponseBodyOperation struct {
+		dyngo.Operation
+		// Error is set by the event listeners when the request must be
+		// blocked because of the response body.
+		Error error
+	}
+
+	// PathParamsOperation type representing the monitoring of the path
+	// parameters. It must be created with StartPathParamsOperation() and
+	// finished with its Finish() method.
+	PathParamsOperation struct {
+		dyngo.Operation
+		// Error is set by the event listeners when the request must be
+		// blocked because of the path parameters.
+		Error error
 	}
 )
 
@@ -240,9 +347,39 @@ func StartSDKBodyOperation(parent *Operation, args SDKBodyOperationArgs) *SDKBod
 	return op
 }
 
-// Finish finishes the SDKBody operation and emits a finish event
-func (op *SDKBodyOperation) Finish() {
+// Finish finishes the SDKBody operation and emits a finish event. It returns
+// the operation's error, if any.
+func (op *SDKBodyOperation) Finish() error {
 	dyngo.FinishOperation(op, SDKBodyOperationRes{})
+	return op.Error
+}
+
+// StartSDKResponseBodyOperation starts the SDKResponseBody operation and emits a start event
+func StartSDKResponseBodyOperation(parent *Operation, args SDKResponseBodyOperationArgs) *SDKResponseBodyOperation {
+	op := &SDKResponseBodyOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return op
+}
+
+// Finish finishes the SDKResponseBody operation and emits a finish event. It
+// returns the operation's error, if any.
+func (op *SDKResponseBodyOperation) Finish() error {
+	dyngo.FinishOperation(op, SDKResponseBodyOperationRes{})
+	return op.Error
+}
+
+// StartPathParamsOperation starts the PathParams operation and emits a start event
+func StartPathParamsOperation(parent *Operation, args PathParamsOperationArgs) *PathParamsOperation {
+	op := &PathParamsOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, args)
+	return op
+}
+
+// Finish finishes the PathParams operation and emits a finish event. It
+// returns the operation's error, if any.
+func (op *PathParamsOperation) Finish() error {
+	dyngo.FinishOperation(op, PathParamsOperationRes{})
+	return op.Error
 }
 
 // HTTP handler operation's start and finish event callback function types.
@@ -259,6 +396,18 @@ type (
 	// OnSDKBodyOperationFinish function type, called when an SDK body
 	// operation finishes.
 	OnSDKBodyOperationFinish func(*SDKBodyOperation, SDKBodyOperationRes)
+	// OnSDKResponseBodyOperationStart function type, called when an SDK
+	// response body operation starts.
+	OnSDKResponseBodyOperationStart func(*SDKResponseBodyOperation, SDKResponseBodyOperationArgs)
+	// OnSDKResponseBodyOperationFinish function type, called when an SDK
+	// response body operation finishes.
+	OnSDKResponseBodyOperationFinish func(*SDKResponseBodyOperation, SDKResponseBodyOperationRes)
+	// OnPathParamsOperationStart function type, called when a path
+	// parameters operation starts.
+	OnPathParamsOperationStart func(*PathParamsOperation, PathParamsOperationArgs)
+	// OnPathParamsOperationFinish function type, called when a path
+	// parameters operation finishes.
+	OnPathParamsOperationFinish func(*PathParamsOperation, PathParamsOperationRes)
 )
 
 var (
@@ -266,6 +415,12 @@ var (
 	handlerOperationResType  = reflect.TypeOf((*HandlerOperationRes)(nil)).Elem()
 	sdkBodyOperationArgsType = reflect.TypeOf((*SDKBodyOperationArgs)(nil)).Elem()
 	sdkBodyOperationResType  = reflect.TypeOf((*SDKBodyOperationRes)(nil)).Elem()
+
+	sdkResponseBodyOperationArgsType = reflect.TypeOf((*SDKResponseBodyOperationArgs)(nil)).Elem()
+	sdkResponseBodyOperationResType  = reflect.TypeOf((*SDKResponseBodyOperationRes)(nil)).Elem()
+
+	pathParamsOperationArgsType = reflect.TypeOf((*PathParamsOperationArgs)(nil)).Elem()
+	pathParamsOperationResType  = reflect.TypeOf((*PathParamsOperationRes)(nil)).Elem()
 )
 
 // ListenedType returns the type a OnHandlerOperationStart event listener
@@ -308,6 +463,50 @@ func (f OnSDKBodyOperationFinish) Call(op dyngo.Operation, v interface{}) {
 	f(op.(*SDKBodyOperation), v.(SDKBodyOperationRes))
 }
 
+// ListenedType returns the type a OnSDKResponseBodyOperationStart event
+// listener listens to, which is the SDKResponseBodyOperationArgs type.
+func (OnSDKResponseBodyOperationStart) ListenedType() reflect.Type {
+	return sdkResponseBodyOperationArgsType
+}
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnSDKResponseBodyOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*SDKResponseBodyOperation), v.(SDKResponseBodyOperationArgs))
+}
+
+// ListenedType returns the type a OnSDKResponseBodyOperationFinish event
+// listener listens to, which is the SDKResponseBodyOperationRes type.
+func (OnSDKResponseBodyOperationFinish) ListenedType() reflect.Type {
+	return sdkResponseBodyOperationResType
+}
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnSDKResponseBodyOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*SDKResponseBodyOperation), v.(SDKResponseBodyOperationRes))
+}
+
+// ListenedType returns the type a OnPathParamsOperationStart event listener
+// listens to, which is the PathParamsOperationArgs type.
+func (OnPathParamsOperationStart) ListenedType() reflect.Type { return pathParamsOperationArgsType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnPathParamsOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*PathParamsOperation), v.(PathParamsOperationArgs))
+}
+
+// ListenedType returns the type a OnPathParamsOperationFinish event listener
+// listens to, which is the PathParamsOperationRes type.
+func (OnPathParamsOperationFinish) ListenedType() reflect.Type { return pathParamsOperationResType }
+
+// Call calls the underlying event listener function by performing the
+// type-assertion on v whose type is the one returned by ListenedType().
+func (f OnPathParamsOperationFinish) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*PathParamsOperation), v.(PathParamsOperationRes))
+}
+
 // blockedTemplateJSON is the default JSON template used to write responses for blocked requests
 //
 //go:embed blocked-template.json
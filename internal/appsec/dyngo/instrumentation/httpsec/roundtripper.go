@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package httpsec
+
+import (
+	"context"
+	"reflect"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+)
+
+type (
+	// RoundTripOperation type representing an outgoing HTTP request sent by an
+	// HTTP client integration. It gets both created and destroyed in a single
+	// call to ProtectRoundTrip.
+	RoundTripOperation struct {
+		dyngo.Operation
+		// Error is set by the event listeners when the outgoing request must be
+		// blocked.
+		Error error
+	}
+	// RoundTripOperationArgs is the round trip operation arguments.
+	RoundTripOperationArgs struct {
+		// URL corresponds to the address `server.io.net.url`.
+		URL string
+	}
+	// RoundTripOperationRes is the round trip operation results.
+	RoundTripOperationRes struct{}
+
+	// OnRoundTripOperationStart function type, called when a round trip
+	// operation starts.
+	OnRoundTripOperationStart func(*RoundTripOperation, RoundTripOperationArgs)
+)
+
+var roundTripOperationArgsType = reflect.TypeOf((*RoundTripOperationArgs)(nil)).Elem()
+
+// ListenedType returns the type a OnRoundTripOperationStart event listener
+// listens to, which is the RoundTripOperationArgs type.
+func (OnRoundTripOperationStart) ListenedType() reflect.Type { return roundTripOperationArgsType }
+
+// Call the underlying event listener function by performing the type-assertion
+// on v whose type is the one returned by ListenedType().
+func (f OnRoundTripOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*RoundTripOperation), v.(RoundTripOperationArgs))
+}
+
+// ProtectRoundTrip starts and finishes a round trip operation, as a child of
+// the HTTP or gRPC handler operation found in the given context, before
+// sending an outgoing request to the given URL. The security rules can then
+// correlate the URL with the incoming request data, in order to detect
+// server-side request forgeries. An error is returned if the outgoing request
+// must be blocked, which the caller must return instead of sending the
+// request. The return value is nil otherwise, and in particular when the
+// outgoing request is not sent in the context of a monitored request.
+func ProtectRoundTrip(ctx context.Context, url string) error {
+	parent, ok := ctx.Value(instrumentation.ContextKey{}).(dyngo.Operation)
+	if !ok {
+		return nil
+	}
+	op := &RoundTripOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, RoundTripOperationArgs{URL: url})
+	dyngo.FinishOperation(op, RoundTripOperationRes{})
+	return op.Error
+}
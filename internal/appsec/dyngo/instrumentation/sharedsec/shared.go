@@ -37,8 +37,21 @@ type (
 	UserMonitoringError struct {
 		error
 	}
+
+	// MonitoringError is returned to the caller of an SDK function when the
+	// monitored data triggered the blocking of the request.
+	MonitoringError struct {
+		error
+	}
 )
 
+// NewMonitoringError creates a new monitoring error that returns `msg` upon calling `Error()`
+func NewMonitoringError(msg string) *MonitoringError {
+	return &MonitoringError{
+		errors.New(msg),
+	}
+}
+
 // NewUserMonitoringError creates a new user monitoring error that returns `msg` upon calling `Error()`
 func NewUserMonitoringError(msg string) *UserMonitoringError {
 	return &UserMonitoringError{
@@ -78,3 +91,49 @@ func MonitorUser(ctx context.Context, userID string) error {
 	return nil
 
 }
+
+type (
+	// UserLoginEventOperation type representing a call to appsec.TrackUserLoginSuccessEvent() or
+	// appsec.TrackUserLoginFailureEvent(). It gets both created and destroyed in a single call to MonitorUserLoginEvent
+	UserLoginEventOperation struct {
+		dyngo.Operation
+	}
+	// UserLoginEventOperationArgs is the user login event operation arguments.
+	UserLoginEventOperationArgs struct {
+		// Success is true for login success events, and false for login failure events.
+		Success bool
+		UserID  string
+	}
+	// UserLoginEventOperationRes is the user login event operation results.
+	UserLoginEventOperationRes struct{}
+
+	// OnUserLoginEventOperationStart function type, called when a user login event operation starts.
+	OnUserLoginEventOperationStart func(operation *UserLoginEventOperation, args UserLoginEventOperationArgs)
+)
+
+var userLoginEventOperationArgsType = reflect.TypeOf((*UserLoginEventOperationArgs)(nil)).Elem()
+
+// ListenedType returns the type a OnUserLoginEventOperationStart event listener
+// listens to, which is the UserLoginEventOperationArgs type.
+func (OnUserLoginEventOperationStart) ListenedType() reflect.Type {
+	return userLoginEventOperationArgsType
+}
+
+// Call the underlying event listener function by performing the type-assertion
+// on v whose type is the one returned by ListenedType().
+func (f OnUserLoginEventOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*UserLoginEventOperation), v.(UserLoginEventOperationArgs))
+}
+
+// MonitorUserLoginEvent starts and finishes a user login event operation, so that the manually tracked user login
+// events take precedence over the automated ones. Calls without a monitored request in the given context are ignored.
+func MonitorUserLoginEvent(ctx context.Context, success bool, userID string) {
+	parent, ok := ctx.Value(instrumentation.ContextKey{}).(dyngo.Operation)
+	if !ok {
+		log.Debug("appsec: user login event monitoring ignored: could not find the http handler instrumentation metadata in the request context")
+		return
+	}
+	op := &UserLoginEventOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, UserLoginEventOperationArgs{Success: success, UserID: userID})
+	dyngo.FinishOperation(op, UserLoginEventOperationRes{})
+}
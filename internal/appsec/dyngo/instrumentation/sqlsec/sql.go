@@ -0,0 +1,71 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package sqlsec defines the SQL instrumentation API and contract for AppSec.
+// It defines an abstract representation of the execution of SQL statements,
+// which SQL integrations must use to enable the protection of the databases
+// against attacks such as SQL injections.
+package sqlsec
+
+import (
+	"context"
+	"reflect"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+)
+
+type (
+	// SQLOperation type representing the execution of a SQL statement. It
+	// gets both created and destroyed in a single call to ProtectSQLOperation.
+	SQLOperation struct {
+		dyngo.Operation
+		// Error is set by the event listeners when the execution of the
+		// statement must be blocked.
+		Error error
+	}
+	// SQLOperationArgs is the SQL operation arguments.
+	SQLOperationArgs struct {
+		// Query corresponds to the address `server.db.statement`
+		Query string
+		// Driver corresponds to the address `server.db.system`
+		Driver string
+	}
+	// SQLOperationRes is the SQL operation results.
+	SQLOperationRes struct{}
+
+	// OnSQLOperationStart function type, called when a SQL operation starts.
+	OnSQLOperationStart func(*SQLOperation, SQLOperationArgs)
+)
+
+var sqlOperationArgsType = reflect.TypeOf((*SQLOperationArgs)(nil)).Elem()
+
+// ListenedType returns the type a OnSQLOperationStart event listener
+// listens to, which is the SQLOperationArgs type.
+func (OnSQLOperationStart) ListenedType() reflect.Type { return sqlOperationArgsType }
+
+// Call the underlying event listener function by performing the type-assertion
+// on v whose type is the one returned by ListenedType().
+func (f OnSQLOperationStart) Call(op dyngo.Operation, v interface{}) {
+	f(op.(*SQLOperation), v.(SQLOperationArgs))
+}
+
+// ProtectSQLOperation starts and finishes a SQL operation, as a child of the
+// HTTP or gRPC handler operation found in the given context, before executing
+// the given query. The security rules can then correlate the query with the
+// request data, in order to detect SQL injections. An error is returned if the
+// execution of the query must be blocked, which the caller must return instead
+// of executing the query. The return value is nil otherwise, and in particular
+// when the query is not executed in the context of a monitored request.
+func ProtectSQLOperation(ctx context.Context, query, driver string) error {
+	parent, ok := ctx.Value(instrumentation.ContextKey{}).(dyngo.Operation)
+	if !ok {
+		return nil
+	}
+	op := &SQLOperation{Operation: dyngo.NewOperation(parent)}
+	dyngo.StartOperation(op, SQLOperationArgs{Query: query, Driver: driver})
+	dyngo.FinishOperation(op, SQLOperationRes{})
+	return op.Error
+}
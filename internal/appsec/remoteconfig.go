@@ -11,6 +11,7 @@ package appsec
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
@@ -141,6 +142,81 @@ func (h *wafHandleWrapper) asmDataCallback(u remoteconfig.ProductUpdate) map[str
 	return statuses
 }
 
+// asmDDCallback deserializes the security rules configurations received through remote config and updates the WAF
+// accordingly. Configurations either hold a full set of rules replacing the default ones, or rules overrides,
+// exclusion filters, custom rules and actions. The WAF handle is swapped with a new one instantiated with the resulting rules,
+// so that the ongoing requests keep being monitored with the previous rules. Used as a callback for the ASM_DD remote
+// config product.
+func (a *appsec) asmDDCallback(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	return a.updateRules(u, true)
+}
+
+// asmCallback deserializes the rules overrides, exclusion filters, custom rules and actions configured by the users
+// and received through remote config, and updates the WAF accordingly. They are merged with the base rules, which are
+// either the default ones or the ones received through the ASM_DD product. Used as a callback for the ASM remote
+// config product.
+func (a *appsec) asmCallback(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	return a.updateRules(u, false)
+}
+
+// updateRules updates the security rules with the given configurations, which can hold a full set of rules only when
+// allowBase is true, and swaps the WAF handle accordingly.
+func (a *appsec) updateRules(u remoteconfig.ProductUpdate, allowBase bool) map[string]rc.ApplyStatus {
+	statuses := make(map[string]rc.ApplyStatus, len(u))
+	updated := make(map[string]*rulesFragment, len(u))
+	for path, raw := range u {
+		log.Debug("appsec: Remote config: processing %s", path)
+		// A nil config means the configuration was removed
+		if raw == nil {
+			updated[path] = nil
+			statuses[path] = genApplyStatus(false, nil)
+			continue
+		}
+		f, err := parseRulesFragment(raw)
+		if err == nil && !allowBase && f.Rules != nil {
+			err = fmt.Errorf("unexpected full set of rules")
+		}
+		if err != nil {
+			log.Error("appsec: Remote config: error while unmarshalling %s: %v. Configuration won't be applied.", path, err)
+			statuses[path] = genApplyStatus(false, err)
+			continue
+		}
+		updated[path] = &f
+		statuses[path] = genApplyStatus(true, nil)
+	}
+
+	setError := func(err error) {
+		for path, f := range updated {
+			if f != nil {
+				statuses[path] = genApplyStatus(false, err)
+			}
+		}
+	}
+	if len(updated) == 0 {
+		// None of the configurations can be applied
+		return statuses
+	}
+	// Prevent AppSec from being stopped or started while its WAF handle is being updated
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rules, commit, err := a.rules.update(updated)
+	if err != nil {
+		log.Error("appsec: Remote config: invalid security rules update: %v. Configuration won't be applied.", err)
+		setError(err)
+		return statuses
+	}
+	if handles := a.wafHandles; handles != nil {
+		if err := handles.update(rules); err != nil {
+			log.Error("appsec: Remote config: could not instantiate the WAF with the updated security rules: %v. Configuration won't be applied.", err)
+			setError(err)
+			return statuses
+		}
+	}
+	// Otherwise, the rules will be used when AppSec starts
+	commit()
+	return statuses
+}
+
 // mergeRulesDataEntries merges two slices of rules data entries together, removing duplicates and
 // only keeping the longest expiration values for similar entries.
 func mergeRulesDataEntries(entries1, entries2 []rc.ASMDataRuleDataEntry) []rc.ASMDataRuleDataEntry {
@@ -242,3 +318,24 @@ func (a *appsec) enableRCBlocking(handle wafHandleWrapper) error {
 	a.registerRCCallback(handle.asmDataCallback, rc.ProductASMData)
 	return nil
 }
+
+func (a *appsec) enableRCRules() error {
+	if a.rc == nil {
+		return fmt.Errorf("no valid remote configuration client")
+	}
+	// The rules provided by the user take precedence over the ones managed by Datadog
+	if os.Getenv(rulesEnvVar) != "" {
+		log.Debug("appsec: Remote config: %s is set, the security rules won't be updated", rulesEnvVar)
+		return nil
+	}
+	a.registerRCProduct(rc.ProductASMDD)
+	a.registerRCProduct(rc.ProductASM)
+	a.registerRCCapability(remoteconfig.ASMDDRules)
+	a.registerRCCapability(remoteconfig.ASMExclusions)
+	a.registerRCCapability(remoteconfig.ASMRequestBlocking)
+	a.registerRCCapability(remoteconfig.ASMCustomRules)
+	a.registerRCCapability(remoteconfig.ASMCustomBlockingResponse)
+	a.registerRCCallback(a.asmDDCallback, rc.ProductASMDD)
+	a.registerRCCallback(a.asmCallback, rc.ProductASM)
+	return nil
+}
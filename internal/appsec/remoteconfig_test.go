@@ -11,6 +11,7 @@ package appsec
 import (
 	"os"
 	"testing"
+	"time"
 
 	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
 	waf "github.com/DataDog/go-libddwaf"
@@ -361,6 +362,12 @@ func TestRemoteActivationScenarios(t *testing.T) {
 		require.NotNil(t, client)
 		require.NotContains(t, client.Capabilities, remoteconfig.ASMActivation)
 		require.NotContains(t, client.Products, rc.ProductASMFeatures)
+		require.Contains(t, client.Capabilities, remoteconfig.ASMDDRules)
+		require.Contains(t, client.Capabilities, remoteconfig.ASMExclusions)
+		require.Contains(t, client.Capabilities, remoteconfig.ASMCustomRules)
+		require.Contains(t, client.Capabilities, remoteconfig.ASMCustomBlockingResponse)
+		require.Contains(t, client.Products, rc.ProductASMDD)
+		require.Contains(t, client.Products, rc.ProductASM)
 	})
 
 	t.Run("DD_APPSEC_ENABLED=false", func(t *testing.T) {
@@ -371,3 +378,164 @@ func TestRemoteActivationScenarios(t *testing.T) {
 		require.False(t, Enabled())
 	})
 }
+
+func TestASMDDCallback(t *testing.T) {
+	if waf.Health() != nil {
+		t.Skip("WAF cannot be used")
+	}
+	t.Setenv(enabledEnvVar, "true")
+	Start(WithRCConfig(remoteconfig.DefaultClientConfig()))
+	defer Stop()
+	require.True(t, Enabled())
+
+	current := func() *wafHandle {
+		return activeAppSec.wafHandles.current.Load().(*wafHandle)
+	}
+	initial := current()
+	// run returns the matches of the given URI with the current handle
+	run := func(t *testing.T, uri string) []byte {
+		wafCtx := waf.NewContext(current().Handle)
+		require.NotNil(t, wafCtx)
+		defer wafCtx.Close()
+		matches, _, err := wafCtx.Run(map[string]interface{}{"server.request.uri.raw": uri}, time.Second)
+		require.NoError(t, err)
+		return matches
+	}
+	// Simulate an ongoing request that must keep using the initial handle
+	_, wafCtx := activeAppSec.wafHandles.newContext()
+	require.NotNil(t, wafCtx)
+	defer wafCtx.Close()
+
+	t.Run("exclusion", func(t *testing.T) {
+		require.NotEmpty(t, run(t, "/../../../secret.txt"))
+		statuses := activeAppSec.asmDDCallback(remoteconfig.ProductUpdate{
+			"exclusions": []byte(`{"exclusions": [{"id": "excl-1", "rules_target": [{"tags": {"type": "lfi"}}]}]}`),
+		})
+		require.Equal(t, map[string]rc.ApplyStatus{"exclusions": {State: rc.ApplyStateAcknowledged}}, statuses)
+		require.NotSame(t, initial, current())
+		require.Empty(t, run(t, "/../../../secret.txt"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		before := current()
+		statuses := activeAppSec.asmDDCallback(remoteconfig.ProductUpdate{
+			"rules": []byte(`{"version": "2.2", "rules": [{"id": "no-conditions"}]}`),
+		})
+		require.Equal(t, rc.ApplyStateError, statuses["rules"].State)
+		require.NotEmpty(t, statuses["rules"].Error)
+		require.Same(t, before, current())
+	})
+
+	t.Run("rules-data", func(t *testing.T) {
+		// The rules data is added to the rules of the current handle and kept for the next ones
+		data := []rc.ASMDataRuleData{{ID: "blocked_ips", Type: "ip_with_expiration", Data: []rc.ASMDataRuleDataEntry{{Value: "1.2.3.4"}}}}
+		require.NoError(t, activeAppSec.wafHandles.UpdateRulesData(data))
+		blocked := func(t *testing.T) bool {
+			wafCtx := waf.NewContext(current().Handle)
+			require.NotNil(t, wafCtx)
+			defer wafCtx.Close()
+			_, actions, err := wafCtx.Run(map[string]interface{}{"http.client_ip": "1.2.3.4"}, time.Second)
+			require.NoError(t, err)
+			return len(actions) > 0
+		}
+		require.True(t, blocked(t))
+		activeAppSec.asmDDCallback(remoteconfig.ProductUpdate{
+			"custom": []byte(`{"custom_rules": [{"id": "custom-1", "name": "custom 1", "tags": {"type": "lfi", "category": "attack_attempt"}, "conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.uri.raw"}], "regex": "custom"}}]}]}`),
+		})
+		require.True(t, blocked(t))
+		require.NoError(t, activeAppSec.wafHandles.UpdateRulesData(nil))
+		require.False(t, blocked(t))
+		activeAppSec.asmDDCallback(remoteconfig.ProductUpdate{"custom": nil})
+	})
+
+	t.Run("removal", func(t *testing.T) {
+		statuses := activeAppSec.asmDDCallback(remoteconfig.ProductUpdate{"exclusions": nil})
+		require.Equal(t, rc.ApplyStateUnacknowledged, statuses["exclusions"].State)
+		require.NotEmpty(t, run(t, "/../../../secret.txt"))
+	})
+
+	// The initial handle is still usable by the ongoing request
+	matches, _, err := wafCtx.Run(map[string]interface{}{"server.request.uri.raw": "/../../../secret.txt"}, time.Second)
+	require.NoError(t, err)
+	require.NotEmpty(t, matches)
+}
+
+func TestASMCallback(t *testing.T) {
+	if waf.Health() != nil {
+		t.Skip("WAF cannot be used")
+	}
+	t.Setenv(enabledEnvVar, "true")
+	Start(WithRCConfig(remoteconfig.DefaultClientConfig()))
+	defer Stop()
+	require.True(t, Enabled())
+
+	current := func() *wafHandle {
+		return activeAppSec.wafHandles.current.Load().(*wafHandle)
+	}
+	base := []byte(`{"version": "2.2", "rules": [
+		{"id": "rule-1", "name": "rule 1", "tags": {"type": "lfi", "category": "attack_attempt"}, "conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.uri.raw"}], "regex": "secret"}}]},
+		{"id": "rule-2", "name": "rule 2", "tags": {"type": "lfi", "category": "attack_attempt"}, "conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.uri.raw"}], "regex": "private"}}]}
+	]}`)
+	statuses := activeAppSec.asmDDCallback(remoteconfig.ProductUpdate{"datadog/2/ASM_DD/rules/config": base})
+	require.Equal(t, rc.ApplyStateAcknowledged, statuses["datadog/2/ASM_DD/rules/config"].State)
+	require.EqualValues(t, 2, current().RulesetInfo().Loaded)
+
+	// run returns the matches and actions of the given URI with the current handle
+	run := func(t *testing.T, uri string) ([]byte, []string) {
+		wafCtx := waf.NewContext(current().Handle)
+		require.NotNil(t, wafCtx)
+		defer wafCtx.Close()
+		matches, actions, err := wafCtx.Run(map[string]interface{}{"server.request.uri.raw": uri}, time.Second)
+		require.NoError(t, err)
+		return matches, actions
+	}
+
+	t.Run("exclusion", func(t *testing.T) {
+		statuses := activeAppSec.asmCallback(remoteconfig.ProductUpdate{
+			"datadog/2/ASM/exclusions/config": []byte(`{"exclusions": [{"id": "excl-1", "rules_target": [{"rule_id": "rule-1"}], "conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.uri.raw"}], "regex": "^/public/"}}]}]}`),
+		})
+		require.Equal(t, rc.ApplyStateAcknowledged, statuses["datadog/2/ASM/exclusions/config"].State)
+		// the conditional exclusion applies to the ASM_DD rules
+		matches, _ := run(t, "/public/secret")
+		require.Empty(t, matches)
+		matches, _ = run(t, "/secret")
+		require.NotEmpty(t, matches)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		statuses := activeAppSec.asmCallback(remoteconfig.ProductUpdate{
+			"datadog/2/ASM/overrides/config": []byte(`{"rules_override": [{"rules_target": [{"rule_id": "rule-2"}], "on_match": ["block"]}]}`),
+		})
+		require.Equal(t, rc.ApplyStateAcknowledged, statuses["datadog/2/ASM/overrides/config"].State)
+		_, actions := run(t, "/private")
+		require.Equal(t, []string{"block"}, actions)
+	})
+
+	t.Run("custom-rules", func(t *testing.T) {
+		statuses := activeAppSec.asmCallback(remoteconfig.ProductUpdate{
+			"datadog/2/ASM/custom/config": []byte(`{"custom_rules": [{"id": "custom-1", "name": "custom 1", "tags": {"type": "lfi", "category": "attack_attempt"}, "conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.uri.raw"}], "regex": "custom"}}]}]}`),
+		})
+		require.Equal(t, rc.ApplyStateAcknowledged, statuses["datadog/2/ASM/custom/config"].State)
+		require.EqualValues(t, 3, current().RulesetInfo().Loaded)
+	})
+
+	t.Run("full-rules", func(t *testing.T) {
+		before := current()
+		statuses := activeAppSec.asmCallback(remoteconfig.ProductUpdate{"datadog/2/ASM/rules/config": base})
+		require.Equal(t, rc.ApplyStateError, statuses["datadog/2/ASM/rules/config"].State)
+		require.Same(t, before, current())
+	})
+
+	t.Run("removal", func(t *testing.T) {
+		activeAppSec.asmCallback(remoteconfig.ProductUpdate{
+			"datadog/2/ASM/exclusions/config": nil,
+			"datadog/2/ASM/overrides/config":  nil,
+			"datadog/2/ASM/custom/config":     nil,
+		})
+		require.EqualValues(t, 2, current().RulesetInfo().Loaded)
+		matches, _ := run(t, "/public/secret")
+		require.NotEmpty(t, matches)
+		_, actions := run(t, "/private")
+		require.Empty(t, actions)
+	})
+}
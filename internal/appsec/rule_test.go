@@ -9,6 +9,7 @@
 package appsec
 
 import (
+	"encoding/json"
 	"testing"
 
 	waf "github.com/DataDog/go-libddwaf"
@@ -24,3 +25,168 @@ func TestStaticRule(t *testing.T) {
 	require.NoError(t, err)
 	waf.Close()
 }
+
+func TestCompileRules(t *testing.T) {
+	const defaultRules = `{
+	"version": "2.2",
+	"metadata": {"rules_version": "1.0.0"},
+	"rules": [
+		{"id": "rule-1", "tags": {"type": "lfi", "category": "attack_attempt"}},
+		{"id": "rule-2", "tags": {"type": "sqli", "category": "attack_attempt"}},
+		{"id": "rule-3", "tags": {"type": "sqli", "category": "attack_attempt"}}
+	],
+	"rules_data": []
+}`
+	parse := func(t *testing.T, data string) *rulesFragment {
+		f, err := parseRulesFragment([]byte(data))
+		require.NoError(t, err)
+		return &f
+	}
+	// ruleIDs returns the IDs of the compiled rules
+	ruleIDs := func(t *testing.T, rules []byte) []string {
+		var ruleset struct {
+			Version string                   `json:"version"`
+			Rules   []map[string]interface{} `json:"rules"`
+		}
+		require.NoError(t, json.Unmarshal(rules, &ruleset))
+		require.Equal(t, "2.2", ruleset.Version)
+		ids := make([]string, 0, len(ruleset.Rules))
+		for _, r := range ruleset.Rules {
+			ids = append(ids, r["id"].(string))
+		}
+		return ids
+	}
+	// edits returns the overrides and exclusions of the compiled rules
+	edits := func(t *testing.T, rules []byte) (overrides, exclusions []string) {
+		var ruleset struct {
+			Overrides  []json.RawMessage `json:"rules_override"`
+			Exclusions []json.RawMessage `json:"exclusions"`
+		}
+		require.NoError(t, json.Unmarshal(rules, &ruleset))
+		for _, o := range ruleset.Overrides {
+			overrides = append(overrides, string(o))
+		}
+		for _, e := range ruleset.Exclusions {
+			exclusions = append(exclusions, string(e))
+		}
+		return overrides, exclusions
+	}
+
+	t.Run("default", func(t *testing.T) {
+		m := newRulesManager([]byte(defaultRules))
+		rules, err := m.compile()
+		require.NoError(t, err)
+		require.Equal(t, defaultRules, string(rules))
+	})
+
+	t.Run("edits", func(t *testing.T) {
+		m := newRulesManager([]byte(defaultRules))
+		rules, commit, err := m.update(map[string]*rulesFragment{
+			"overrides":  parse(t, `{"rules_override": [{"rules_target": [{"rule_id": "rule-1"}], "on_match": ["block"]}, {"id": "rule-2", "enabled": false}]}`),
+			"exclusions": parse(t, `{"exclusions": [{"id": "excl-1", "rules_target": [{"tags": {"type": "sqli"}}], "conditions": [{"operator": "match_regex", "parameters": {"inputs": [{"address": "server.request.uri.raw"}], "regex": "^/admin"}}]}]}`),
+			"custom":     parse(t, `{"custom_rules": [{"id": "custom-1", "tags": {"type": "custom"}}]}`),
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"rule-1", "rule-2", "rule-3", "custom-1"}, ruleIDs(t, rules))
+		// The overrides and exclusions are left as-is for the WAF to apply
+		overrides, exclusions := edits(t, rules)
+		require.Equal(t, []string{
+			`{"rules_target":[{"rule_id":"rule-1"}],"on_match":["block"]}`,
+			`{"id":"rule-2","enabled":false}`,
+		}, overrides)
+		require.Equal(t, []string{
+			`{"id":"excl-1","rules_target":[{"tags":{"type":"sqli"}}],"conditions":[{"operator":"match_regex","parameters":{"inputs":[{"address":"server.request.uri.raw"}],"regex":"^/admin"}}]}`,
+		}, exclusions)
+
+		// The manager is only updated once committed
+		current, err := m.compile()
+		require.NoError(t, err)
+		require.Equal(t, defaultRules, string(current))
+		commit()
+		current, err = m.compile()
+		require.NoError(t, err)
+		require.Equal(t, rules, current)
+
+		rules, commit, err = m.update(map[string]*rulesFragment{"exclusions": nil})
+		require.NoError(t, err)
+		commit()
+		overrides, exclusions = edits(t, rules)
+		require.Len(t, overrides, 2)
+		require.Empty(t, exclusions)
+	})
+
+	t.Run("base-rules", func(t *testing.T) {
+		m := newRulesManager([]byte(defaultRules))
+		rules, commit, err := m.update(map[string]*rulesFragment{
+			"base":      parse(t, `{"version": "2.2", "metadata": {"rules_version": "1.1.0"}, "rules": [{"id": "rule-4"}], "exclusions": [{"id": "excl-base"}]}`),
+			"overrides": parse(t, `{"rules_override": [{"id": "rule-4", "on_match": ["block"]}]}`),
+		})
+		require.NoError(t, err)
+		commit()
+		require.Equal(t, []string{"rule-4"}, ruleIDs(t, rules))
+		overrides, exclusions := edits(t, rules)
+		require.Equal(t, []string{`{"id":"rule-4","on_match":["block"]}`}, overrides)
+		require.Equal(t, []string{`{"id":"excl-base"}`}, exclusions)
+
+		// Only one full set of rules can be used at a time
+		_, _, err = m.update(map[string]*rulesFragment{
+			"other-base": parse(t, `{"version": "2.2", "rules": [{"id": "rule-5"}]}`),
+		})
+		require.Error(t, err)
+
+		// Removing the base rules restores the default ones
+		rules, commit, err = m.update(map[string]*rulesFragment{"base": nil})
+		require.NoError(t, err)
+		commit()
+		require.Equal(t, []string{"rule-1", "rule-2", "rule-3"}, ruleIDs(t, rules))
+		_, exclusions = edits(t, rules)
+		require.Empty(t, exclusions)
+	})
+
+	t.Run("actions", func(t *testing.T) {
+		actions := func(t *testing.T, rules []byte) []actionDefinition {
+			actions, err := readActionDefinitions(rules)
+			require.NoError(t, err)
+			return actions
+		}
+		m := newRulesManager([]byte(defaultRules))
+		rules, commit, err := m.update(map[string]*rulesFragment{
+			"base":    parse(t, `{"version": "2.2", "rules": [{"id": "rule-4"}], "actions": [{"id": "block", "type": "block_request", "parameters": {"status_code": 401}}]}`),
+			"actions": parse(t, `{"actions": [{"id": "block", "type": "block_request", "parameters": {"status_code": 418, "type": "json"}}, {"id": "redirect", "type": "redirect_request", "parameters": {"location": "/blocked"}}]}`),
+		})
+		require.NoError(t, err)
+		commit()
+		require.Equal(t, []string{"rule-4"}, ruleIDs(t, rules))
+		require.Equal(t, []actionDefinition{
+			{ID: "block", Type: blockRequestActionType, Parameters: actionParameters{StatusCode: 418, Type: "json"}},
+			{ID: "redirect", Type: redirectRequestActionType, Parameters: actionParameters{Location: "/blocked"}},
+		}, actions(t, rules))
+
+		// Removing the actions restores the ones of the base rules
+		rules, commit, err = m.update(map[string]*rulesFragment{"actions": nil})
+		require.NoError(t, err)
+		commit()
+		require.Equal(t, []actionDefinition{
+			{ID: "block", Type: blockRequestActionType, Parameters: actionParameters{StatusCode: 401}},
+		}, actions(t, rules))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, data := range []string{
+			`{"actions": [{"type": "block_request"}]}`,
+			`{"actions": [{"id": "redirect", "type": "redirect_request", "parameters": {"status_code": 302}}]}`,
+			`{"actions": [{"id": "block", "type": "block_request", "parameters": {"type": "xml"}}]}`,
+			`{"actions": [{"id": "block", "type": "block_request", "parameters": {"grpc_status_code": 42}}]}`,
+			`{"actions": [{"id": "block", "type": "block_request", "parameters": {"status_code": -1}}]}`,
+			`{"actions": [{"id": "block", "type": "block_request", "parameters": {"status_code": 1000}}]}`,
+			`{"actions": [{"id": "redirect", "type": "redirect_request", "parameters": {"status_code": 99, "location": "/blocked"}}]}`,
+			`{"rules_override": 1}`,
+			`{"exclusions": {}}`,
+			`{"custom_rules": [{"name": "no id"}]}`,
+			`{"rules": 1}`,
+		} {
+			_, err := parseRulesFragment([]byte(data))
+			require.Error(t, err, data)
+		}
+	})
+}
@@ -8,10 +8,207 @@
 
 package appsec
 
-import _ "embed"
+import (
+	// Blank import needed to use embed for the default rules
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
 
 // Static recommended AppSec rule 1.5.1
 // Source: https://github.com/DataDog/appsec-event-rules/blob/1.5.1/build/recommended.json
 //
 //go:embed rules.json
 var staticRecommendedRules string
+
+// rulesFragment is a security rules configuration, as received through the ASM_DD or ASM remote config products. It
+// either holds a full set of rules replacing the base ones, or edits of the current rules, or both. Only the ASM_DD
+// configurations can hold a full set of rules.
+type rulesFragment struct {
+	// Rules, when not nil, replaces the base rules along with the other top-level fields of the ruleset, such as
+	// its version and metadata.
+	Rules []map[string]interface{} `json:"rules,omitempty"`
+	// Overrides enables, disables or changes the action of existing rules. They are passed as-is to the WAF.
+	Overrides []json.RawMessage `json:"rules_override,omitempty"`
+	// Exclusions excludes rules from being evaluated, possibly only under some conditions or for some inputs. They
+	// are passed as-is to the WAF.
+	Exclusions []json.RawMessage `json:"exclusions,omitempty"`
+	// CustomRules are user-defined rules added to the base ones.
+	CustomRules []map[string]interface{} `json:"custom_rules,omitempty"`
+	// Actions define or redefine the actions the rules refer to in their on_match field.
+	Actions []actionDefinition `json:"actions,omitempty"`
+	// ruleset holds the other top-level fields of a full set of rules.
+	ruleset map[string]json.RawMessage
+}
+
+// parseRulesFragment parses and validates the given rules configuration.
+func parseRulesFragment(data []byte) (rulesFragment, error) {
+	var f rulesFragment
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, err
+	}
+	if f.Rules != nil {
+		if err := json.Unmarshal(data, &f.ruleset); err != nil {
+			return f, err
+		}
+		for _, key := range []string{"rules", "rules_override", "exclusions", "custom_rules", "actions"} {
+			delete(f.ruleset, key)
+		}
+	}
+	for _, r := range f.CustomRules {
+		if id, _ := r["id"].(string); id == "" {
+			return f, errors.New("custom rule without id")
+		}
+	}
+	for _, a := range f.Actions {
+		if err := a.validate(); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// rulesManager builds the security rules from the base rules and the rules configurations received through remote
+// config, keyed by their remote config path.
+type rulesManager struct {
+	mu sync.Mutex
+	// defaultRules are the rules AppSec was configured with.
+	defaultRules []byte
+	// basePath is the remote config path of the fragment holding the base rules, if any.
+	basePath  string
+	fragments map[string]rulesFragment
+}
+
+func newRulesManager(defaultRules []byte) *rulesManager {
+	return &rulesManager{
+		defaultRules: defaultRules,
+		fragments:    map[string]rulesFragment{},
+	}
+}
+
+// compile returns the security rules to instantiate the WAF with.
+func (m *rulesManager) compile() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return compileRules(m.defaultRules, m.basePath, m.fragments)
+}
+
+// update returns the security rules resulting from the given updated and removed (nil) fragments, along with a
+// function committing them to the manager. The manager is left unchanged when an error is returned, or when the
+// returned rules can't be used.
+func (m *rulesManager) update(updated map[string]*rulesFragment) ([]byte, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	basePath := m.basePath
+	fragments := make(map[string]rulesFragment, len(m.fragments)+len(updated))
+	for path, f := range m.fragments {
+		fragments[path] = f
+	}
+	for path, f := range updated {
+		if f == nil {
+			delete(fragments, path)
+			if path == basePath {
+				basePath = ""
+			}
+			continue
+		}
+		if f.Rules != nil {
+			if basePath != "" && basePath != path {
+				if _, updated := updated[basePath]; !updated {
+					return nil, nil, fmt.Errorf("a full set of rules was already received from %s", basePath)
+				}
+			}
+			basePath = path
+		}
+		fragments[path] = *f
+	}
+	rules, err := compileRules(m.defaultRules, basePath, fragments)
+	if err != nil {
+		return nil, nil, err
+	}
+	commit := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.basePath = basePath
+		m.fragments = fragments
+	}
+	return rules, commit, nil
+}
+
+// compileRules merges the base rules, which are either the default ones or the ones from the fragment at basePath,
+// with the custom rules, overrides, exclusions and actions of the fragments. The overrides and exclusions are left
+// to the WAF to apply.
+func compileRules(defaultRules []byte, basePath string, fragments map[string]rulesFragment) ([]byte, error) {
+	if basePath == "" && len(fragments) == 0 {
+		return defaultRules, nil
+	}
+	base, ok := fragments[basePath]
+	if !ok {
+		var err error
+		if base, err = parseRulesFragment(defaultRules); err != nil {
+			return nil, fmt.Errorf("invalid default rules: %v", err)
+		}
+	}
+	// Apply the fragments in a deterministic order
+	paths := make([]string, 0, len(fragments))
+	for path := range fragments {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	rules := append([]map[string]interface{}{}, base.Rules...)
+	overrides := append([]json.RawMessage{}, base.Overrides...)
+	exclusions := append([]json.RawMessage{}, base.Exclusions...)
+	for _, path := range paths {
+		f := fragments[path]
+		rules = append(rules, f.CustomRules...)
+		if path != basePath {
+			overrides = append(overrides, f.Overrides...)
+			exclusions = append(exclusions, f.Exclusions...)
+		}
+	}
+	// Merge the actions by ID, the fragments redefining the base ones
+	var actions []actionDefinition
+	actionIndex := make(map[string]int)
+	for _, fragmentActions := range append([][]actionDefinition{base.Actions}, fragmentsActions(paths, fragments)...) {
+		for _, a := range fragmentActions {
+			if i, ok := actionIndex[a.ID]; ok {
+				actions[i] = a
+				continue
+			}
+			actionIndex[a.ID] = len(actions)
+			actions = append(actions, a)
+		}
+	}
+
+	ruleset := make(map[string]interface{}, len(base.ruleset)+4)
+	for k, v := range base.ruleset {
+		ruleset[k] = v
+	}
+	ruleset["rules"] = rules
+	if len(overrides) > 0 {
+		ruleset["rules_override"] = overrides
+	}
+	if len(exclusions) > 0 {
+		ruleset["exclusions"] = exclusions
+	}
+	if len(actions) > 0 {
+		ruleset["actions"] = actions
+	}
+	return json.Marshal(ruleset)
+}
+
+// fragmentsActions returns the actions of the fragments at the given paths, except the base one whose actions are
+// already the base actions.
+func fragmentsActions(paths []string, fragments map[string]rulesFragment) [][]actionDefinition {
+	actions := make([][]actionDefinition, 0, len(paths))
+	for _, path := range paths {
+		if f := fragments[path]; f.Rules == nil {
+			actions = append(actions, f.Actions)
+		}
+	}
+	return actions
+}
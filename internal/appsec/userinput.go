@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+//go:build appsec
+// +build appsec
+
+package appsec
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+)
+
+// userInputs collects the string values of the request data controlled by the
+// client, such as its query and path parameters, headers, cookies and body.
+// The security events detected in SQL statements are only kept when the
+// matched fragments come from these values, so that the queries hard-coded
+// in the application are never blocked.
+type userInputs struct {
+	mu     sync.Mutex
+	values []string // lowercased
+}
+
+// maxUserInputDepth limits the depth of the values walked by userInputs.add.
+const maxUserInputDepth = 10
+
+// add collects the string values found in v.
+func (u *userInputs) add(v interface{}) {
+	if v == nil {
+		return
+	}
+	var values []string
+	walkStrings(reflect.ValueOf(v), maxUserInputDepth, func(s string) {
+		if s != "" {
+			values = append(values, strings.ToLower(s))
+		}
+	})
+	if len(values) == 0 {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.values = append(u.values, values...)
+}
+
+// contains returns true when fragment is part of one of the collected values.
+func (u *userInputs) contains(fragment string) bool {
+	fragment = strings.ToLower(fragment)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, v := range u.values {
+		if strings.Contains(v, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkStrings calls fn with every string found in v, including map keys and
+// exported struct fields, up to the given depth.
+func walkStrings(v reflect.Value, depth int, fn func(string)) {
+	if depth <= 0 || !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		fn(v.String())
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), depth-1, fn)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// byte slices, such as raw request bodies
+			if v.Kind() == reflect.Slice {
+				fn(string(v.Bytes()))
+			}
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), depth-1, fn)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walkStrings(iter.Key(), depth-1, fn)
+			walkStrings(iter.Value(), depth-1, fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				walkStrings(v.Field(i), depth-1, fn)
+			}
+		}
+	}
+}
+
+// wafEvent is the subset of a WAF security event needed to correlate it with
+// the user inputs.
+type wafEvent struct {
+	Rule struct {
+		OnMatch []string `json:"on_match"`
+	} `json:"rule"`
+	RuleMatches []struct {
+		Parameters []struct {
+			Address   string   `json:"address"`
+			Highlight []string `json:"highlight"`
+		} `json:"parameters"`
+	} `json:"rule_matches"`
+}
+
+// filterSQLMatches returns the WAF security events of matches whose SQL
+// statement highlights all come from the user inputs, along with the ids of
+// the actions of their rules. Nothing is returned when no events are left.
+func filterSQLMatches(matches []byte, inputs *userInputs) ([]byte, []string) {
+	var events []json.RawMessage
+	if err := json.Unmarshal(matches, &events); err != nil {
+		log.Error("appsec: unexpected waf events format: %v", err)
+		return nil, nil
+	}
+	var (
+		kept    []json.RawMessage
+		actions []string
+	)
+	for _, raw := range events {
+		var event wafEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			log.Error("appsec: unexpected waf event format: %v", err)
+			continue
+		}
+		if !fromUserInputs(event, inputs) {
+			continue
+		}
+		kept = append(kept, raw)
+		actions = append(actions, event.Rule.OnMatch...)
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+	filtered, err := json.Marshal(kept)
+	if err != nil {
+		log.Error("appsec: unexpected error while encoding the waf events: %v", err)
+		return nil, nil
+	}
+	return filtered, actions
+}
+
+// fromUserInputs returns true when the SQL statement fragments matched by the
+// event are found in the user inputs.
+func fromUserInputs(event wafEvent, inputs *userInputs) bool {
+	for _, match := range event.RuleMatches {
+		for _, param := range match.Parameters {
+			if param.Address != serverDBStatementAddr {
+				continue
+			}
+			if len(param.Highlight) == 0 {
+				return false
+			}
+			for _, h := range param.Highlight {
+				if !inputs.contains(h) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -19,12 +19,16 @@ import (
 
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/graphqlsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/grpcsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/httpsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/sharedsec"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/appsec/dyngo/instrumentation/sqlsec"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/samplernames"
 
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
 	waf "github.com/DataDog/go-libddwaf"
 )
 
@@ -46,70 +50,224 @@ func (a *appsec) registerWAF() (unreg dyngo.UnregisterFunc, err error) {
 		return nil, err
 	}
 
-	// Instantiate the WAF
-	waf, err := waf.NewHandle(a.cfg.rules, a.cfg.obfuscator.KeyRegex, a.cfg.obfuscator.ValueRegex)
+	// Instantiate the WAF with the security rules, including the ones received through remote config, if any
+	rules, err := a.rules.compile()
+	if err != nil {
+		return nil, err
+	}
+	handles, err := newWAFHandleSwapper(rules, a.cfg.obfuscator)
+	if err != nil {
+		return nil, err
+	}
+
+	// Register the WAF event listeners. They run the WAF handle that is current when an operation starts, so that
+	// the WAF handle can be swapped when the security rules get updated.
+	listeners := []dyngo.EventListener{
+		newHTTPWAFEventListener(handles, a.cfg.wafTimeout, a.limiter),
+		newGRPCWAFEventListener(handles, a.cfg.wafTimeout, a.limiter),
+	}
+	if a.cfg.apiSec.Enabled {
+		listeners = append(listeners, newAPISecEventListener(newAPISecSampler(a.cfg.apiSec.SampleDelay)))
+	}
+	if a.cfg.autoUserEvents.Mode != AutoUserEventsDisabled {
+		listeners = append(listeners, newAutoUserEventsListener(a.cfg.autoUserEvents))
+	}
+	unregister := dyngo.Register(listeners...)
+	a.mu.Lock()
+	a.wafHandles = handles
+	a.mu.Unlock()
+
+	if err := a.enableRCBlocking(wafHandleWrapper{handles}); err != nil {
+		log.Error("appsec: Remote config: cannot enable blocking, rules data won't be updated: %v", err)
+	}
+	if err := a.enableRCRules(); err != nil {
+		log.Error("appsec: Remote config: cannot enable security rules updates: %v", err)
+	}
+
+	// Return an unregistration function that will also release the WAF instance.
+	return func() {
+		defer handles.close()
+		unregister()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.wafHandles = nil
+	}, nil
+}
+
+// wafHandle is a WAF handle along with the rule addresses it listens to.
+type wafHandle struct {
+	*waf.Handle
+	httpAddresses []string
+	grpcAddresses []string
+	// Actions handlers holding the actions defined by the rules
+	httpActions *httpsec.ActionsHandler
+	grpcActions *grpcsec.ActionsHandler
+	// Used to add the rules monitoring tags once per WAF handle and protocol
+	monitorHTTPRulesOnce sync.Once
+	monitorGRPCRulesOnce sync.Once
+}
+
+// newWAFHandle instantiates the WAF with the given security rules. An error is returned when the rules are invalid
+// or don't contain any supported address.
+func newWAFHandle(rules []byte, obfuscator ObfuscatorConfig) (*wafHandle, error) {
+	actions, err := readActionDefinitions(rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid actions: %v", err)
+	}
+	handle, err := waf.NewHandle(rules, obfuscator.KeyRegex, obfuscator.ValueRegex)
 	if err != nil {
 		return nil, err
 	}
-	// Close the WAF in case of an error in what's following
-	defer func() {
-		if err != nil {
-			waf.Close()
-		}
-	}()
 
 	// Check if there are addresses in the rule
-	ruleAddresses := waf.Addresses()
+	ruleAddresses := handle.Addresses()
 	if len(ruleAddresses) == 0 {
+		handle.Close()
 		return nil, errors.New("no addresses found in the rule")
 	}
 	// Check there are supported addresses in the rule
 	httpAddresses, grpcAddresses, notSupported := supportedAddresses(ruleAddresses)
 	if len(httpAddresses) == 0 && len(grpcAddresses) == 0 {
+		handle.Close()
 		return nil, fmt.Errorf("the addresses present in the rule are not supported: %v", notSupported)
 	} else if len(notSupported) > 0 {
 		log.Debug("appsec: the addresses present in the rule are partially supported: not supported=%v", notSupported)
 	}
+	log.Debug("appsec: waf listening to http addresses %v and grpc addresses %v", httpAddresses, grpcAddresses)
+	httpActions, grpcActions := newActionsHandlers(actions)
+	return &wafHandle{
+		Handle:        handle,
+		httpAddresses: httpAddresses,
+		grpcAddresses: grpcAddresses,
+		httpActions:   httpActions,
+		grpcActions:   grpcActions,
+	}, nil
+}
+
+// wafHandleSwapper holds the WAF handle in use, which can be atomically swapped with a new one when the security
+// rules or the rules data get updated. The WAF contexts that were created with the previous handle keep it alive until
+// they are closed, so that ongoing requests are still monitored.
+type wafHandleSwapper struct {
+	current atomic.Value // *wafHandle
+	// mu serializes the handle swaps
+	mu         sync.Mutex
+	obfuscator ObfuscatorConfig
+	// rules are the security rules of the current handle
+	rules []byte
+	// rulesData is the last rules data received, which is added to the rules of the new handles as well
+	rulesData []rc.ASMDataRuleData
+}
+
+func newWAFHandleSwapper(rules []byte, obfuscator ObfuscatorConfig) (*wafHandleSwapper, error) {
+	handle, err := newWAFHandle(rules, obfuscator)
+	if err != nil {
+		return nil, err
+	}
+	s := &wafHandleSwapper{obfuscator: obfuscator, rules: rules}
+	s.current.Store(handle)
+	return s, nil
+}
 
-	// Register the WAF event listener
-	var unregisterHTTP, unregisterGRPC dyngo.UnregisterFunc
-	if len(httpAddresses) > 0 {
-		log.Debug("appsec: registering http waf listening to addresses %v", httpAddresses)
-		unregisterHTTP = dyngo.Register(newHTTPWAFEventListener(waf, httpAddresses, a.cfg.wafTimeout, a.limiter))
+// newContext returns a new WAF context along with the WAF handle it was created with. It returns a nil context when
+// the WAF got released.
+func (s *wafHandleSwapper) newContext() (*wafHandle, *waf.Context) {
+	for {
+		handle := s.current.Load().(*wafHandle)
+		if wafCtx := waf.NewContext(handle.Handle); wafCtx != nil {
+			return handle, wafCtx
+		}
+		// The handle was concurrently released: try again with the new one if it was swapped
+		if s.current.Load().(*wafHandle) == handle {
+			return handle, nil
+		}
 	}
-	if len(grpcAddresses) > 0 {
-		log.Debug("appsec: registering grpc waf listening to addresses %v", grpcAddresses)
-		unregisterGRPC = dyngo.Register(newGRPCWAFEventListener(waf, grpcAddresses, a.cfg.wafTimeout, a.limiter))
+}
+
+// update swaps the current WAF handle with a new one instantiated with the given security rules, along with the
+// current rules data. The current handle is left unchanged when the new one can't be instantiated.
+func (s *wafHandleSwapper) update(rules []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.swap(rules, s.rulesData); err != nil {
+		return err
 	}
+	s.rules = rules
+	return nil
+}
 
-	if err := a.enableRCBlocking(wafHandleWrapper{waf}); err != nil {
-		log.Error("appsec: Remote config: cannot enable blocking, rules data won't be updated: %v", err)
+// UpdateRulesData swaps the current WAF handle with a new one instantiated with the current security rules along with
+// the given rules data, which is retained for the next handles.
+func (s *wafHandleSwapper) UpdateRulesData(data []rc.ASMDataRuleData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.swap(s.rules, data); err != nil {
+		return err
 	}
+	s.rulesData = data
+	return nil
+}
 
-	// Return an unregistration function that will also release the WAF instance.
-	return func() {
-		defer waf.Close()
-		if unregisterHTTP != nil {
-			unregisterHTTP()
+// swap replaces the current WAF handle with a new one instantiated with the given rules and rules data. The previous
+// handle is released once it's no longer used by any WAF context. It must be called with s.mu held.
+func (s *wafHandleSwapper) swap(rules []byte, data []rc.ASMDataRuleData) error {
+	if data != nil {
+		var err error
+		if rules, err = withRulesData(rules, data); err != nil {
+			return err
 		}
-		if unregisterGRPC != nil {
-			unregisterGRPC()
-		}
-	}, nil
+	}
+	handle, err := newWAFHandle(rules, s.obfuscator)
+	if err != nil {
+		return err
+	}
+	prev := s.current.Load().(*wafHandle)
+	s.current.Store(handle)
+	prev.Close()
+	return nil
 }
 
-// newWAFEventListener returns the WAF event listener to register in order to enable it.
-func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout time.Duration, limiter Limiter) dyngo.EventListener {
-	var monitorRulesOnce sync.Once // per instantiation
-	actionHandler := httpsec.NewActionsHandler()
+// withRulesData returns the given security rules with their rules data replaced by data.
+func withRulesData(rules []byte, data []rc.ASMDataRuleData) ([]byte, error) {
+	var ruleset map[string]json.RawMessage
+	if err := json.Unmarshal(rules, &ruleset); err != nil {
+		return nil, err
+	}
+	rulesData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	ruleset["rules_data"] = rulesData
+	return json.Marshal(ruleset)
+}
 
+// close releases the current WAF handle.
+func (s *wafHandleSwapper) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.Load().(*wafHandle).Close()
+}
+
+// newWAFEventListener returns the WAF event listener to register in order to enable it.
+func newHTTPWAFEventListener(handles *wafHandleSwapper, timeout time.Duration, limiter Limiter) dyngo.EventListener {
 	return httpsec.OnHandlerOperationStart(func(op *httpsec.Operation, args httpsec.HandlerOperationArgs) {
-		var body interface{}
-		wafCtx := waf.NewContext(handle)
+		handle, wafCtx := handles.newContext()
 		if wafCtx == nil {
 			// The WAF event listener got concurrently released
 			return
 		}
+		addresses := handle.httpAddresses
+		actionHandler := handle.httpActions
+		if len(addresses) == 0 {
+			wafCtx.Close()
+			return
+		}
+
+		// The request data controlled by the client, correlated with the SQL statements executed by the handler
+		var inputs userInputs
+		inputs.add(args.Query)
+		inputs.add(args.PathParams)
+		inputs.add(args.Headers)
+		inputs.add(args.Cookies)
 
 		// OnUserIDOperationStart happens when appsec.SetUser() is called. We run the WAF and apply actions to
 		// see if the associated user should be blocked. Since we don't control the execution flow in this case
@@ -133,16 +291,34 @@ func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			}
 		}))
 
-		values := map[string]interface{}{}
+		// Run the WAF on the rule addresses available when the request begins
+		values := make(map[string]interface{}, len(addresses))
 		for _, addr := range addresses {
 			switch addr {
 			case httpClientIPAddr:
 				if args.ClientIP.IsValid() {
 					values[httpClientIPAddr] = args.ClientIP.String()
 				}
+			case serverRequestRawURIAddr:
+				values[serverRequestRawURIAddr] = args.RequestURI
+			case serverRequestHeadersNoCookiesAddr:
+				if headers := args.Headers; headers != nil {
+					values[serverRequestHeadersNoCookiesAddr] = headers
+				}
+			case serverRequestCookiesAddr:
+				if cookies := args.Cookies; cookies != nil {
+					values[serverRequestCookiesAddr] = cookies
+				}
+			case serverRequestQueryAddr:
+				if query := args.Query; query != nil {
+					values[serverRequestQueryAddr] = query
+				}
+			case serverRequestPathParamsAddr:
+				if pathParams := args.PathParams; pathParams != nil {
+					values[serverRequestPathParamsAddr] = pathParams
+				}
 			}
 		}
-		// TODO: suspicious request blocking by moving here all the addresses available when the request begins
 
 		matches, actionIds := runWAF(wafCtx, values, timeout)
 		if len(matches) > 0 {
@@ -150,7 +326,10 @@ func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			for _, id := range actionIds {
 				interrupt = actionHandler.Apply(id, op) || interrupt
 			}
-			op.AddSecurityEvents(matches)
+			// Blocked requests are always reported, while the other ones are subject to the rate limiter
+			if interrupt || limiter.Allow() {
+				op.AddSecurityEvents(matches)
+			}
 			log.Debug("appsec: WAF detected an attack before executing the request")
 			if interrupt {
 				wafCtx.Close()
@@ -158,42 +337,189 @@ func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			}
 		}
 
-		op.On(httpsec.OnSDKBodyOperationStart(func(op *httpsec.SDKBodyOperation, args httpsec.SDKBodyOperationArgs) {
-			body = args.Body
+		// OnSDKBodyOperationStart happens when appsec.MonitorParsedHTTPBody() is called. As for the user ID
+		// operation, interrupting the handler is delegated to the caller through the operation error.
+		op.On(httpsec.OnSDKBodyOperationStart(func(sdkBodyOp *httpsec.SDKBodyOperation, args httpsec.SDKBodyOperationArgs) {
+			inputs.add(args.Body)
+			values := map[string]interface{}{}
+			for _, addr := range addresses {
+				if addr == serverRequestBodyAddr && args.Body != nil {
+					values[serverRequestBodyAddr] = args.Body
+				}
+			}
+			matches, actionIds := runWAF(wafCtx, values, timeout)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					if actionHandler.Apply(id, op) {
+						sdkBodyOp.Error = sharedsec.NewMonitoringError("Request blocked")
+					}
+				}
+				if sdkBodyOp.Error != nil || limiter.Allow() {
+					op.AddSecurityEvents(matches)
+				}
+				log.Debug("appsec: WAF detected a suspicious request body")
+			}
 		}))
 
-		// At the moment, AppSec doesn't block the requests, and so we can use the fact we are in monitoring-only mode
-		// to call the WAF only once at the end of the handler operation.
-		op.On(httpsec.OnHandlerOperationFinish(func(op *httpsec.Operation, res httpsec.HandlerOperationRes) {
-			defer wafCtx.Close()
-			// Run the WAF on the rule addresses available in the request args
-			values := make(map[string]interface{}, len(addresses))
+		// OnPathParamsOperationStart happens when the path parameters are only known once the route got matched after
+		// the handler operation started. As for the SDK body operation, interrupting the handler is delegated to the
+		// caller through the operation error.
+		op.On(httpsec.OnPathParamsOperationStart(func(paramsOp *httpsec.PathParamsOperation, args httpsec.PathParamsOperationArgs) {
+			inputs.add(args.PathParams)
+			values := map[string]interface{}{}
 			for _, addr := range addresses {
-				switch addr {
-				case serverRequestRawURIAddr:
-					values[serverRequestRawURIAddr] = args.RequestURI
-				case serverRequestHeadersNoCookiesAddr:
-					if headers := args.Headers; headers != nil {
-						values[serverRequestHeadersNoCookiesAddr] = headers
+				if addr == serverRequestPathParamsAddr && args.PathParams != nil {
+					values[serverRequestPathParamsAddr] = args.PathParams
+				}
+			}
+			if len(values) == 0 {
+				return
+			}
+			matches, actionIds := runWAF(wafCtx, values, timeout)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					if actionHandler.Apply(id, op) {
+						paramsOp.Error = sharedsec.NewMonitoringError("Request blocked")
 					}
-				case serverRequestCookiesAddr:
-					if cookies := args.Cookies; cookies != nil {
-						values[serverRequestCookiesAddr] = cookies
+				}
+				if paramsOp.Error != nil || limiter.Allow() {
+					op.AddSecurityEvents(matches)
+				}
+				log.Debug("appsec: WAF detected suspicious path parameters")
+			}
+		}))
+
+		// OnSQLOperationStart happens before a SQL statement gets executed by a database/sql integration. As for the
+		// user ID operation, interrupting the handler is delegated to the caller through the operation error.
+		op.On(sqlsec.OnSQLOperationStart(func(sqlOp *sqlsec.SQLOperation, args sqlsec.SQLOperationArgs) {
+			values := sqlValues(addresses, args)
+			if len(values) == 0 {
+				return
+			}
+			// Every statement is evaluated on its own, since the rules matched by a previous one would otherwise
+			// no longer match in the request WAF context.
+			matches, _ := runEphemeralWAF(handle.Handle, values, timeout)
+			if len(matches) == 0 {
+				return
+			}
+			// Only the statements whose suspicious fragments come from the request are reported
+			matches, actionIds := filterSQLMatches(matches, &inputs)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					if actionHandler.Apply(id, op) {
+						sqlOp.Error = sharedsec.NewMonitoringError("Request blocked")
 					}
-				case serverRequestQueryAddr:
-					if query := args.Query; query != nil {
-						values[serverRequestQueryAddr] = query
+				}
+				if sqlOp.Error != nil || limiter.Allow() {
+					op.AddSecurityEvents(matches)
+				}
+				log.Debug("appsec: WAF detected a suspicious sql statement")
+			}
+		}))
+
+		// OnRoundTripOperationStart happens before an outgoing request gets sent by an HTTP client integration. As for
+		// the SQL operation, interrupting the handler is delegated to the caller through the operation error.
+		op.On(httpsec.OnRoundTripOperationStart(func(rtOp *httpsec.RoundTripOperation, args httpsec.RoundTripOperationArgs) {
+			values := roundTripValues(addresses, args)
+			if len(values) == 0 {
+				return
+			}
+			matches, actionIds := runWAF(wafCtx, values, timeout)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					if actionHandler.Apply(id, op) {
+						rtOp.Error = sharedsec.NewMonitoringError("Request blocked")
 					}
-				case serverRequestPathParamsAddr:
-					if pathParams := args.PathParams; pathParams != nil {
-						values[serverRequestPathParamsAddr] = pathParams
+				}
+				if rtOp.Error != nil || limiter.Allow() {
+					op.AddSecurityEvents(matches)
+				}
+				log.Debug("appsec: WAF detected a suspicious outgoing request")
+			}
+		}))
+
+		// OnResolverOperationStart happens before a GraphQL resolver gets executed by a GraphQL integration. Blocking
+		// is reported through the operation error, which the integration returns as the GraphQL error of the field,
+		// so that the blocking action isn't applied to the HTTP response.
+		op.On(graphqlsec.OnResolverOperationStart(func(resolverOp *graphqlsec.ResolverOperation, args graphqlsec.ResolverOperationArgs) {
+			values := graphqlResolverValues(addresses, args)
+			if len(values) == 0 {
+				return
+			}
+			// As for the SQL statements, the arguments of every resolver are evaluated on their own, so that the
+			// sibling resolvers of a blocked one are blocked as well
+			matches, actionIds := runEphemeralWAF(handle.Handle, values, timeout)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					if actionHandler.Interrupts(id) {
+						resolverOp.Error = sharedsec.NewMonitoringError("Request blocked")
 					}
-				case serverRequestBodyAddr:
-					if body != nil {
-						values[serverRequestBodyAddr] = body
+				}
+				if resolverOp.Error != nil {
+					op.AddTag(instrumentation.BlockedRequestTag, true)
+				}
+				if resolverOp.Error != nil || limiter.Allow() {
+					op.AddSecurityEvents(matches)
+				}
+				log.Debug("appsec: WAF detected suspicious graphql resolver arguments")
+			}
+		}))
+
+		// OnRequestOperationFinish happens when a GraphQL request has been executed, with the arguments of all its
+		// resolvers. Blocking is no longer possible at this point.
+		op.On(graphqlsec.OnRequestOperationFinish(func(_ *graphqlsec.RequestOperation, res graphqlsec.RequestOperationRes) {
+			values := map[string]interface{}{}
+			for _, addr := range addresses {
+				if addr == graphqlServerAllResolversAddr && len(res.Resolvers) > 0 {
+					values[graphqlServerAllResolversAddr] = res.Resolvers
+				}
+			}
+			if len(values) == 0 {
+				return
+			}
+			matches, _ := runWAF(wafCtx, values, timeout)
+			if len(matches) > 0 && limiter.Allow() {
+				op.AddSecurityEvents(matches)
+				log.Debug("appsec: WAF detected suspicious graphql resolver arguments")
+			}
+		}))
+
+		// OnSDKResponseBodyOperationStart happens when appsec.MonitorParsedHTTPResponseBody() is called, before the
+		// response is written, so that the response can still be blocked by the caller.
+		op.On(httpsec.OnSDKResponseBodyOperationStart(func(sdkBodyOp *httpsec.SDKResponseBodyOperation, args httpsec.SDKResponseBodyOperationArgs) {
+			values := map[string]interface{}{}
+			for _, addr := range addresses {
+				if addr == serverResponseBodyAddr && args.Body != nil {
+					values[serverResponseBodyAddr] = args.Body
+				}
+			}
+			matches, actionIds := runWAF(wafCtx, values, timeout)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					if actionHandler.Apply(id, op) {
+						sdkBodyOp.Error = sharedsec.NewMonitoringError("Request blocked")
 					}
+				}
+				if sdkBodyOp.Error != nil || limiter.Allow() {
+					op.AddSecurityEvents(matches)
+				}
+				log.Debug("appsec: WAF detected a suspicious response body")
+			}
+		}))
+
+		op.On(httpsec.OnHandlerOperationFinish(func(op *httpsec.Operation, res httpsec.HandlerOperationRes) {
+			defer wafCtx.Close()
+			// Run the WAF on the rule addresses available in the handler results. The request addresses were
+			// already evaluated when the request began and are kept by the WAF context.
+			values := make(map[string]interface{}, 2)
+			for _, addr := range addresses {
+				switch addr {
 				case serverResponseStatusAddr:
 					values[serverResponseStatusAddr] = res.Status
+				case serverResponseHeadersNoCookiesAddr:
+					if headers := res.Headers; headers != nil {
+						values[serverResponseHeadersNoCookiesAddr] = headers
+					}
 				}
 			}
 			// Run the WAF, ignoring the returned actions - if any - since blocking after the request handler's
@@ -206,7 +532,7 @@ func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			addWAFMonitoringTags(op, rInfo.Version, overallRuntimeNs, internalRuntimeNs, wafCtx.TotalTimeouts())
 
 			// Add the following metrics once per instantiation of a WAF handle
-			monitorRulesOnce.Do(func() {
+			handle.monitorHTTPRulesOnce.Do(func() {
 				addRulesMonitoringTags(op, rInfo)
 				op.AddTag(ext.ManualKeep, samplernames.AppSec)
 			})
@@ -225,10 +551,7 @@ func newHTTPWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 
 // newGRPCWAFEventListener returns the WAF event listener to register in order
 // to enable it.
-func newGRPCWAFEventListener(handle *waf.Handle, addresses []string, timeout time.Duration, limiter Limiter) dyngo.EventListener {
-	var monitorRulesOnce sync.Once // per instantiation
-	actionHandler := grpcsec.NewActionsHandler()
-
+func newGRPCWAFEventListener(handles *wafHandleSwapper, timeout time.Duration, limiter Limiter) dyngo.EventListener {
 	return grpcsec.OnHandlerOperationStart(func(op *grpcsec.HandlerOperation, handlerArgs grpcsec.HandlerOperationArgs) {
 		// Limit the maximum number of security events, as a streaming RPC could
 		// receive unlimited number of messages where we could find security events
@@ -244,11 +567,21 @@ func newGRPCWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			mu     sync.Mutex // events mutex
 		)
 
-		wafCtx := waf.NewContext(handle)
+		handle, wafCtx := handles.newContext()
 		if wafCtx == nil {
 			// The WAF event listener got concurrently released
 			return
 		}
+		addresses := handle.grpcAddresses
+		actionHandler := handle.grpcActions
+		if len(addresses) == 0 {
+			wafCtx.Close()
+			return
+		}
+
+		// The request data controlled by the client, correlated with the SQL statements executed by the handler
+		var inputs userInputs
+		inputs.add(handlerArgs.Metadata)
 
 		// OnUserIDOperationStart happens when appsec.SetUser() is called. We run the WAF and apply actions to
 		// see if the associated user should be blocked. Since we don't control the execution flow in this case
@@ -271,6 +604,48 @@ func newGRPCWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			}
 		}))
 
+		// OnSQLOperationStart happens before a SQL statement gets executed by a database/sql integration. As for the
+		// user ID operation, interrupting the handler is delegated to the caller through the operation error.
+		op.On(sqlsec.OnSQLOperationStart(func(sqlOp *sqlsec.SQLOperation, args sqlsec.SQLOperationArgs) {
+			values := sqlValues(addresses, args)
+			if len(values) == 0 {
+				return
+			}
+			// Every statement is evaluated on its own, since the rules matched by a previous one would otherwise
+			// no longer match in the request WAF context.
+			matches, _ := runEphemeralWAF(handle.Handle, values, timeout)
+			if len(matches) == 0 {
+				return
+			}
+			// Only the statements whose suspicious fragments come from the request are reported
+			matches, actionIds := filterSQLMatches(matches, &inputs)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					actionHandler.Apply(id, op)
+				}
+				sqlOp.Error = op.Error
+				op.AddSecurityEvents(matches)
+				log.Debug("appsec: WAF detected a suspicious sql statement")
+			}
+		}))
+
+		// OnRoundTripOperationStart happens before an outgoing request gets sent by an HTTP client integration.
+		op.On(httpsec.OnRoundTripOperationStart(func(rtOp *httpsec.RoundTripOperation, args httpsec.RoundTripOperationArgs) {
+			values := roundTripValues(addresses, args)
+			if len(values) == 0 {
+				return
+			}
+			matches, actionIds := runWAF(wafCtx, values, timeout)
+			if len(matches) > 0 {
+				for _, id := range actionIds {
+					actionHandler.Apply(id, op)
+				}
+				rtOp.Error = op.Error
+				op.AddSecurityEvents(matches)
+				log.Debug("appsec: WAF detected a suspicious outgoing request")
+			}
+		}))
+
 		// The same address is used for gRPC and http when it comes to client ip
 		values := map[string]interface{}{}
 		for _, addr := range addresses {
@@ -294,6 +669,7 @@ func newGRPCWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 		}
 
 		op.On(grpcsec.OnReceiveOperationFinish(func(_ grpcsec.ReceiveOperation, res grpcsec.ReceiveOperationRes) {
+			inputs.add(res.Message)
 			if atomic.LoadUint32(&nbEvents) == maxWAFEventsPerRequest {
 				logOnce.Do(func() {
 					log.Debug("appsec: ignoring the rpc message due to the maximum number of security events per grpc call reached")
@@ -308,7 +684,7 @@ func newGRPCWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			//      the RPC lifetime.
 			//   2. We avoid the limitation of 1 event per attack type.
 			// TODO(Julio-Guerra): a future libddwaf API should solve this out.
-			wafCtx := waf.NewContext(handle)
+			wafCtx := waf.NewContext(handle.Handle)
 			if wafCtx == nil {
 				// The WAF event listener got concurrently released
 				return
@@ -350,7 +726,7 @@ func newGRPCWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 			addWAFMonitoringTags(op, rInfo.Version, overallRuntimeNs.Load(), internalRuntimeNs.Load(), nbTimeouts.Load())
 
 			// Log the following metrics once per instantiation of a WAF handle
-			monitorRulesOnce.Do(func() {
+			handle.monitorGRPCRulesOnce.Do(func() {
 				addRulesMonitoringTags(op, rInfo)
 				op.AddTag(ext.ManualKeep, samplernames.AppSec)
 			})
@@ -363,6 +739,48 @@ func newGRPCWAFEventListener(handle *waf.Handle, addresses []string, timeout tim
 	})
 }
 
+// sqlValues returns the values of the SQL operation for the given rule addresses. No values are returned when the
+// rules don't target the SQL statement.
+func sqlValues(addresses []string, args sqlsec.SQLOperationArgs) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, addr := range addresses {
+		if addr == serverDBStatementAddr {
+			values[serverDBStatementAddr] = args.Query
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	for _, addr := range addresses {
+		if addr == serverDBSystemAddr {
+			values[serverDBSystemAddr] = args.Driver
+		}
+	}
+	return values
+}
+
+// roundTripValues returns the values of the round trip operation for the given rule addresses.
+func roundTripValues(addresses []string, args httpsec.RoundTripOperationArgs) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, addr := range addresses {
+		if addr == serverIONetURLAddr {
+			values[serverIONetURLAddr] = args.URL
+		}
+	}
+	return values
+}
+
+// graphqlResolverValues returns the values of the GraphQL resolver operation for the given rule addresses.
+func graphqlResolverValues(addresses []string, args graphqlsec.ResolverOperationArgs) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, addr := range addresses {
+		if addr == graphqlServerResolverAddr {
+			values[graphqlServerResolverAddr] = map[string]interface{}{args.FieldName: args.Arguments}
+		}
+	}
+	return values
+}
+
 func runWAF(wafCtx *waf.Context, values map[string]interface{}, timeout time.Duration) ([]byte, []string) {
 	matches, actions, err := wafCtx.Run(values, timeout)
 	if err != nil {
@@ -376,17 +794,36 @@ func runWAF(wafCtx *waf.Context, values map[string]interface{}, timeout time.Dur
 	return matches, actions
 }
 
+// runEphemeralWAF runs the WAF on the given values in a short-lived WAF context of its own. Unlike the request WAF
+// context, the values are not kept once evaluated and the rules they match can still match the next values.
+func runEphemeralWAF(handle *waf.Handle, values map[string]interface{}, timeout time.Duration) ([]byte, []string) {
+	wafCtx := waf.NewContext(handle)
+	if wafCtx == nil {
+		// The WAF handle got concurrently released
+		return nil, nil
+	}
+	defer wafCtx.Close()
+	return runWAF(wafCtx, values, timeout)
+}
+
 // HTTP rule addresses currently supported by the WAF
 const (
-	serverRequestRawURIAddr           = "server.request.uri.raw"
-	serverRequestHeadersNoCookiesAddr = "server.request.headers.no_cookies"
-	serverRequestCookiesAddr          = "server.request.cookies"
-	serverRequestQueryAddr            = "server.request.query"
-	serverRequestPathParamsAddr       = "server.request.path_params"
-	serverRequestBodyAddr             = "server.request.body"
-	serverResponseStatusAddr          = "server.response.status"
-	httpClientIPAddr                  = "http.client_ip"
-	userIDAddr                        = "usr.id"
+	serverRequestRawURIAddr            = "server.request.uri.raw"
+	serverRequestHeadersNoCookiesAddr  = "server.request.headers.no_cookies"
+	serverRequestCookiesAddr           = "server.request.cookies"
+	serverRequestQueryAddr             = "server.request.query"
+	serverRequestPathParamsAddr        = "server.request.path_params"
+	serverRequestBodyAddr              = "server.request.body"
+	serverResponseStatusAddr           = "server.response.status"
+	serverResponseHeadersNoCookiesAddr = "server.response.headers.no_cookies"
+	serverResponseBodyAddr             = "server.response.body"
+	httpClientIPAddr                   = "http.client_ip"
+	userIDAddr                         = "usr.id"
+	serverDBStatementAddr              = "server.db.statement"
+	serverDBSystemAddr                 = "server.db.system"
+	serverIONetURLAddr                 = "server.io.net.url"
+	graphqlServerResolverAddr          = "graphql.server.resolver"
+	graphqlServerAllResolversAddr      = "graphql.server.all_resolvers"
 )
 
 // List of HTTP rule addresses currently supported by the WAF
@@ -398,8 +835,15 @@ var httpAddresses = []string{
 	serverRequestPathParamsAddr,
 	serverRequestBodyAddr,
 	serverResponseStatusAddr,
+	serverResponseHeadersNoCookiesAddr,
+	serverResponseBodyAddr,
 	httpClientIPAddr,
 	userIDAddr,
+	serverDBStatementAddr,
+	serverDBSystemAddr,
+	serverIONetURLAddr,
+	graphqlServerResolverAddr,
+	graphqlServerAllResolversAddr,
 }
 
 // gRPC rule addresses currently supported by the WAF
@@ -414,6 +858,9 @@ var grpcAddresses = []string{
 	grpcServerRequestMetadata,
 	httpClientIPAddr,
 	userIDAddr,
+	serverDBStatementAddr,
+	serverDBSystemAddr,
+	serverIONetURLAddr,
 }
 
 func init() {
@@ -38,3 +38,23 @@ func TestTagsTypes(t *testing.T) {
 		require.Contains(t, tags, tag)
 	}
 }
+
+func TestFilterSQLMatches(t *testing.T) {
+	matches := []byte(`[{"rule":{"id":"blk-001-004","on_match":["block"]},"rule_matches":[{"operator":"match_regex","parameters":[{"address":"server.db.statement","highlight":["OR 1=1"]}]}]}]`)
+
+	t.Run("user-input", func(t *testing.T) {
+		var inputs userInputs
+		inputs.add(map[string][]string{"name": {"' or 1=1 --"}})
+		filtered, actions := filterSQLMatches(matches, &inputs)
+		require.JSONEq(t, string(matches), string(filtered))
+		require.Equal(t, []string{"block"}, actions)
+	})
+
+	t.Run("hard-coded", func(t *testing.T) {
+		var inputs userInputs
+		inputs.add(map[string][]string{"name": {"gopher"}})
+		filtered, actions := filterSQLMatches(matches, &inputs)
+		require.Nil(t, filtered)
+		require.Nil(t, actions)
+	})
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package datastreams
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// Pathway is used to monitor how payloads are sent across different services.
+// An example Pathway would be:
+// service A -- kafka topic A -- service B -- kafka topic B -- service C
+// Each edge of the pathway is identified by the checkpoints set by the
+// producers and consumers, and the hash of the pathway identifies the whole
+// chain of checkpoints leading to the current one.
+type Pathway struct {
+	// hash is the hash of the current node, of the parent node and of the
+	// edge that connects them.
+	hash uint64
+	// pathwayStart is the start of the first node of the pathway.
+	pathwayStart time.Time
+	// edgeStart is the start of the previous node.
+	edgeStart time.Time
+}
+
+// GetHash returns the hash of the pathway, representing the upstream path of the data.
+func (p Pathway) GetHash() uint64 {
+	return p.hash
+}
+
+// PathwayStart returns the start timestamp of the pathway.
+func (p Pathway) PathwayStart() time.Time {
+	return p.pathwayStart
+}
+
+// EdgeStart returns the start timestamp of the current edge of the pathway.
+func (p Pathway) EdgeStart() time.Time {
+	return p.edgeStart
+}
+
+// nodeHash computes the hash of a node of the pathway, identified by the
+// service and env of the application and the tags describing the edge.
+func nodeHash(service, env string, edgeTags []string) uint64 {
+	tags := make([]string, len(edgeTags))
+	copy(tags, edgeTags)
+	sort.Strings(tags)
+	h := fnv.New64()
+	h.Write([]byte(service))
+	h.Write([]byte(env))
+	for _, t := range tags {
+		h.Write([]byte(t))
+	}
+	return h.Sum64()
+}
+
+// pathwayHash chains the hash of a node with the hash of its parent.
+func pathwayHash(nodeHash, parentHash uint64) uint64 {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint64(b, nodeHash)
+	binary.LittleEndian.PutUint64(b[8:], parentHash)
+	h := fnv.New64()
+	h.Write(b)
+	return h.Sum64()
+}
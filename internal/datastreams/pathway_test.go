@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathway(t *testing.T) {
+	t.Run("hash", func(t *testing.T) {
+		h := nodeHash("service", "env", []string{"type:kafka", "topic:orders", "direction:out"})
+		// the order of the edge tags doesn't matter
+		assert.Equal(t, h, nodeHash("service", "env", []string{"direction:out", "topic:orders", "type:kafka"}))
+		assert.NotEqual(t, h, nodeHash("service", "env", []string{"direction:in", "topic:orders", "type:kafka"}))
+		assert.NotEqual(t, h, nodeHash("other-service", "env", []string{"direction:out", "topic:orders", "type:kafka"}))
+		assert.NotEqual(t, pathwayHash(h, 1), pathwayHash(h, 2))
+	})
+
+	t.Run("encoding", func(t *testing.T) {
+		start := time.Now().Truncate(time.Millisecond)
+		p := Pathway{
+			hash:         pathwayHash(nodeHash("service", "env", []string{"type:kafka"}), 42),
+			pathwayStart: start,
+			edgeStart:    start.Add(time.Second),
+		}
+		decoded, err := Decode(p.Encode())
+		assert.NoError(t, err)
+		assert.Equal(t, p.GetHash(), decoded.GetHash())
+		assert.True(t, p.PathwayStart().Equal(decoded.PathwayStart()))
+		assert.True(t, p.EdgeStart().Equal(decoded.EdgeStart()))
+
+		decoded, err = DecodeBase64(p.EncodeBase64())
+		assert.NoError(t, err)
+		assert.Equal(t, p.GetHash(), decoded.GetHash())
+		assert.True(t, p.EdgeStart().Equal(decoded.EdgeStart()))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := Decode([]byte{1, 2, 3})
+		assert.Error(t, err)
+		_, err = Decode([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		assert.Error(t, err)
+		_, err = DecodeBase64("not base64!")
+		assert.Error(t, err)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		_, ok := PathwayFromContext(context.Background())
+		assert.False(t, ok)
+		p := Pathway{hash: 1}
+		got, ok := PathwayFromContext(ContextWithPathway(context.Background(), p))
+		assert.True(t, ok)
+		assert.Equal(t, p, got)
+	})
+}
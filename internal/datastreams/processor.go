@@ -0,0 +1,330 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/version"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"google.golang.org/protobuf/proto"
+)
+
+// bucketDuration specifies the span of time covered by one stats bucket.
+const bucketDuration = 10 * time.Second
+
+// newSketch returns a sketch storing a latency distribution, in seconds.
+func newSketch() *ddsketch.DDSketch {
+	const (
+		// relativeAccuracy is the value accuracy we have on the percentiles. For example, we can
+		// say that p99 is 100ms +- 1ms
+		relativeAccuracy = 0.01
+		// maxNumBins is the maximum number of bins of the ddSketch we use to store percentiles.
+		maxNumBins = 2048
+	)
+	sketch, err := ddsketch.LogCollapsingLowestDenseDDSketch(relativeAccuracy, maxNumBins)
+	if err != nil {
+		log.Error("Error when creating ddsketch: %v", err)
+	}
+	return sketch
+}
+
+// statsPoint is a single checkpoint reported by SetCheckpoint.
+type statsPoint struct {
+	edgeTags       []string
+	hash           uint64
+	parentHash     uint64
+	timestamp      int64
+	pathwayLatency int64
+	edgeLatency    int64
+}
+
+// statsGroup aggregates the latencies of all the checkpoints sharing the
+// same pathway hash within a bucket.
+type statsGroup struct {
+	edgeTags       []string
+	hash           uint64
+	parentHash     uint64
+	pathwayLatency *ddsketch.DDSketch
+	edgeLatency    *ddsketch.DDSketch
+}
+
+type bucket struct {
+	points   map[uint64]statsGroup
+	start    uint64
+	duration uint64
+}
+
+func newBucket(start, duration uint64) bucket {
+	return bucket{
+		points:   make(map[uint64]statsGroup),
+		start:    start,
+		duration: duration,
+	}
+}
+
+func (b bucket) export() StatsBucket {
+	stats := make([]StatsPoint, 0, len(b.points))
+	for _, s := range b.points {
+		pathwayLatency, err := proto.Marshal(s.pathwayLatency.ToProto())
+		if err != nil {
+			log.Error("Failed to serialize pathway latency sketch: %v", err)
+			continue
+		}
+		edgeLatency, err := proto.Marshal(s.edgeLatency.ToProto())
+		if err != nil {
+			log.Error("Failed to serialize edge latency sketch: %v", err)
+			continue
+		}
+		stats = append(stats, StatsPoint{
+			EdgeTags:       s.edgeTags,
+			Hash:           s.hash,
+			ParentHash:     s.parentHash,
+			PathwayLatency: pathwayLatency,
+			EdgeLatency:    edgeLatency,
+		})
+	}
+	return StatsBucket{
+		Start:    b.start,
+		Duration: b.duration,
+		Stats:    stats,
+	}
+}
+
+// Processor aggregates the checkpoints reported by the data streams
+// integrations into time buckets of latency sketches, and flushes them
+// periodically to the agent.
+type Processor struct {
+	// in receives the checkpoints to aggregate. In order for in to have a
+	// consumer, the processor must be started using a call to Start.
+	in chan statsPoint
+
+	// mu guards below fields
+	mu sync.Mutex
+
+	// buckets maintains a set of buckets, where the map key represents
+	// the starting point in time of that bucket, in nanoseconds.
+	buckets map[int64]bucket
+
+	// stopped reports whether the processor is stopped (when non-zero)
+	stopped uint32
+
+	// dropped counts the checkpoints dropped because in was full.
+	dropped uint64
+
+	wg         sync.WaitGroup   // waits for any active goroutines
+	stop       chan struct{}    // closing this channel triggers shutdown
+	service    string           // service of the application
+	env        string           // env of the application
+	transport  transport        // transport used to send the stats payloads
+	timeSource func() time.Time // replaced in tests
+}
+
+// NewProcessor returns a new Processor for the given service and env,
+// sending its stats to the agent located at agentURL.
+func NewProcessor(service, env string, agentURL *url.URL, httpClient *http.Client) *Processor {
+	return &Processor{
+		in:         make(chan statsPoint, 10000),
+		buckets:    make(map[int64]bucket),
+		stopped:    1,
+		service:    service,
+		env:        env,
+		transport:  newHTTPTransport(agentURL, httpClient),
+		timeSource: time.Now,
+	}
+}
+
+// alignTs returns the provided timestamp truncated to the bucket size.
+// It gives us the start time of the time bucket in which such timestamp falls.
+func alignTs(ts, bucketSize int64) int64 { return ts - ts%bucketSize }
+
+// Start starts the processor. A started processor needs to be stopped
+// in order to gracefully shut down, using Stop.
+func (p *Processor) Start() {
+	if atomic.SwapUint32(&p.stopped, 0) == 0 {
+		// already running
+		log.Warn("(*Processor).Start called more than once. This is likely a programming error.")
+		return
+	}
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		tick := time.NewTicker(bucketDuration)
+		defer tick.Stop()
+		p.runFlusher(tick.C)
+	}()
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.runIngester()
+	}()
+}
+
+// runFlusher runs the flushing loop which sends stats to the underlying transport.
+func (p *Processor) runFlusher(tick <-chan time.Time) {
+	for {
+		select {
+		case now := <-tick:
+			p.flushAndSend(now, withoutCurrentBucket)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// runIngester runs the loop which accepts incoming checkpoints on the
+// processor's in channel.
+func (p *Processor) runIngester() {
+	for {
+		select {
+		case s := <-p.in:
+			p.add(s)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// add adds s into the processor's internal stats buckets.
+func (p *Processor) add(s statsPoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	btime := alignTs(s.timestamp, bucketDuration.Nanoseconds())
+	b, ok := p.buckets[btime]
+	if !ok {
+		b = newBucket(uint64(btime), uint64(bucketDuration.Nanoseconds()))
+		p.buckets[btime] = b
+	}
+	group, ok := b.points[s.hash]
+	if !ok {
+		group = statsGroup{
+			edgeTags:       s.edgeTags,
+			hash:           s.hash,
+			parentHash:     s.parentHash,
+			pathwayLatency: newSketch(),
+			edgeLatency:    newSketch(),
+		}
+		b.points[s.hash] = group
+	}
+	if err := group.pathwayLatency.Add(time.Duration(s.pathwayLatency).Seconds()); err != nil {
+		log.Error("Failed to add pathway latency: %v", err)
+	}
+	if err := group.edgeLatency.Add(time.Duration(s.edgeLatency).Seconds()); err != nil {
+		log.Error("Failed to add edge latency: %v", err)
+	}
+}
+
+// Stop stops the processor and blocks until the operation completes.
+func (p *Processor) Stop() {
+	if atomic.SwapUint32(&p.stopped, 1) > 0 {
+		return
+	}
+	close(p.stop)
+	p.wg.Wait()
+drain:
+	for {
+		select {
+		case s := <-p.in:
+			p.add(s)
+		default:
+			break drain
+		}
+	}
+	p.flushAndSend(p.timeSource(), withCurrentBucket)
+}
+
+// Flush flushes all the stats buckets, including the current one.
+func (p *Processor) Flush() {
+	p.flushAndSend(p.timeSource(), withCurrentBucket)
+}
+
+const (
+	withCurrentBucket    = true
+	withoutCurrentBucket = false
+)
+
+// flushAndSend flushes all the stats buckets with the given timestamp and sends them using the
+// processor's transport. The current bucket is only included if includeCurrent is true, such as
+// during shutdown.
+func (p *Processor) flushAndSend(timenow time.Time, includeCurrent bool) {
+	sp := func() StatsPayload {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		now := timenow.UnixNano()
+		sp := StatsPayload{
+			Service:       p.service,
+			Env:           p.env,
+			Lang:          "go",
+			TracerVersion: version.Tag,
+			Stats:         make([]StatsBucket, 0, len(p.buckets)),
+		}
+		for ts, b := range p.buckets {
+			if !includeCurrent && ts > now-bucketDuration.Nanoseconds() {
+				// do not flush the current bucket
+				continue
+			}
+			sp.Stats = append(sp.Stats, b.export())
+			delete(p.buckets, ts)
+		}
+		return sp
+	}()
+
+	if n := atomic.SwapUint64(&p.dropped, 0); n > 0 {
+		log.Warn("Data streams monitoring: dropped %d checkpoints, the payload queue is full.", n)
+	}
+	if len(sp.Stats) == 0 {
+		// nothing to flush
+		return
+	}
+	if err := p.transport.sendPipelineStats(&sp); err != nil {
+		log.Error("Error sending data streams stats payload: %v", err)
+	}
+}
+
+// SetCheckpoint sets a checkpoint on the pathway found in ctx, or on a new
+// pathway if ctx doesn't contain any, and returns a copy of ctx containing
+// the resulting pathway. The edge tags identify the checkpoint, for example
+// "direction:out", "topic:orders" and "type:kafka".
+func (p *Processor) SetCheckpoint(ctx context.Context, edgeTags ...string) context.Context {
+	now := p.timeSource()
+	var (
+		parentHash   uint64
+		pathwayStart = now
+		edgeStart    = now
+	)
+	if parent, ok := PathwayFromContext(ctx); ok {
+		parentHash = parent.GetHash()
+		pathwayStart = parent.PathwayStart()
+		edgeStart = parent.EdgeStart()
+	}
+	child := Pathway{
+		hash:         pathwayHash(nodeHash(p.service, p.env, edgeTags), parentHash),
+		pathwayStart: pathwayStart,
+		edgeStart:    now,
+	}
+	select {
+	case p.in <- statsPoint{
+		edgeTags:       edgeTags,
+		hash:           child.hash,
+		parentHash:     parentHash,
+		timestamp:      now.UnixNano(),
+		pathwayLatency: now.Sub(pathwayStart).Nanoseconds(),
+		edgeLatency:    now.Sub(edgeStart).Nanoseconds(),
+	}:
+	default:
+		atomic.AddUint64(&p.dropped, 1)
+	}
+	return ContextWithPathway(ctx, child)
+}
@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package datastreams
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/sketches-go/ddsketch"
+	"github.com/DataDog/sketches-go/ddsketch/pb/sketchpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+	"google.golang.org/protobuf/proto"
+)
+
+type mockTransport struct {
+	mu       sync.Mutex
+	payloads []*StatsPayload
+}
+
+func (t *mockTransport) sendPipelineStats(p *StatsPayload) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.payloads = append(t.payloads, p)
+	return nil
+}
+
+func decodeSketch(t *testing.T, data []byte) *ddsketch.DDSketch {
+	var pb sketchpb.DDSketch
+	require.NoError(t, proto.Unmarshal(data, &pb))
+	sketch, err := ddsketch.FromProto(&pb)
+	require.NoError(t, err)
+	return sketch
+}
+
+func TestProcessor(t *testing.T) {
+	transport := &mockTransport{}
+	p := NewProcessor("service", "env", &url.URL{Scheme: "http", Host: "localhost:8126"}, http.DefaultClient)
+	p.transport = transport
+	now := time.Unix(0, 0).Add(3 * bucketDuration)
+	p.timeSource = func() time.Time { return now }
+	p.Start()
+
+	// producer
+	ctx := p.SetCheckpoint(context.Background(), "direction:out", "topic:orders", "type:kafka")
+	producer, ok := PathwayFromContext(ctx)
+	require.True(t, ok)
+	assert.True(t, producer.PathwayStart().Equal(now))
+
+	// consumer, two seconds later
+	now = now.Add(2 * time.Second)
+	ctx = p.SetCheckpoint(ctx, "direction:in", "group:group", "topic:orders", "type:kafka")
+	consumer, ok := PathwayFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, pathwayHash(nodeHash("service", "env", []string{"direction:in", "group:group", "topic:orders", "type:kafka"}), producer.GetHash()), consumer.GetHash())
+	assert.True(t, consumer.EdgeStart().Equal(now))
+
+	// downstream producer, one more second later
+	now = now.Add(time.Second)
+	p.SetCheckpoint(ctx, "direction:out", "topic:invoices", "type:kafka")
+
+	p.Stop()
+	require.Len(t, transport.payloads, 1)
+	payload := transport.payloads[0]
+	assert.Equal(t, "service", payload.Service)
+	assert.Equal(t, "env", payload.Env)
+	assert.Equal(t, "go", payload.Lang)
+	require.Len(t, payload.Stats, 1)
+	bucket := payload.Stats[0]
+	assert.Equal(t, uint64(3*bucketDuration), bucket.Start)
+	assert.Equal(t, uint64(bucketDuration), bucket.Duration)
+	require.Len(t, bucket.Stats, 3)
+
+	points := make(map[uint64]StatsPoint)
+	for _, s := range bucket.Stats {
+		points[s.Hash] = s
+	}
+	point, ok := points[consumer.GetHash()]
+	require.True(t, ok)
+	assert.Equal(t, producer.GetHash(), point.ParentHash)
+	assert.Equal(t, []string{"direction:in", "group:group", "topic:orders", "type:kafka"}, point.EdgeTags)
+	edgeLatency, err := decodeSketch(t, point.EdgeLatency).GetMaxValue()
+	require.NoError(t, err)
+	assert.InDelta(t, 2, edgeLatency, 0.02)
+
+	var last StatsPoint
+	for _, s := range bucket.Stats {
+		if s.ParentHash == consumer.GetHash() {
+			last = s
+		}
+	}
+	pathwayLatency, err := decodeSketch(t, last.PathwayLatency).GetMaxValue()
+	require.NoError(t, err)
+	assert.InDelta(t, 3, pathwayLatency, 0.03)
+	edgeLatency, err = decodeSketch(t, last.EdgeLatency).GetMaxValue()
+	require.NoError(t, err)
+	assert.InDelta(t, 1, edgeLatency, 0.01)
+}
+
+func TestProcessorBuckets(t *testing.T) {
+	transport := &mockTransport{}
+	p := NewProcessor("service", "env", &url.URL{Scheme: "http", Host: "localhost:8126"}, http.DefaultClient)
+	p.transport = transport
+	now := time.Unix(0, 0).Add(3 * bucketDuration)
+	p.timeSource = func() time.Time { return now }
+
+	p.add(statsPoint{edgeTags: []string{"type:kafka"}, hash: 1, timestamp: now.UnixNano()})
+	p.add(statsPoint{edgeTags: []string{"type:kafka"}, hash: 1, timestamp: now.Add(bucketDuration).UnixNano()})
+
+	// the current bucket isn't flushed
+	p.flushAndSend(now.Add(bucketDuration), withoutCurrentBucket)
+	require.Len(t, transport.payloads, 1)
+	require.Len(t, transport.payloads[0].Stats, 1)
+	assert.Equal(t, uint64(now.UnixNano()), transport.payloads[0].Stats[0].Start)
+
+	p.flushAndSend(now.Add(bucketDuration), withCurrentBucket)
+	require.Len(t, transport.payloads, 2)
+	require.Len(t, transport.payloads[1].Stats, 1)
+
+	// nothing left to flush
+	p.flushAndSend(now.Add(bucketDuration), withCurrentBucket)
+	assert.Len(t, transport.payloads, 2)
+}
+
+func TestHTTPTransport(t *testing.T) {
+	var payload StatsPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v0.1/pipeline_stats", r.URL.Path)
+		assert.Equal(t, "application/msgpack", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, msgp.Decode(gz, &payload))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	transport := newHTTPTransport(u, srv.Client())
+	err = transport.sendPipelineStats(&StatsPayload{Service: "service", Env: "env", Stats: []StatsBucket{{Start: 1, Duration: 2}}})
+	require.NoError(t, err)
+	assert.Equal(t, "service", payload.Service)
+	assert.Equal(t, "env", payload.Env)
+	require.Len(t, payload.Stats, 1)
+	assert.Equal(t, uint64(2), payload.Stats[0].Duration)
+}
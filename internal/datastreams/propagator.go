@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package datastreams
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// PropagationKeyBase64 is the key used to propagate the base64 encoded
+// pathway context in message headers and attributes.
+const PropagationKeyBase64 = "dd-pathway-ctx-base64"
+
+type contextKey struct{}
+
+// ContextWithPathway returns a copy of the given context which includes the pathway p.
+func ContextWithPathway(ctx context.Context, p Pathway) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PathwayFromContext returns the pathway contained in a Go context if present.
+func PathwayFromContext(ctx context.Context) (p Pathway, ok bool) {
+	if ctx == nil {
+		return p, false
+	}
+	p, ok = ctx.Value(contextKey{}).(Pathway)
+	return p, ok
+}
+
+// Encode encodes the pathway as its hash followed by the varint encoded
+// pathway and edge start times, in milliseconds.
+func (p Pathway) Encode() []byte {
+	data := make([]byte, 8, 8+2*binary.MaxVarintLen64)
+	binary.LittleEndian.PutUint64(data, p.hash)
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], toMillis(p.pathwayStart))
+	data = append(data, buf[:n]...)
+	n = binary.PutVarint(buf[:], toMillis(p.edgeStart))
+	data = append(data, buf[:n]...)
+	return data
+}
+
+// Decode decodes a pathway previously encoded with Encode.
+func Decode(data []byte) (p Pathway, err error) {
+	if len(data) < 8 {
+		return p, errors.New("hash smaller than 8 bytes")
+	}
+	p.hash = binary.LittleEndian.Uint64(data)
+	data = data[8:]
+	pathwayStart, n := binary.Varint(data)
+	if n <= 0 {
+		return p, errors.New("failed to decode pathway start")
+	}
+	data = data[n:]
+	edgeStart, n := binary.Varint(data)
+	if n <= 0 {
+		return p, errors.New("failed to decode edge start")
+	}
+	p.pathwayStart = time.UnixMilli(pathwayStart)
+	p.edgeStart = time.UnixMilli(edgeStart)
+	return p, nil
+}
+
+// EncodeBase64 encodes the pathway into a base64 string.
+func (p Pathway) EncodeBase64() string {
+	return base64.StdEncoding.EncodeToString(p.Encode())
+}
+
+// DecodeBase64 decodes a pathway previously encoded with EncodeBase64.
+func DecodeBase64(str string) (p Pathway, err error) {
+	data, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return p, err
+	}
+	return Decode(data)
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -24,6 +24,7 @@ type config struct {
 	analyticsRate float64
 	serviceName   string
 	runtimeID     string
+	headerTags    []HeaderTag
 }
 
 // AnalyticsRate returns the sampling rate at which events should be marked. It uses
@@ -62,3 +63,31 @@ func RuntimeID() string {
 	defer cfg.mu.RUnlock()
 	return cfg.runtimeID
 }
+
+// HeaderTag specifies an HTTP header whose value should be reported as a span
+// tag by the HTTP integrations, along with the names of the tags to use on
+// requests and responses.
+type HeaderTag struct {
+	// Header is the canonical name of the HTTP header (see textproto.CanonicalMIMEHeaderKey).
+	Header string
+	// RequestTag is the name of the tag holding the value of the header in requests.
+	RequestTag string
+	// ResponseTag is the name of the tag holding the value of the header in responses.
+	ResponseTag string
+}
+
+// HeaderTags returns the HTTP headers which should be reported as span tags. The
+// returned slice must not be modified.
+func HeaderTags() []HeaderTag {
+	cfg.mu.RLock()
+	defer cfg.mu.RUnlock()
+	return cfg.headerTags
+}
+
+// SetHeaderTags sets the HTTP headers which should be reported as span tags,
+// replacing any previous ones.
+func SetHeaderTags(tags []HeaderTag) {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.headerTags = tags
+}
@@ -40,8 +40,18 @@ const (
 	ASMIPBlocking
 	// ASMDDRules represents the capability to update the rules used by the ASM WAF for threat detection
 	ASMDDRules
+	// ASMExclusions represents the capability for ASM to exclude rules from being evaluated
+	ASMExclusions
+	// ASMRequestBlocking represents the capability for ASM to change the action of rules, eg. to block requests
+	ASMRequestBlocking
 	// ASMUserBlocking represents the capability for ASM to block requests based on user ID
 	ASMUserBlocking = 7
+	// ASMCustomRules represents the capability for ASM to add user-defined rules to the ASM WAF
+	ASMCustomRules Capability = 8
+	// ASMCustomBlockingResponse represents the capability for ASM to define the blocking responses of the actions
+	ASMCustomBlockingResponse Capability = 9
+	// APMTracingProfiling represents the capability to update the profiler configuration through APM_TRACING
+	APMTracingProfiling Capability = 16
 )
 
 // ProductUpdate represents an update for a specific product.
@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Package statusrange parses the lists of HTTP status codes and status code
+// ranges used to configure the tracer, such as "500-599,429".
+package statusrange
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of HTTP status codes.
+type Range struct {
+	Min, Max int
+}
+
+// List is a list of HTTP status code ranges.
+type List []Range
+
+// Contains reports whether statusCode is within one of the ranges.
+func (l List) Contains(statusCode int) bool {
+	for _, r := range l {
+		if statusCode >= r.Min && statusCode <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a comma-separated list of status codes and inclusive status code
+// ranges such as "500-599,429". Invalid entries are skipped, and reported by the
+// returned error along with the list of the valid ones. The returned list is
+// never nil.
+func Parse(s string) (List, error) {
+	l := List{}
+	var invalid []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(v, "-")
+		min, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			invalid = append(invalid, v)
+			continue
+		}
+		max := min
+		if isRange {
+			if max, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || max < min {
+				invalid = append(invalid, v)
+				continue
+			}
+		}
+		l = append(l, Range{Min: min, Max: max})
+	}
+	if len(invalid) > 0 {
+		return l, fmt.Errorf("invalid status codes %q", invalid)
+	}
+	return l, nil
+}
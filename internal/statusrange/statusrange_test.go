@@ -0,0 +1,46 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package statusrange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		out     List
+		invalid bool
+	}{
+		{in: "", out: List{}},
+		{in: "500-599", out: List{{500, 599}}},
+		{in: "500-599,429", out: List{{500, 599}, {429, 429}}},
+		{in: " 400 - 403 , 404,", out: List{{400, 403}, {404, 404}}},
+		{in: "abc,599-500,400-,-500,410", out: List{{410, 410}}, invalid: true},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			l, err := Parse(tc.in)
+			require.Equal(t, tc.out, l)
+			if tc.invalid {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	l := List{{500, 599}, {429, 429}}
+	require.True(t, l.Contains(500))
+	require.True(t, l.Contains(599))
+	require.True(t, l.Contains(429))
+	require.False(t, l.Contains(428))
+	require.False(t, l.Contains(600))
+	require.False(t, List{}.Contains(500))
+}
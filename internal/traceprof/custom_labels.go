@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package traceprof
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// CustomLabelOverflow is the value used for a custom label once the
+// cardinality limit for its key has been reached.
+const CustomLabelOverflow = "other"
+
+// globalCustomLabels is shared between the profiler and the tracer.
+var globalCustomLabels = NewCustomLabels()
+
+// GlobalCustomLabels returns the custom label allowlist that is shared between
+// tracing and profiling. It is configured by the profiler and used by the
+// tracer to decide which user-provided span labels become pprof labels.
+func GlobalCustomLabels() *CustomLabels {
+	return globalCustomLabels
+}
+
+// NewCustomLabels returns a new, disabled CustomLabels.
+func NewCustomLabels() *CustomLabels {
+	return &CustomLabels{values: map[string]map[string]struct{}{}}
+}
+
+// CustomLabels filters user-provided pprof labels against an allowlist of keys
+// and caps the number of distinct values per key.
+type CustomLabels struct {
+	enabled uint64
+	mu      sync.Mutex
+	// values maps every allowed key to the values seen since the last reset.
+	values    map[string]map[string]struct{}
+	maxValues int
+}
+
+// SetKeys replaces the allowed label keys. At most maxValues distinct values
+// are kept per key between two calls to Reset, additional values are replaced
+// by CustomLabelOverflow. A maxValues of <= 0 indicates no limit. Calling
+// SetKeys without keys disables custom labels.
+func (c *CustomLabels) SetKeys(maxValues int, keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = make(map[string]map[string]struct{}, len(keys))
+	for _, k := range keys {
+		c.values[k] = map[string]struct{}{}
+	}
+	c.maxValues = maxValues
+	atomic.StoreUint64(&c.enabled, boolToUint64(len(keys) > 0))
+}
+
+// Keys returns the allowed label keys in sorted order.
+func (c *CustomLabels) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Enabled returns true if custom labels are enabled, i.e. some keys are
+// allowed.
+func (c *CustomLabels) Enabled() bool {
+	return atomic.LoadUint64(&c.enabled) == 1
+}
+
+// Append appends the key/value pairs from labels whose key is allowed to dst
+// and returns the result. labels must contain an even number of elements. If
+// custom labels are disabled, this method returns dst and is almost zero-cost.
+func (c *CustomLabels) Append(dst []string, labels ...string) []string {
+	// Fast-path return if custom labels are disabled.
+	if atomic.LoadUint64(&c.enabled) == 0 || len(labels) < 2 {
+		return dst
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		key, val := labels[i], labels[i+1]
+		seen, ok := c.values[key]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[val]; !ok {
+			if c.maxValues > 0 && len(seen) >= c.maxValues {
+				val = CustomLabelOverflow
+			} else {
+				seen[val] = struct{}{}
+			}
+		}
+		dst = append(dst, key, val)
+	}
+	return dst
+}
+
+// Reset forgets the values seen for every key, so that new values can be
+// accepted up to the cardinality limit again.
+func (c *CustomLabels) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.values {
+		c.values[k] = map[string]struct{}{}
+	}
+}
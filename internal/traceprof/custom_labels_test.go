@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package traceprof
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomLabels(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		cl := NewCustomLabels()
+		require.False(t, cl.Enabled())
+		require.Empty(t, cl.Append(nil, "tenant", "foo"))
+		require.Empty(t, cl.Keys())
+	})
+
+	t.Run("allowlist", func(t *testing.T) {
+		cl := NewCustomLabels()
+		cl.SetKeys(0, "tenant", "tier")
+		require.True(t, cl.Enabled())
+		require.Equal(t, []string{"tenant", "tier"}, cl.Keys())
+		got := cl.Append([]string{"span id", "1"}, "tenant", "foo", "secret", "bar", "tier", "gold")
+		require.Equal(t, []string{"span id", "1", "tenant", "foo", "tier", "gold"}, got)
+		// odd number of elements, the dangling key is ignored
+		require.Equal(t, []string{"tenant", "foo"}, cl.Append(nil, "tenant", "foo", "tier"))
+	})
+
+	t.Run("cardinality", func(t *testing.T) {
+		cl := NewCustomLabels()
+		cl.SetKeys(2, "tenant")
+		require.Equal(t, []string{"tenant", "a"}, cl.Append(nil, "tenant", "a"))
+		require.Equal(t, []string{"tenant", "b"}, cl.Append(nil, "tenant", "b"))
+		require.Equal(t, []string{"tenant", CustomLabelOverflow}, cl.Append(nil, "tenant", "c"))
+		require.Equal(t, []string{"tenant", "a"}, cl.Append(nil, "tenant", "a"))
+		cl.Reset()
+		require.Equal(t, []string{"tenant", "c"}, cl.Append(nil, "tenant", "c"))
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		cl := NewCustomLabels()
+		cl.SetKeys(0, "tenant")
+		cl.SetKeys(0)
+		require.False(t, cl.Enabled())
+		require.Empty(t, cl.Append(nil, "tenant", "foo"))
+	})
+}
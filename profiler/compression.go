@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression algorithms supported for uploads, see WithUploadCompression and
+// WithBodyCompression.
+const (
+	// compressionLegacy uploads pprof profiles gzip compressed as returned by
+	// the runtime, and the other attachments uncompressed.
+	compressionLegacy = ""
+	compressionNone   = "none"
+	compressionGzip   = "gzip"
+	compressionZstd   = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+func isGzipData(data []byte) bool {
+	return bytes.HasPrefix(data, gzipMagic)
+}
+
+func isZstdData(data []byte) bool {
+	return bytes.HasPrefix(data, zstdMagic)
+}
+
+// validateCompression returns an error if algorithm is not supported or level
+// is out of range for it. A level of 0 selects the default level.
+func validateCompression(algorithm string, level int) error {
+	switch algorithm {
+	case compressionLegacy, compressionNone:
+		return nil
+	case compressionGzip:
+		if level < 0 || level > gzip.BestCompression {
+			return fmt.Errorf("invalid gzip compression level %d, must be between 1 and %d", level, gzip.BestCompression)
+		}
+		return nil
+	case compressionZstd:
+		if level < 0 || level > 22 {
+			return fmt.Errorf("invalid zstd compression level %d, must be between 1 and 22", level)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown compression algorithm: %q", algorithm)
+	}
+}
+
+// compressor is a streaming compressor which can be reused for multiple
+// outputs.
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// nopCompressor writes its input unchanged.
+type nopCompressor struct{ io.Writer }
+
+func (c *nopCompressor) Reset(w io.Writer) { c.Writer = w }
+func (*nopCompressor) Close() error        { return nil }
+
+// newCompressor returns a compressor for the given algorithm and level, which
+// must have been checked with validateCompression. pprof profiles are always
+// compressed, so the legacy algorithm uses gzip.
+func newCompressor(algorithm string, level int) compressor {
+	switch algorithm {
+	case compressionNone:
+		return &nopCompressor{}
+	case compressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		if w, err := gzip.NewWriterLevel(io.Discard, level); err == nil {
+			return w
+		}
+	case compressionZstd:
+		opts := []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		if w, err := zstd.NewWriter(nil, opts...); err == nil {
+			return w
+		}
+	}
+	return gzip.NewWriter(io.Discard)
+}
+
+// decompressor decompresses gzip or zstd compressed data. It can be reused,
+// but it is not safe for concurrent use.
+type decompressor struct {
+	gzr gzip.Reader
+	zr  *zstd.Decoder
+	buf []byte
+}
+
+// Decompress returns the uncompressed data, detecting the compression
+// algorithm from data's header. Uncompressed data is returned unchanged. The
+// returned slice is only valid until the next call to Decompress.
+func (d *decompressor) Decompress(data []byte) ([]byte, error) {
+	switch {
+	case isGzipData(data):
+		if err := d.gzr.Reset(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		buf := bytes.NewBuffer(d.buf[:0])
+		if _, err := io.Copy(buf, &d.gzr); err != nil {
+			return nil, fmt.Errorf("decompressing gzip data: %v", err)
+		}
+		d.buf = buf.Bytes()
+		return d.buf, nil
+	case isZstdData(data):
+		if d.zr == nil {
+			zr, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			d.zr = zr
+		}
+		out, err := d.zr.DecodeAll(data, d.buf[:0])
+		if err != nil {
+			return nil, fmt.Errorf("decompressing zstd data: %v", err)
+		}
+		d.buf = out
+		return d.buf, nil
+	default:
+		return data, nil
+	}
+}
+
+// attachmentCompressor compresses profiles before they are uploaded. It is not
+// safe for concurrent use.
+type attachmentCompressor struct {
+	algorithm string
+	w         compressor
+	d         decompressor
+	buf       bytes.Buffer
+}
+
+func newAttachmentCompressor(algorithm string, level int) *attachmentCompressor {
+	return &attachmentCompressor{algorithm: algorithm, w: newCompressor(algorithm, level)}
+}
+
+// Compress returns data compressed with the configured algorithm. Data that is
+// already compressed with it, e.g. delta profiles, is returned unchanged,
+// other compressed data is re-compressed. With the legacy algorithm, data is
+// always returned unchanged. The returned bool reports whether the data was
+// compressed by this call.
+func (c *attachmentCompressor) Compress(data []byte) ([]byte, bool, error) {
+	switch c.algorithm {
+	case compressionLegacy:
+		return data, false, nil
+	case compressionGzip:
+		if isGzipData(data) {
+			return data, false, nil
+		}
+	case compressionZstd:
+		if isZstdData(data) {
+			return data, false, nil
+		}
+	}
+	raw, err := c.d.Decompress(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if c.algorithm == compressionNone {
+		return copyBytes(raw), false, nil
+	}
+	c.buf.Reset()
+	c.w.Reset(&c.buf)
+	if _, err := c.w.Write(raw); err != nil {
+		return nil, false, err
+	} else if err := c.w.Close(); err != nil {
+		return nil, false, err
+	}
+	return copyBytes(c.buf.Bytes()), true, nil
+}
+
+// Suffix returns the suffix to append to the name of an attachment compressed
+// by Compress, so that the backend can tell the compressed attachments from the
+// uncompressed ones. gzip compressed pprof profiles don't need one, since the
+// pprof format is gzip compressed already.
+func (c *attachmentCompressor) Suffix(name string) string {
+	switch c.algorithm {
+	case compressionGzip:
+		if !strings.HasSuffix(name, ".pprof") {
+			return ".gz"
+		}
+	case compressionZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// copyBytes returns a copy of b. Profiles are retained in case their upload
+// fails, so they must not share memory with reused buffers.
+func copyBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+// compressBody returns body compressed with the given algorithm, for use with
+// the matching Content-Encoding header.
+func compressBody(algorithm string, level int, body []byte) ([]byte, error) {
+	w := newCompressor(algorithm, level)
+	var buf bytes.Buffer
+	w.Reset(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	} else if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package profiler
+
+import (
+	"bytes"
+	"testing"
+
+	pprofile "github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+)
+
+func TestValidateCompression(t *testing.T) {
+	for _, alg := range []string{"", "none", "gzip", "zstd"} {
+		assert.NoError(t, validateCompression(alg, 0), alg)
+	}
+	assert.NoError(t, validateCompression("gzip", 9))
+	assert.NoError(t, validateCompression("zstd", 19))
+	assert.Error(t, validateCompression("gzip", 10))
+	assert.Error(t, validateCompression("zstd", 23))
+	assert.Error(t, validateCompression("lz4", 0))
+}
+
+func TestAttachmentCompressor(t *testing.T) {
+	raw := []byte(`{"metrics": "a metrics.json-like payload, a metrics.json-like payload"}`)
+	gz := newAttachmentCompressor("gzip", 0)
+	gzData, compressed, err := gz.Compress(raw)
+	require.NoError(t, err)
+	require.True(t, compressed)
+	require.True(t, isGzipData(gzData))
+
+	t.Run("legacy", func(t *testing.T) {
+		c := newAttachmentCompressor("", 0)
+		for _, data := range [][]byte{raw, gzData} {
+			out, compressed, err := c.Compress(data)
+			require.NoError(t, err)
+			require.False(t, compressed)
+			require.Equal(t, data, out)
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		// Already gzip compressed data, e.g. runtime profiles, is
+		// returned unchanged.
+		out, compressed, err := gz.Compress(gzData)
+		require.NoError(t, err)
+		require.False(t, compressed)
+		require.Equal(t, gzData, out)
+	})
+
+	for _, level := range []int{0, 1, 19} {
+		c := newAttachmentCompressor("zstd", level)
+		for _, data := range [][]byte{raw, gzData} {
+			out, compressed, err := c.Compress(data)
+			require.NoError(t, err)
+			require.True(t, compressed)
+			require.True(t, isZstdData(out))
+			var d decompressor
+			got, err := d.Decompress(out)
+			require.NoError(t, err)
+			require.Equal(t, raw, got)
+
+			again, compressed, err := c.Compress(out)
+			require.NoError(t, err)
+			require.False(t, compressed)
+			require.Equal(t, out, again)
+		}
+	}
+
+	t.Run("none", func(t *testing.T) {
+		c := newAttachmentCompressor("none", 0)
+		out, compressed, err := c.Compress(gzData)
+		require.NoError(t, err)
+		require.False(t, compressed)
+		require.Equal(t, raw, out)
+	})
+}
+
+func TestAttachmentSuffix(t *testing.T) {
+	for _, tt := range []struct {
+		algorithm, name, want string
+	}{
+		{"", "cpu.pprof", ""},
+		{"", "metrics.json", ""},
+		{"none", "go.trace", ""},
+		{"gzip", "cpu.pprof", ""},
+		{"gzip", "metrics.json", ".gz"},
+		{"gzip", "go.trace", ".gz"},
+		{"zstd", "delta-heap.pprof", ".zst"},
+		{"zstd", "metrics.json", ".zst"},
+	} {
+		c := newAttachmentCompressor(tt.algorithm, 0)
+		assert.Equal(t, tt.want, c.Suffix(tt.name), "%s / %s", tt.algorithm, tt.name)
+	}
+}
+
+func TestFastDeltaProfilerCompression(t *testing.T) {
+	prof, err := pprofutils.Text{}.Convert(bytes.NewReader([]byte("x/count\nmain;foo 5\nmain;bar 3")))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, prof.Write(&buf))
+
+	for alg, check := range map[string]func([]byte) bool{
+		"":     isGzipData,
+		"gzip": isGzipData,
+		"zstd": isZstdData,
+		"none": func(data []byte) bool { return !isGzipData(data) && !isZstdData(data) },
+	} {
+		cfg := &config{compression: alg}
+		dp := newFastDeltaProfiler(cfg, pprofutils.ValueType{Type: "x", Unit: "count"})
+		for i := 0; i < 2; i++ {
+			out, err := dp.Delta(buf.Bytes())
+			require.NoError(t, err)
+			require.True(t, check(out), alg)
+			var d decompressor
+			raw, err := d.Decompress(out)
+			require.NoError(t, err)
+			_, err = pprofile.ParseUncompressed(raw)
+			require.NoError(t, err, alg)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/pprof/profile"
+	"github.com/klauspost/compress/zstd"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/fastdelta"
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+)
+
+// readProfile reads the profile at path, which can be a gzip or zstd
+// compressed or uncompressed pprof profile, or a profile in the folded text format
+// understood by pprofutils.Text, and returns it as an uncompressed pprof
+// profile suitable for fastdelta.
+func readProfile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeProfile(data)
+}
+
+// normalizeProfile parses data as pprof, falling back to the folded text
+// format, and re-encodes it as an uncompressed pprof profile.
+//
+// fastdelta matches samples by the instruction addresses of their locations,
+// which only works for profiles taken from the same binary. The addresses are
+// replaced by a hash of the symbolized frames of each location, so that
+// profiles from different builds of a program, e.g. before and after a
+// change in a CI benchmark, can be compared as well.
+func normalizeProfile(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		// zstd compressed, see profiler.WithUploadCompression
+		d, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		if data, err = d.DecodeAll(data, nil); err != nil {
+			return nil, err
+		}
+	}
+	prof, err := profile.ParseData(data)
+	if err != nil {
+		var textErr error
+		prof, textErr = pprofutils.Text{}.Convert(bytes.NewReader(data))
+		if textErr != nil {
+			return nil, fmt.Errorf("neither a pprof (%v) nor a text profile (%v)", err, textErr)
+		}
+	}
+	for _, loc := range prof.Location {
+		if len(loc.Line) == 0 {
+			continue
+		}
+		h := fnv.New64a()
+		for _, l := range loc.Line {
+			if l.Function != nil {
+				io.WriteString(h, l.Function.Name)
+				io.WriteString(h, l.Function.Filename)
+			}
+			io.WriteString(h, strconv.FormatInt(l.Line, 10))
+		}
+		loc.Address = h.Sum64()
+	}
+	var buf bytes.Buffer
+	if err := prof.WriteUncompressed(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// diff returns the profile b-a. The difference is computed for all sample
+// types. Samples that only exist in a are not part of the result.
+func diff(a, b []byte) (*profile.Profile, error) {
+	profA, err := profile.ParseData(a)
+	if err != nil {
+		return nil, err
+	}
+	profB, err := profile.ParseData(b)
+	if err != nil {
+		return nil, err
+	}
+	if got, want := sampleTypes(profB), sampleTypes(profA); got != want {
+		return nil, fmt.Errorf("sample types don't match: %s vs %s", want, got)
+	}
+
+	var fields []pprofutils.ValueType
+	for _, st := range profA.SampleType {
+		fields = append(fields, pprofutils.ValueType{Type: st.Type, Unit: st.Unit})
+	}
+	dc := fastdelta.NewDeltaComputer(fields...)
+	if err := dc.Delta(a, io.Discard); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := dc.Delta(b, &out); err != nil {
+		return nil, err
+	}
+	return profile.ParseData(out.Bytes())
+}
+
+func sampleTypes(p *profile.Profile) string {
+	var types []string
+	for _, st := range p.SampleType {
+		types = append(types, st.Type+"/"+st.Unit)
+	}
+	return strings.Join(types, " ")
+}
+
+// sampleIndex returns the index of the sample type named sampleType, or of
+// the default sample type if sampleType is empty.
+func sampleIndex(p *profile.Profile, sampleType string) (int, error) {
+	if sampleType == "" {
+		sampleType = p.DefaultSampleType
+	}
+	if sampleType == "" {
+		return len(p.SampleType) - 1, nil
+	}
+	for i, st := range p.SampleType {
+		if st.Type == sampleType {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("sample type %q not found, available: %s", sampleType, sampleTypes(p))
+}
+
+// functionDelta is the change of the flat and cumulative values of a function.
+type functionDelta struct {
+	Name string
+	// Flat is the change of the value of samples in which the function is
+	// the leaf frame.
+	Flat int64
+	// Cum is the change of the value of samples in which the function
+	// appears.
+	Cum int64
+}
+
+// topFunctions returns the n functions whose flat value increased the most in
+// the delta profile p, for the sample type at index idx.
+func topFunctions(p *profile.Profile, idx, n int) []functionDelta {
+	byName := make(map[string]*functionDelta)
+	get := func(name string) *functionDelta {
+		fd, ok := byName[name]
+		if !ok {
+			fd = &functionDelta{Name: name}
+			byName[name] = fd
+		}
+		return fd
+	}
+	for _, s := range p.Sample {
+		v := s.Value[idx]
+		seen := make(map[string]struct{})
+		for i, loc := range s.Location {
+			for j, l := range loc.Line {
+				name := "?"
+				if l.Function != nil {
+					name = l.Function.Name
+				}
+				// The first line of the first location is the leaf
+				// frame, the following lines are the callers it
+				// was inlined into.
+				if i == 0 && j == 0 {
+					get(name).Flat += v
+				}
+				if _, ok := seen[name]; !ok {
+					seen[name] = struct{}{}
+					get(name).Cum += v
+				}
+			}
+		}
+	}
+
+	var top []functionDelta
+	for _, fd := range byName {
+		if fd.Flat > 0 {
+			top = append(top, *fd)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Flat != top[j].Flat {
+			return top[i].Flat > top[j].Flat
+		} else if top[i].Cum != top[j].Cum {
+			return top[i].Cum > top[j].Cum
+		}
+		return top[i].Name < top[j].Name
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// printTop writes the top regressions of the delta profile p to w.
+func printTop(w io.Writer, p *profile.Profile, sampleType string, n int) error {
+	idx, err := sampleIndex(p, sampleType)
+	if err != nil {
+		return err
+	}
+	st := p.SampleType[idx]
+	top := topFunctions(p, idx, n)
+	if len(top) == 0 {
+		_, err := fmt.Fprintf(w, "no regressions of %s/%s\n", st.Type, st.Unit)
+		return err
+	}
+	fmt.Fprintf(w, "top regressions of %s/%s:\n", st.Type, st.Unit)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "flat\tcum\tfunction\n")
+	for _, fd := range top {
+		fmt.Fprintf(tw, "%+d\t%+d\t%s\n", fd.Flat, fd.Cum, fd.Name)
+	}
+	return tw.Flush()
+}
+
+// batchPairs returns the profiles found in both the first and the last batch
+// of dir, as written by the profiler when an output directory is configured:
+// one sub-directory per batch, named after the time at which the batch ended.
+// The returned map associates every profile name, without the compression
+// suffix of the profiler.WithUploadCompression attachments, to its path in
+// the first and last batch.
+func batchPairs(dir string) (map[string][2]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var batches []string
+	for _, e := range entries {
+		if e.IsDir() {
+			batches = append(batches, e.Name())
+		}
+	}
+	if len(batches) < 2 {
+		return nil, fmt.Errorf("%s: found %d batches, need at least 2", dir, len(batches))
+	}
+	// The batch names are timestamps in basic ISO 8601 format, so sorting
+	// them lexically sorts them chronologically.
+	sort.Strings(batches)
+	first := filepath.Join(dir, batches[0])
+	last := filepath.Join(dir, batches[len(batches)-1])
+
+	firstProfiles, err := batchProfiles(first)
+	if err != nil {
+		return nil, err
+	}
+	lastProfiles, err := batchProfiles(last)
+	if err != nil {
+		return nil, err
+	}
+	pairs := make(map[string][2]string)
+	for name, b := range lastProfiles {
+		if a, ok := firstProfiles[name]; ok {
+			pairs[name] = [2]string{a, b}
+		}
+	}
+	return pairs, nil
+}
+
+// batchProfiles returns the paths of the pprof profiles of the batch
+// directory dir, by profile name. The profiles can be compressed, in which
+// case their name is the file name without the compression suffix.
+func batchProfiles(dir string) (map[string]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[string]string)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(f.Name(), ".zst"), ".gz")
+		if !strings.HasSuffix(name, ".pprof") {
+			continue
+		}
+		profiles[name] = filepath.Join(dir, f.Name())
+	}
+	return profiles, nil
+}
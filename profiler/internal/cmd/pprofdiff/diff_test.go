@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+)
+
+const (
+	beforeText = `
+samples/count cpu/nanoseconds
+main;foo 5 50
+main;foo;bar 3 30
+main;foobar 4 40
+`
+	afterText = `
+samples/count cpu/nanoseconds
+main;foo 8 80
+main;foo;bar 3 30
+main;foobar 5 50
+main;baz 2 20
+`
+)
+
+func TestDiff(t *testing.T) {
+	before, err := normalizeProfile([]byte(strings.TrimSpace(beforeText)))
+	require.NoError(t, err)
+	after, err := normalizeProfile([]byte(strings.TrimSpace(afterText)))
+	require.NoError(t, err)
+
+	delta, err := diff(before, after)
+	require.NoError(t, err)
+
+	var text bytes.Buffer
+	require.NoError(t, pprofutils.Protobuf{SampleTypes: true}.Convert(delta, &text))
+	require.Equal(t, strings.TrimSpace(`
+samples/count cpu/nanoseconds
+main;foo 3 30
+main;baz 2 20
+main;foobar 1 10
+`)+"\n", text.String())
+
+	idx, err := sampleIndex(delta, "cpu")
+	require.NoError(t, err)
+	require.Equal(t, []functionDelta{
+		{Name: "foo", Flat: 30, Cum: 30},
+		{Name: "baz", Flat: 20, Cum: 20},
+		{Name: "foobar", Flat: 10, Cum: 10},
+	}, topFunctions(delta, idx, 0))
+	require.Len(t, topFunctions(delta, idx, 1), 1)
+
+	_, err = sampleIndex(delta, "nope")
+	require.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	writeFile := func(t *testing.T, path, data string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(strings.TrimSpace(data)), 0644))
+	}
+
+	t.Run("files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "before.txt"), beforeText)
+		writeFile(t, filepath.Join(dir, "after.txt"), afterText)
+		out := filepath.Join(dir, "delta.pprof")
+
+		var stdout bytes.Buffer
+		err := run([]string{"-top", "2", "-o", out, filepath.Join(dir, "before.txt"), filepath.Join(dir, "after.txt")}, &stdout)
+		require.NoError(t, err)
+		require.Equal(t, strings.TrimLeft(`
+top regressions of cpu/nanoseconds:
+flat  cum  function
++30   +30  foo
++20   +20  baz
+`, "\n"), stdout.String())
+		require.FileExists(t, out)
+
+		// The delta profile can be diffed again
+		stdout.Reset()
+		err = run([]string{"-text", out, out}, &stdout)
+		require.NoError(t, err)
+		require.Equal(t, "samples/count cpu/nanoseconds\n", stdout.String())
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "20230101T000100Z", "cpu.pprof"), beforeText)
+		writeFile(t, filepath.Join(dir, "20230101T000200Z", "cpu.pprof"), beforeText)
+		writeFile(t, filepath.Join(dir, "20230101T000300Z", "cpu.pprof"), afterText)
+		writeFile(t, filepath.Join(dir, "20230101T000300Z", "metrics.json"), "{}")
+
+		var stdout bytes.Buffer
+		err := run([]string{"-dir", dir, "-text"}, &stdout)
+		require.NoError(t, err)
+		require.Equal(t, strings.TrimLeft(`
+== cpu.pprof ==
+samples/count cpu/nanoseconds
+main;foo 3 30
+main;baz 2 20
+main;foobar 1 10
+`, "\n"), stdout.String())
+	})
+
+	t.Run("dir-zstd", func(t *testing.T) {
+		enc, err := zstd.NewWriter(nil)
+		require.NoError(t, err)
+		defer enc.Close()
+		writeProfile := func(t *testing.T, path, text string) {
+			data, err := normalizeProfile([]byte(strings.TrimSpace(text)))
+			require.NoError(t, err)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+			require.NoError(t, os.WriteFile(path, enc.EncodeAll(data, nil), 0644))
+		}
+		dir := t.TempDir()
+		writeProfile(t, filepath.Join(dir, "20230101T000100Z", "cpu.pprof.zst"), beforeText)
+		writeProfile(t, filepath.Join(dir, "20230101T000200Z", "cpu.pprof.zst"), afterText)
+		writeFile(t, filepath.Join(dir, "20230101T000200Z", "metrics.json.zst"), "{}")
+
+		var stdout bytes.Buffer
+		err = run([]string{"-dir", dir, "-text"}, &stdout)
+		require.NoError(t, err)
+		require.Equal(t, strings.TrimLeft(`
+== cpu.pprof ==
+samples/count cpu/nanoseconds
+main;foo 3 30
+main;baz 2 20
+main;foobar 1 10
+`, "\n"), stdout.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.txt"), beforeText)
+		writeFile(t, filepath.Join(dir, "b.txt"), "x/count\nmain 1")
+		var stdout bytes.Buffer
+		require.Error(t, run([]string{filepath.Join(dir, "a.txt")}, &stdout))
+		require.Error(t, run([]string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}, &stdout))
+		require.Error(t, run([]string{"-dir", dir}, &stdout))
+	})
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+// Command pprofdiff compares pprof profiles offline, e.g. to spot regressions
+// between two runs of a benchmark in CI. It computes the difference between
+// two profiles using the same delta implementation as the profiler, and
+// prints the functions whose value increased the most.
+//
+// Usage:
+//
+//	go run ./profiler/internal/cmd/pprofdiff [flags] before.pprof after.pprof
+//	go run ./profiler/internal/cmd/pprofdiff [flags] -dir <output dir>
+//
+// Profiles can be gzip or zstd compressed or uncompressed pprof files, or folded text files
+// as produced by the -text flag. With -dir, the first and last batches of
+// profiles written by the profiler to an output directory (see
+// DD_PROFILING_OUTPUT_DIR) are compared, whatever their upload compression.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/google/pprof/profile"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "pprofdiff: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("pprofdiff", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: pprofdiff [flags] <before> <after>\n       pprofdiff [flags] -dir <dir>\n\n")
+		fs.PrintDefaults()
+	}
+	var (
+		dir        = fs.String("dir", "", "compare the first and last batches of profiles in this profiler output directory")
+		top        = fs.Int("top", 10, "number of functions to print, 0 prints all of them")
+		sampleType = fs.String("sample_type", "", "sample type used to rank functions, defaults to the profile's default sample type")
+		text       = fs.Bool("text", false, "print the delta profile in folded text format instead of the top functions")
+		output     = fs.String("o", "", "write the delta profile in pprof format to this file")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	print := func(delta *profile.Profile) error {
+		if *text {
+			return pprofutils.Protobuf{SampleTypes: true}.Convert(delta, stdout)
+		}
+		return printTop(stdout, delta, *sampleType, *top)
+	}
+
+	if *dir != "" {
+		if fs.NArg() != 0 || *output != "" {
+			fs.Usage()
+			return fmt.Errorf("-dir can't be combined with profile arguments or -o")
+		}
+		pairs, err := batchPairs(*dir)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for name := range pairs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stdout, "== %s ==\n", name)
+			delta, err := diffFiles(pairs[name][0], pairs[name][1])
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			} else if err := print(delta); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		return nil
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected 2 profiles, got %d", fs.NArg())
+	}
+	delta, err := diffFiles(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := delta.Write(f); err != nil {
+			return err
+		}
+	}
+	return print(delta)
+}
+
+// diffFiles returns the difference between the profiles at paths a and b.
+func diffFiles(a, b string) (*profile.Profile, error) {
+	before, err := readProfile(a)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", a, err)
+	}
+	after, err := readProfile(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b, err)
+	}
+	return diff(before, after)
+}
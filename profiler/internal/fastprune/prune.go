@@ -0,0 +1,381 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+/*
+Package fastprune reduces the size of pprof profiles by dropping the samples
+with the lowest weight, along with the locations, functions and strings that
+are no longer referenced by any of the remaining samples.
+
+The weight of a sample is the absolute value of its default sample type (or
+the last sample type if no default is set, following pprof's convention).
+
+# Implementation
+
+Like fastdelta, pruning is done in a few passes over the input using pproflite
+in order to keep memory usage low:
+
+Pass 1
+* Find the sample type used for weighing samples.
+* Index the string table.
+* Record the weight of every sample (this requires a second look at the
+samples, as the sample types may be encoded after them).
+
+Pass 2
+* Write out the samples we keep and track the locations and strings they
+reference.
+
+Pass 3
+* Write out all remaining records except functions and strings, dropping
+locations that are not referenced by a kept sample.
+
+Pass 4
+* Write out the functions referenced by the kept locations.
+
+Pass 5
+* Write out the string table, replacing unreferenced strings with empty
+strings to preserve the index of the remaining ones, followed by the
+strings for the comments describing what was pruned.
+*/
+package fastprune
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pproflite"
+)
+
+// Stats describes the outcome of a call to Prune.
+type Stats struct {
+	// Samples is the number of samples in the input profile.
+	Samples int
+	// DroppedSamples is the number of samples that were removed.
+	DroppedSamples int
+	// Weight is the total weight of all samples in the input profile.
+	Weight int64
+	// DroppedWeight is the total weight of all removed samples.
+	DroppedWeight int64
+	// DroppedLocations is the number of locations that were removed.
+	DroppedLocations int
+	// DroppedFunctions is the number of functions that were removed.
+	DroppedFunctions int
+	// WeightType is the "type/unit" of the sample value used for weighing
+	// samples, e.g. "inuse_space/bytes".
+	WeightType string
+}
+
+// Pruner removes the lowest weight samples from pprof-encoded profiles. A
+// Pruner can be reused, but it is not safe for concurrent use.
+type Pruner struct {
+	decoder pproflite.Decoder
+	encoder pproflite.Encoder
+
+	strings           [][]byte
+	weights           []int64
+	keep              []bool
+	includedLocations map[uint64]struct{}
+	includedFunctions map[uint64]struct{}
+	includedStrings   map[int64]struct{}
+	weightType        string
+}
+
+// NewPruner returns a new Pruner.
+func NewPruner() *Pruner {
+	return &Pruner{
+		includedLocations: make(map[uint64]struct{}),
+		includedFunctions: make(map[uint64]struct{}),
+		includedStrings:   make(map[int64]struct{}),
+	}
+}
+
+// Prune writes the uncompressed pprof-encoded profile p to out, keeping only
+// the maxSamples samples with the highest weight. If p has no more than
+// maxSamples samples, it is written unchanged. When samples are dropped, a
+// comment describing what was removed is added to the profile.
+func (pr *Pruner) Prune(p []byte, maxSamples int, out io.Writer) (stats Stats, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("internal panic during profile pruning: %v", e)
+		}
+	}()
+	if maxSamples < 0 {
+		maxSamples = 0
+	}
+
+	pr.reset()
+	pr.decoder.Reset(p)
+	if err := pr.pass1Index(); err != nil {
+		return stats, fmt.Errorf("pass1Index: %w", err)
+	}
+	stats = pr.selectSamples(maxSamples)
+	if stats.DroppedSamples == 0 {
+		_, err := out.Write(p)
+		return stats, err
+	}
+
+	pr.encoder.Reset(out)
+	if err := pr.pass2WriteSamples(); err != nil {
+		return stats, fmt.Errorf("pass2WriteSamples: %w", err)
+	} else if err := pr.pass3WriteRecords(&stats); err != nil {
+		return stats, fmt.Errorf("pass3WriteRecords: %w", err)
+	} else if err := pr.pass4WriteFunctions(&stats); err != nil {
+		return stats, fmt.Errorf("pass4WriteFunctions: %w", err)
+	} else if err := pr.pass5WriteStringTable(stats); err != nil {
+		return stats, fmt.Errorf("pass5WriteStringTable: %w", err)
+	}
+	return stats, nil
+}
+
+func (pr *Pruner) reset() {
+	pr.strings = pr.strings[:0]
+	pr.weights = pr.weights[:0]
+	pr.keep = pr.keep[:0]
+	pr.weightType = ""
+	for k := range pr.includedLocations {
+		delete(pr.includedLocations, k)
+	}
+	for k := range pr.includedFunctions {
+		delete(pr.includedFunctions, k)
+	}
+	for k := range pr.includedStrings {
+		delete(pr.includedStrings, k)
+	}
+	// always include the zero-index empty string
+	pr.includedStrings[0] = struct{}{}
+}
+
+func (pr *Pruner) pass1Index() error {
+	var (
+		sampleTypes       []pproflite.ValueType
+		defaultSampleType int64
+	)
+	err := pr.decoder.FieldEach(
+		func(f pproflite.Field) error {
+			switch t := f.(type) {
+			case *pproflite.SampleType:
+				sampleTypes = append(sampleTypes, t.ValueType)
+			case *pproflite.DefaultSampleType:
+				defaultSampleType = t.Value
+			case *pproflite.StringTable:
+				pr.strings = append(pr.strings, t.Value)
+			default:
+				return fmt.Errorf("unexpected field: %T", f)
+			}
+			return nil
+		},
+		pproflite.SampleTypeDecoder,
+		pproflite.DefaultSampleTypeDecoder,
+		pproflite.StringTableDecoder,
+	)
+	if err != nil {
+		return err
+	} else if len(sampleTypes) == 0 {
+		return fmt.Errorf("profile has no sample types")
+	}
+
+	weightIdx := len(sampleTypes) - 1
+	if defaultSampleType != 0 {
+		for i, st := range sampleTypes {
+			if st.Type == defaultSampleType {
+				weightIdx = i
+				break
+			}
+		}
+	}
+	st := sampleTypes[weightIdx]
+	pr.weightType = pr.string(st.Type) + "/" + pr.string(st.Unit)
+
+	// The sample type might be encoded after the samples, so we need a
+	// second look at the samples once we know how to weigh them.
+	return pr.decoder.FieldEach(
+		func(f pproflite.Field) error {
+			sample, ok := f.(*pproflite.Sample)
+			if !ok {
+				return fmt.Errorf("unexpected field: %T", f)
+			} else if weightIdx >= len(sample.Value) {
+				return fmt.Errorf("sample has %d values, want %d", len(sample.Value), len(sampleTypes))
+			}
+			w := sample.Value[weightIdx]
+			if w < 0 {
+				w = -w
+			}
+			pr.weights = append(pr.weights, w)
+			return nil
+		},
+		pproflite.SampleDecoder,
+	)
+}
+
+// selectSamples decides which samples to keep. Samples are kept in order of
+// decreasing weight, ties are broken by keeping the samples that appear first
+// in the profile.
+func (pr *Pruner) selectSamples(maxSamples int) Stats {
+	stats := Stats{Samples: len(pr.weights), WeightType: pr.weightType}
+	order := make([]int, len(pr.weights))
+	for i, w := range pr.weights {
+		order[i] = i
+		stats.Weight += w
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return pr.weights[order[i]] > pr.weights[order[j]]
+	})
+	for i := 0; i < len(pr.weights); i++ {
+		pr.keep = append(pr.keep, false)
+	}
+	for i, idx := range order {
+		if i < maxSamples {
+			pr.keep[idx] = true
+			continue
+		}
+		stats.DroppedSamples++
+		stats.DroppedWeight += pr.weights[idx]
+	}
+	return stats
+}
+
+func (pr *Pruner) pass2WriteSamples() error {
+	i := 0
+	return pr.decoder.FieldEach(
+		func(f pproflite.Field) error {
+			sample, ok := f.(*pproflite.Sample)
+			if !ok {
+				return fmt.Errorf("unexpected field: %T", f)
+			}
+			keep := pr.keep[i]
+			i++
+			if !keep {
+				return nil
+			}
+			for _, id := range sample.LocationID {
+				pr.includedLocations[id] = struct{}{}
+			}
+			for _, l := range sample.Label {
+				pr.includeStrings(l.Key, l.Str, l.NumUnit)
+			}
+			return pr.encoder.Encode(sample)
+		},
+		pproflite.SampleDecoder,
+	)
+}
+
+func (pr *Pruner) pass3WriteRecords(stats *Stats) error {
+	return pr.decoder.FieldEach(
+		func(f pproflite.Field) error {
+			switch t := f.(type) {
+			case *pproflite.SampleType:
+				pr.includeStrings(t.Type, t.Unit)
+			case *pproflite.Mapping:
+				pr.includeStrings(t.Filename, t.BuildID)
+			case *pproflite.LocationFast:
+				if _, ok := pr.includedLocations[t.ID]; !ok {
+					stats.DroppedLocations++
+					return nil
+				}
+				for _, id := range t.FunctionID {
+					pr.includedFunctions[id] = struct{}{}
+				}
+			case *pproflite.DropFrames:
+				pr.includeStrings(t.Value)
+			case *pproflite.KeepFrames:
+				pr.includeStrings(t.Value)
+			case *pproflite.TimeNanos:
+			case *pproflite.DurationNanos:
+			case *pproflite.PeriodType:
+				pr.includeStrings(t.Type, t.Unit)
+			case *pproflite.Period:
+			case *pproflite.Comment:
+				pr.includeStrings(t.Value)
+			case *pproflite.DefaultSampleType:
+				pr.includeStrings(t.Value)
+			default:
+				return fmt.Errorf("unexpected field: %T", f)
+			}
+			return pr.encoder.Encode(f)
+		},
+		pproflite.SampleTypeDecoder,
+		pproflite.MappingDecoder,
+		pproflite.LocationFastDecoder,
+		pproflite.DropFramesDecoder,
+		pproflite.KeepFramesDecoder,
+		pproflite.TimeNanosDecoder,
+		pproflite.DurationNanosDecoder,
+		pproflite.PeriodTypeDecoder,
+		pproflite.PeriodDecoder,
+		pproflite.CommentDecoder,
+		pproflite.DefaultSampleTypeDecoder,
+	)
+}
+
+func (pr *Pruner) pass4WriteFunctions(stats *Stats) error {
+	return pr.decoder.FieldEach(
+		func(f pproflite.Field) error {
+			fn, ok := f.(*pproflite.Function)
+			if !ok {
+				return fmt.Errorf("unexpected field: %T", f)
+			}
+			if _, ok := pr.includedFunctions[fn.ID]; !ok {
+				stats.DroppedFunctions++
+				return nil
+			}
+			pr.includeStrings(fn.Name, fn.SystemName, fn.FileName)
+			return pr.encoder.Encode(f)
+		},
+		pproflite.FunctionDecoder,
+	)
+}
+
+func (pr *Pruner) pass5WriteStringTable(stats Stats) error {
+	var (
+		idx int64
+		str pproflite.StringTable
+	)
+	for _, s := range pr.strings {
+		str.Value = s
+		if _, ok := pr.includedStrings[idx]; !ok {
+			str.Value = nil
+		}
+		if err := pr.encoder.Encode(&str); err != nil {
+			return err
+		}
+		idx++
+	}
+	for _, c := range Comments(stats) {
+		str.Value = []byte(c)
+		if err := pr.encoder.Encode(&str); err != nil {
+			return err
+		} else if err := pr.encoder.Encode(&pproflite.Comment{Value: idx}); err != nil {
+			return err
+		}
+		idx++
+	}
+	return nil
+}
+
+// Comments returns the profile comments describing the pruning summarized by
+// stats.
+func Comments(stats Stats) []string {
+	if stats.DroppedSamples == 0 {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("pruned %d of %d samples to fit size limits", stats.DroppedSamples, stats.Samples),
+		fmt.Sprintf("pruned %d of %d %s", stats.DroppedWeight, stats.Weight, stats.WeightType),
+		fmt.Sprintf("pruned %d locations and %d functions", stats.DroppedLocations, stats.DroppedFunctions),
+	}
+}
+
+func (pr *Pruner) includeStrings(indices ...int64) {
+	for _, i := range indices {
+		pr.includedStrings[i] = struct{}{}
+	}
+}
+
+func (pr *Pruner) string(i int64) string {
+	if i < 0 || int(i) >= len(pr.strings) {
+		return ""
+	}
+	return string(pr.strings[i])
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package fastprune_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/fastprune"
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
+)
+
+func TestPrune(t *testing.T) {
+	t.Run("heap", func(t *testing.T) {
+		data, err := os.ReadFile(filepath.Join("testdata", "heap.pprof"))
+		require.NoError(t, err)
+		inProf, err := profile.ParseData(data)
+		require.NoError(t, err)
+		var inBuf bytes.Buffer
+		require.NoError(t, inProf.WriteUncompressed(&inBuf))
+
+		const maxSamples = 10
+		require.Greater(t, len(inProf.Sample), maxSamples)
+
+		var outBuf bytes.Buffer
+		stats, err := fastprune.NewPruner().Prune(inBuf.Bytes(), maxSamples, &outBuf)
+		require.NoError(t, err)
+		require.Equal(t, len(inProf.Sample), stats.Samples)
+		require.Equal(t, len(inProf.Sample)-maxSamples, stats.DroppedSamples)
+		require.Equal(t, "alloc_space/bytes", stats.WeightType) // default sample type
+		require.Less(t, outBuf.Len(), inBuf.Len())
+
+		outProf, err := profile.ParseData(outBuf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, outProf.CheckValid())
+		require.Len(t, outProf.Sample, maxSamples)
+		require.Equal(t, len(inProf.Location)-stats.DroppedLocations, len(outProf.Location))
+		require.Equal(t, len(inProf.Function)-stats.DroppedFunctions, len(outProf.Function))
+		require.Equal(t, fastprune.Comments(stats), outProf.Comments)
+
+		// The kept samples must be the ones with the highest weight.
+		weightIdx := 1 // alloc_space/bytes
+		var inWeights, outWeights []int64
+		for _, s := range inProf.Sample {
+			inWeights = append(inWeights, s.Value[weightIdx])
+		}
+		for _, s := range outProf.Sample {
+			outWeights = append(outWeights, s.Value[weightIdx])
+		}
+		sort.Slice(inWeights, func(i, j int) bool { return inWeights[i] > inWeights[j] })
+		sort.Slice(outWeights, func(i, j int) bool { return outWeights[i] > outWeights[j] })
+		require.Equal(t, inWeights[:maxSamples], outWeights)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		in := textProfile(t, `
+samples/count cpu/nanoseconds
+main;foo 5 50
+main;bar 3 30
+`)
+		var out bytes.Buffer
+		stats, err := fastprune.NewPruner().Prune(in, 2, &out)
+		require.NoError(t, err)
+		require.Equal(t, 0, stats.DroppedSamples)
+		require.Equal(t, in, out.Bytes())
+	})
+
+	t.Run("text", func(t *testing.T) {
+		in := textProfile(t, `
+samples/count cpu/nanoseconds
+main;foo 5 50
+main;bar 3 -70
+main;baz;qux 1 10
+main;baz 2 20
+`)
+		var out bytes.Buffer
+		pr := fastprune.NewPruner()
+		for i := 0; i < 2; i++ {
+			out.Reset()
+			stats, err := pr.Prune(in, 2, &out)
+			require.NoError(t, err)
+			require.Equal(t, fastprune.Stats{
+				Samples:          4,
+				DroppedSamples:   2,
+				Weight:           150,
+				DroppedWeight:    30,
+				DroppedLocations: 5,
+				DroppedFunctions: 5,
+				WeightType:       "cpu/nanoseconds",
+			}, stats)
+		}
+		outProf, err := profile.ParseData(out.Bytes())
+		require.NoError(t, err)
+		var text bytes.Buffer
+		require.NoError(t, pprofutils.Protobuf{SampleTypes: true}.Convert(outProf, &text))
+		require.Equal(t, strings.TrimSpace(`
+samples/count cpu/nanoseconds
+main;foo 5 50
+main;bar 3 -70
+`), strings.TrimSpace(text.String()))
+	})
+}
+
+func textProfile(t *testing.T, text string) []byte {
+	t.Helper()
+	prof, err := pprofutils.Text{}.Convert(strings.NewReader(strings.TrimSpace(text)))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, prof.WriteUncompressed(&buf))
+	return buf.Bytes()
+}
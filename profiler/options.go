@@ -51,6 +51,12 @@ const (
 	// DefaultDuration specifies the default length of the CPU profile snapshot.
 	DefaultDuration = time.Minute
 
+	// DefaultCustomLabelMaxValues specifies the default number of distinct
+	// values per custom profiler label key accepted in a profiling period. For
+	// more information or for changing this value, check
+	// WithCustomProfilerLabelMaxValues.
+	DefaultCustomLabelMaxValues = 100
+
 	// DefaultUploadTimeout specifies the default timeout for uploading profiles.
 	// It can be overwritten using the DD_PROFILING_UPLOAD_TIMEOUT env variable
 	// or the WithUploadTimeout option.
@@ -111,6 +117,14 @@ type config struct {
 	traceEnabled         bool
 	traceConfig          executionTraceConfig
 	endpointCountEnabled bool
+	maxProfileBytes      int
+	maxProfileSamples    int
+	customLabelKeys      []string
+	customLabelMaxValues int
+	remoteConfig         bool
+	compression          string
+	compressionLevel     int
+	bodyCompression      string
 }
 
 // logStartup records the configuration to the configured logger in JSON format
@@ -142,6 +156,14 @@ func logStartup(c *config) {
 		TracePeriod          string   `json:"execution_trace_period"`
 		TraceSizeLimit       int      `json:"execution_trace_size_limit"`
 		EndpointCountEnabled bool     `json:"endpoint_count_enabled"`
+		MaxProfileBytes      int      `json:"max_profile_bytes"`
+		MaxProfileSamples    int      `json:"max_profile_samples"`
+		CustomLabelKeys      []string `json:"custom_label_keys"`
+		CustomLabelMaxValues int      `json:"custom_label_max_values"`
+		RemoteConfig         bool     `json:"remote_configuration_enabled"`
+		Compression          string   `json:"compression"`
+		CompressionLevel     int      `json:"compression_level"`
+		BodyCompression      string   `json:"body_compression"`
 	}{
 		Date:                 time.Now().Format(time.RFC3339),
 		OSName:               osinfo.OSName(),
@@ -168,6 +190,14 @@ func logStartup(c *config) {
 		TracePeriod:          c.traceConfig.Period.String(),
 		TraceSizeLimit:       c.traceConfig.Limit,
 		EndpointCountEnabled: c.endpointCountEnabled,
+		MaxProfileBytes:      c.maxProfileBytes,
+		MaxProfileSamples:    c.maxProfileSamples,
+		CustomLabelKeys:      c.customLabelKeys,
+		CustomLabelMaxValues: c.customLabelMaxValues,
+		RemoteConfig:         c.remoteConfig,
+		Compression:          c.compression,
+		CompressionLevel:     c.compressionLevel,
+		BodyCompression:      c.bodyCompression,
 	}
 	for t := range c.types {
 		info.EnabledProfiles = append(info.EnabledProfiles, t.String())
@@ -222,6 +252,13 @@ func defaultConfig() (*config, error) {
 		deltaMethod:          os.Getenv("DD_PROFILING_DELTA_METHOD"),
 		logStartup:           internal.BoolEnv("DD_TRACE_STARTUP_LOGS", true),
 		endpointCountEnabled: internal.BoolEnv(traceprof.EndpointCountEnvVar, false),
+		maxProfileBytes:      internal.IntEnv("DD_PROFILING_MAX_PROFILE_BYTES", 0),
+		maxProfileSamples:    internal.IntEnv("DD_PROFILING_MAX_PROFILE_SAMPLES", 0),
+		customLabelMaxValues: DefaultCustomLabelMaxValues,
+		remoteConfig:         internal.BoolEnv("DD_PROFILING_REMOTE_CONFIGURATION_ENABLED", false),
+		compression:          os.Getenv("DD_PROFILING_COMPRESSION"),
+		compressionLevel:     internal.IntEnv("DD_PROFILING_COMPRESSION_LEVEL", 0),
+		bodyCompression:      os.Getenv("DD_PROFILING_BODY_COMPRESSION"),
 	}
 	c.tags = c.tags.Append(fmt.Sprintf("process_id:%d", os.Getpid()))
 	for _, t := range defaultProfileTypes {
@@ -541,6 +578,95 @@ func WithHostname(hostname string) Option {
 	}
 }
 
+// WithMaxProfileSize caps the size, in bytes, of every uploaded pprof profile.
+// Profiles exceeding the limit are pruned by dropping their lowest-weight
+// samples along with the locations, functions and strings that are no longer
+// needed. The number of dropped samples is recorded as comments in the
+// profile. A value of 0 disables the limit, which is the default. This option
+// takes precedence over the DD_PROFILING_MAX_PROFILE_BYTES env variable.
+func WithMaxProfileSize(bytes int) Option {
+	return func(cfg *config) {
+		cfg.maxProfileBytes = bytes
+	}
+}
+
+// WithMaxProfileSamples caps the number of samples of every uploaded pprof
+// profile. Profiles exceeding the limit are pruned the same way as described
+// for WithMaxProfileSize. A value of 0 disables the limit, which is the
+// default. This option takes precedence over the
+// DD_PROFILING_MAX_PROFILE_SAMPLES env variable.
+func WithMaxProfileSamples(n int) Option {
+	return func(cfg *config) {
+		cfg.maxProfileSamples = n
+	}
+}
+
+// WithCustomProfilerLabelKeys specifies the pprof label keys that spans are
+// allowed to apply using tracer.ProfilerLabel and tracer.SetProfilerLabel.
+// This allows to break down CPU and goroutine profiles by business dimensions
+// such as tenant or job type. Labels with other keys are ignored by the
+// tracer. The keys are also reported with every profile so they can be used as
+// attributes in the Datadog UI.
+func WithCustomProfilerLabelKeys(keys ...string) Option {
+	return func(cfg *config) {
+		cfg.customLabelKeys = append(cfg.customLabelKeys, keys...)
+	}
+}
+
+// WithCustomProfilerLabelMaxValues caps the number of distinct values per
+// custom label key, see WithCustomProfilerLabelKeys, during a profiling period.
+// Values exceeding the limit are replaced by "other" to bound the overhead of
+// labels and the cardinality of profiles. The default is given by
+// DefaultCustomLabelMaxValues. A value <= 0 disables the limit.
+func WithCustomProfilerLabelMaxValues(n int) Option {
+	return func(cfg *config) {
+		cfg.customLabelMaxValues = n
+	}
+}
+
+// WithRemoteConfiguration enables updating the enabled profile types, the CPU
+// profile duration, the mutex and block profile rates and the execution trace
+// configuration at runtime through Datadog remote configuration. Updates are
+// applied at the start of the next profiling period, and removing the remote
+// configuration restores the configuration given to Start. This requires
+// uploading profiles through the Datadog Agent. It is disabled by default, and
+// this option takes precedence over the
+// DD_PROFILING_REMOTE_CONFIGURATION_ENABLED env variable.
+func WithRemoteConfiguration(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.remoteConfig = enabled
+	}
+}
+
+// WithUploadCompression sets the compression algorithm and level used for the
+// profiles attached to uploads. Supported algorithms are "gzip", "zstd" and
+// "none". A level of 0 selects the default level of the algorithm, otherwise
+// it must be between 1 and 9 for gzip and between 1 and 22 for zstd. By
+// default, pprof profiles are uploaded gzip compressed and the other
+// attachments, such as metrics and execution traces, uncompressed. The names of
+// the compressed attachments get the ".gz" or ".zst" suffix, except for gzip
+// compressed pprof profiles. This option takes precedence over the
+// DD_PROFILING_COMPRESSION and DD_PROFILING_COMPRESSION_LEVEL env variables.
+func WithUploadCompression(algorithm string, level int) Option {
+	return func(cfg *config) {
+		cfg.compression = algorithm
+		cfg.compressionLevel = level
+	}
+}
+
+// WithBodyCompression compresses the whole body of upload requests using the
+// given algorithm, "gzip" or "zstd", at the level set by WithUploadCompression.
+// The algorithm is sent as the Content-Encoding of the requests. If the Datadog
+// Agent, or the intake for agentless uploads, rejects it, the upload is retried
+// right away with an uncompressed body, and the following uploads are no longer
+// compressed. It is disabled by default, and this option takes precedence over
+// the DD_PROFILING_BODY_COMPRESSION env variable.
+func WithBodyCompression(algorithm string) Option {
+	return func(cfg *config) {
+		cfg.bodyCompression = algorithm
+	}
+}
+
 // executionTraceConfig controls how often, and for how long, runtime execution
 // traces are collected, see defaultConfig() for more details.
 type executionTraceConfig struct {
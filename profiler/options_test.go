@@ -242,6 +242,29 @@ func TestOptions(t *testing.T) {
 		assert.Equal(t, false, cfg.deltaProfiles)
 	})
 
+	t.Run("WithMaxProfileSize", func(t *testing.T) {
+		var cfg config
+		WithMaxProfileSize(1024)(&cfg)
+		WithMaxProfileSamples(100)(&cfg)
+		assert.Equal(t, 1024, cfg.maxProfileBytes)
+		assert.Equal(t, 100, cfg.maxProfileSamples)
+	})
+
+	t.Run("WithUploadCompression", func(t *testing.T) {
+		var cfg config
+		WithUploadCompression("zstd", 3)(&cfg)
+		WithBodyCompression("gzip")(&cfg)
+		assert.Equal(t, "zstd", cfg.compression)
+		assert.Equal(t, 3, cfg.compressionLevel)
+		assert.Equal(t, "gzip", cfg.bodyCompression)
+	})
+
+	t.Run("WithRemoteConfiguration", func(t *testing.T) {
+		var cfg config
+		WithRemoteConfiguration(true)(&cfg)
+		assert.True(t, cfg.remoteConfig)
+	})
+
 	t.Run("WithHostname", func(t *testing.T) {
 		var cfg config
 		WithHostname("example")(&cfg)
@@ -340,6 +363,37 @@ func TestEnvVars(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, cfg.deltaProfiles, false)
 	})
+
+	t.Run("DD_PROFILING_MAX_PROFILE_BYTES", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_MAX_PROFILE_BYTES", "1024")
+		t.Setenv("DD_PROFILING_MAX_PROFILE_SAMPLES", "100")
+		cfg, err := defaultConfig()
+		require.NoError(t, err)
+		assert.Equal(t, 1024, cfg.maxProfileBytes)
+		assert.Equal(t, 100, cfg.maxProfileSamples)
+	})
+
+	t.Run("DD_PROFILING_COMPRESSION", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_COMPRESSION", "zstd")
+		t.Setenv("DD_PROFILING_COMPRESSION_LEVEL", "3")
+		t.Setenv("DD_PROFILING_BODY_COMPRESSION", "gzip")
+		cfg, err := defaultConfig()
+		require.NoError(t, err)
+		assert.Equal(t, "zstd", cfg.compression)
+		assert.Equal(t, 3, cfg.compressionLevel)
+		assert.Equal(t, "gzip", cfg.bodyCompression)
+
+		t.Setenv("DD_PROFILING_COMPRESSION", "lz4")
+		_, err = unstartedProfiler()
+		assert.Error(t, err)
+	})
+
+	t.Run("DD_PROFILING_REMOTE_CONFIGURATION_ENABLED", func(t *testing.T) {
+		t.Setenv("DD_PROFILING_REMOTE_CONFIGURATION_ENABLED", "true")
+		cfg, err := defaultConfig()
+		require.NoError(t, err)
+		assert.True(t, cfg.remoteConfig)
+	})
 }
 
 func TestDefaultConfig(t *testing.T) {
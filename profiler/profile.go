@@ -7,10 +7,10 @@ package profiler
 
 import (
 	"bytes"
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"runtime"
 	"runtime/trace"
 	"sync"
@@ -22,6 +22,7 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/fastdelta"
+	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/fastprune"
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler/internal/pprofutils"
 )
 
@@ -307,7 +308,7 @@ func (t ProfileType) Tag() string {
 	return fmt.Sprintf("profile_type:%s", t)
 }
 
-// profile specifies a profiles data (gzipped protobuf, json), and the types contained within it.
+// profile specifies a profiles data (compressed protobuf, json), and the types contained within it.
 type profile struct {
 	// name indicates profile type and format (e.g. cpu.pprof, metrics.json)
 	name string
@@ -323,6 +324,9 @@ type batch struct {
 	host           string
 	profiles       []*profile
 	endpointCounts map[string]uint64
+	// customAttributes are the custom pprof label keys to be shown as
+	// attributes in the UI, see WithCustomProfilerLabelKeys.
+	customAttributes []string
 }
 
 func (b *batch) addProfile(p *profile) {
@@ -344,9 +348,113 @@ func (p *profiler) runProfile(pt ProfileType) ([]*profile, error) {
 		filename = "delta-" + filename
 	}
 	p.cfg.statsd.Timing("datadog.profiling.go.collect_time", end.Sub(start), tags, 1)
+	if pp, ok := p.pruners[pt]; ok {
+		data = p.pruneProfile(pp, data, tags)
+	}
+	if c, ok := p.compressors[pt]; ok {
+		start := now()
+		var compressed bool
+		if data, compressed, err = c.Compress(data); err != nil {
+			return nil, fmt.Errorf("compressing profile: %v", err)
+		}
+		if compressed {
+			p.cfg.statsd.Timing("datadog.profiling.go.compress_time", now().Sub(start), tags, 1)
+		}
+		filename += c.Suffix(filename)
+	}
 	return []*profile{{name: filename, pt: pt, data: data}}, nil
 }
 
+// pruneProfile enforces the configured profile size limits on data. If
+// pruning fails, the profile is returned unchanged.
+func (p *profiler) pruneProfile(pp *profilePruner, data []byte, tags []string) []byte {
+	pruned, stats, err := pp.Prune(data, p.cfg.maxProfileBytes, p.cfg.maxProfileSamples)
+	if err != nil {
+		log.Error("Failed to prune profile: %v", err)
+		p.cfg.statsd.Count("datadog.profiling.go.prune_error", 1, tags, 1)
+		return data
+	}
+	if stats.DroppedSamples > 0 {
+		p.cfg.statsd.Count("datadog.profiling.go.pruned_samples", int64(stats.DroppedSamples), tags, 1)
+		p.cfg.statsd.Count("datadog.profiling.go.pruned_bytes", int64(len(data)-len(pruned)), tags, 1)
+	}
+	return pruned
+}
+
+// maxPruneAttempts is the number of times profilePruner tries to get a
+// profile below the byte limit before giving up.
+const maxPruneAttempts = 5
+
+// profilePruner caps the size of compressed pprof profiles, see
+// WithMaxProfileSize and WithMaxProfileSamples. Pruned profiles are compressed
+// with the algorithm configured for uploads.
+type profilePruner struct {
+	pr  *fastprune.Pruner
+	buf bytes.Buffer
+	d   decompressor
+	w   compressor
+}
+
+func newProfilePruner(cfg *config) *profilePruner {
+	return &profilePruner{
+		pr: fastprune.NewPruner(),
+		w:  newCompressor(cfg.compression, cfg.compressionLevel),
+	}
+}
+
+// Prune returns data with its lowest-weight samples removed so that it holds
+// at most maxSamples samples and is at most maxBytes long. A limit of 0 is
+// ignored. As the compressed size of a profile can't be predicted, the byte
+// limit is enforced by pruning repeatedly, which may leave the profile slightly
+// above the limit after maxPruneAttempts.
+func (pp *profilePruner) Prune(data []byte, maxBytes, maxSamples int) (b []byte, stats fastprune.Stats, err error) {
+	if (maxBytes <= 0 || len(data) <= maxBytes) && maxSamples <= 0 {
+		return data, stats, nil
+	}
+	raw, err := pp.d.Decompress(data)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	keep := math.MaxInt32
+	if maxSamples > 0 {
+		keep = maxSamples
+	}
+	for i := 0; i < maxPruneAttempts; i++ {
+		pp.buf.Reset()
+		pp.w.Reset(&pp.buf)
+		if stats, err = pp.pr.Prune(raw, keep, pp.w); err != nil {
+			return nil, stats, fmt.Errorf("error pruning profile: %v", err)
+		}
+		if err = pp.w.Close(); err != nil {
+			return nil, stats, fmt.Errorf("error flushing compressor: %v", err)
+		}
+		if stats.DroppedSamples == 0 && (maxBytes <= 0 || len(data) <= maxBytes) {
+			// Within the sample limit and the byte limit already, no
+			// need to re-compress the profile.
+			return data, stats, nil
+		}
+		if maxBytes <= 0 || pp.buf.Len() <= maxBytes {
+			break
+		}
+		// Shrink the number of samples proportionally to the excess
+		// bytes, with some headroom since the locations, functions and
+		// strings shared between samples don't shrink linearly.
+		kept := stats.Samples - stats.DroppedSamples
+		if kept == 0 {
+			break
+		}
+		keep = int(float64(kept) * float64(maxBytes) / float64(pp.buf.Len()) * 0.9)
+		if keep >= kept {
+			keep = kept - 1
+		}
+	}
+	// The returned slice will be retained in case the profile upload fails,
+	// so we need to return a copy of the buffer's bytes to avoid a data
+	// race.
+	return copyBytes(pp.buf.Bytes()), stats, nil
+}
+
 type deltaProfiler interface {
 	Delta(curData []byte) ([]byte, error)
 }
@@ -374,9 +482,9 @@ func newDeltaProfiler(cfg *config, v ...pprofutils.ValueType) deltaProfiler {
 		return newComparingDeltaProfiler(
 			cfg,
 			&pprofileDeltaProfiler{delta: pprofutils.Delta{SampleTypes: v}},
-			newFastDeltaProfiler(v...))
+			newFastDeltaProfiler(cfg, v...))
 	default:
-		return newFastDeltaProfiler(v...)
+		return newFastDeltaProfiler(cfg, v...)
 	}
 }
 
@@ -420,51 +528,42 @@ func (d *pprofileDeltaProfiler) Delta(curData []byte) ([]byte, error) {
 	return deltaData, nil
 }
 
+// fastDeltaProfiler computes delta profiles using fastdelta. The delta
+// profiles are written with the compression algorithm configured for uploads,
+// so they don't need to be re-compressed before uploading.
 type fastDeltaProfiler struct {
 	dc  *fastdelta.DeltaComputer
 	buf bytes.Buffer
-	gzr gzip.Reader
-	gzw *gzip.Writer
+	d   decompressor
+	w   compressor
 }
 
-func newFastDeltaProfiler(v ...pprofutils.ValueType) deltaProfiler {
-	fd := &fastDeltaProfiler{
+func newFastDeltaProfiler(cfg *config, v ...pprofutils.ValueType) deltaProfiler {
+	return &fastDeltaProfiler{
 		dc: fastdelta.NewDeltaComputer(v...),
+		w:  newCompressor(cfg.compression, cfg.compressionLevel),
 	}
-	fd.gzw = gzip.NewWriter(&fd.buf)
-	return fd
-}
-
-func isGzipData(data []byte) bool {
-	return bytes.HasPrefix(data, []byte{0x1f, 0x8b})
 }
 
 func (fdp *fastDeltaProfiler) Delta(data []byte) (b []byte, err error) {
-	if isGzipData(data) {
-		if err := fdp.gzr.Reset(bytes.NewReader(data)); err != nil {
-			return nil, err
-		}
-		data, err = io.ReadAll(&fdp.gzr)
-		if err != nil {
-			return nil, fmt.Errorf("decompressing profile: %v", err)
-		}
+	data, err = fdp.d.Decompress(data)
+	if err != nil {
+		return nil, err
 	}
 
 	fdp.buf.Reset()
-	fdp.gzw.Reset(&fdp.buf)
+	fdp.w.Reset(&fdp.buf)
 
-	if err = fdp.dc.Delta(data, fdp.gzw); err != nil {
+	if err = fdp.dc.Delta(data, fdp.w); err != nil {
 		return nil, fmt.Errorf("error computing delta: %v", err)
 	}
-	if err = fdp.gzw.Close(); err != nil {
-		return nil, fmt.Errorf("error flushing gzip writer: %v", err)
+	if err = fdp.w.Close(); err != nil {
+		return nil, fmt.Errorf("error flushing compressor: %v", err)
 	}
 	// The returned slice will be retained in case the profile upload fails,
 	// so we need to return a copy of the buffer's bytes to avoid a data
 	// race.
-	b = make([]byte, len(fdp.buf.Bytes()))
-	copy(b, fdp.buf.Bytes())
-	return b, nil
+	return copyBytes(fdp.buf.Bytes()), nil
 }
 
 type comparingDeltaProfiler struct {
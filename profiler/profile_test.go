@@ -9,6 +9,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -217,6 +219,33 @@ func TestRunProfile(t *testing.T) {
 		assert.Equal(t, []byte("goroutine"), profs[0].data)
 	})
 
+	t.Run("pruned", func(t *testing.T) {
+		prof := textProfile{Text: `
+contentions/count delay/nanoseconds
+main;foo 5 50
+main;bar 3 70
+main;baz;qux 1 10
+main;baz 2 20
+`}.Protobuf()
+		p, err := unstartedProfiler(
+			WithPeriod(time.Millisecond),
+			WithProfileTypes(MutexProfile),
+			WithDeltaProfiles(false),
+			WithMaxProfileSamples(2),
+		)
+		require.NoError(t, err)
+		p.testHooks.lookupProfile = func(_ string, w io.Writer, _ int) error {
+			_, err := w.Write(prof)
+			return err
+		}
+		profs, err := p.runProfile(MutexProfile)
+		require.NoError(t, err)
+		require.Equal(t, "contentions/count delay/nanoseconds\nmain;foo 5 50\nmain;bar 3 70\n", protobufToText(profs[0].data))
+		pruned, err := pprofile.ParseData(profs[0].data)
+		require.NoError(t, err)
+		require.Contains(t, pruned.Comments, "pruned 2 of 4 samples to fit size limits")
+	})
+
 	t.Run("goroutinewait", func(t *testing.T) {
 		const sample = `
 goroutine 1 [running]:
@@ -349,6 +378,42 @@ func (c panicReader) Read(_ []byte) (int, error) {
 	panic("42")
 }
 
+func TestProfilePruner(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("internal", "fastprune", "testdata", "heap.pprof"))
+	require.NoError(t, err)
+	heap, err := pprofile.ParseData(raw)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, heap.Write(&buf)) // gzip compressed, like runtime profiles
+	data := buf.Bytes()
+
+	pp := newProfilePruner(&config{})
+	t.Run("disabled", func(t *testing.T) {
+		out, stats, err := pp.Prune(data, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, data, out)
+		require.Zero(t, stats.DroppedSamples)
+	})
+
+	t.Run("within-limits", func(t *testing.T) {
+		out, stats, err := pp.Prune(data, len(data), 1000000)
+		require.NoError(t, err)
+		require.Equal(t, data, out)
+		require.Zero(t, stats.DroppedSamples)
+	})
+
+	t.Run("max-bytes", func(t *testing.T) {
+		maxBytes := len(data) / 2
+		out, stats, err := pp.Prune(data, maxBytes, 0)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(out), maxBytes)
+		require.NotZero(t, stats.DroppedSamples)
+		prof, err := pprofile.ParseData(out)
+		require.NoError(t, err)
+		require.Len(t, prof.Sample, stats.Samples-stats.DroppedSamples)
+	})
+}
+
 // textProfile is a test helper for converting folded text to pprof protobuf
 // profiles.
 // See https://github.com/brendangregg/FlameGraph#2-fold-stacks
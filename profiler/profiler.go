@@ -13,11 +13,13 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 	"sync"
 	"time"
 
 	"gopkg.in/DataDog/dd-trace-go.v1/internal"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
 	"gopkg.in/DataDog/dd-trace-go.v1/internal/traceprof"
 )
 
@@ -73,6 +75,9 @@ type profiler struct {
 	wg              sync.WaitGroup    // wg waits for all goroutines to exit when stopping.
 	met             *metrics          // metric collector state
 	deltas          map[ProfileType]deltaProfiler
+	pruners         map[ProfileType]*profilePruner
+	compressors     map[ProfileType]*attachmentCompressor
+	bodyCompression string         // bodyCompression is the Content-Encoding of uploads, see WithBodyCompression. Disabled for good when rejected by the agent.
 	seq             uint64         // seq is the value of the profile_seq tag
 	pendingProfiles sync.WaitGroup // signal that profile collection is done, for stopping CPU profiling
 
@@ -80,6 +85,19 @@ type profiler struct {
 
 	// lastTrace is the last time an execution trace was collected
 	lastTrace time.Time
+
+	// rcClient receives configuration updates from remote config, if enabled.
+	rcClient *remoteconfig.Client
+	// initialConfig is the configuration the profiler was started with. It
+	// is restored when the remote configuration is removed.
+	initialConfig runtimeConfig
+	// rcMu guards rcPending and rcPath
+	rcMu sync.Mutex
+	// rcPending is the configuration to apply at the start of the next
+	// profiling period, if any.
+	rcPending *runtimeConfig
+	// rcPath is the remote config file of the current configuration.
+	rcPath string
 }
 
 func (p *profiler) shouldTrace() bool {
@@ -183,24 +201,59 @@ func newProfiler(opts ...Option) (*profiler, error) {
 	if cfg.cpuDuration > cfg.period {
 		cfg.cpuDuration = cfg.period
 	}
+	if err := validateCompression(cfg.compression, cfg.compressionLevel); err != nil {
+		return nil, err
+	}
+	if err := validateCompression(cfg.bodyCompression, cfg.compressionLevel); err != nil {
+		return nil, fmt.Errorf("body compression: %v", err)
+	}
 	if cfg.logStartup {
 		logStartup(cfg)
 	}
 
 	p := profiler{
-		cfg:    cfg,
-		out:    make(chan batch, outChannelSize),
-		exit:   make(chan struct{}),
-		met:    newMetrics(),
-		deltas: make(map[ProfileType]deltaProfiler),
+		cfg:             cfg,
+		out:             make(chan batch, outChannelSize),
+		exit:            make(chan struct{}),
+		met:             newMetrics(),
+		deltas:          make(map[ProfileType]deltaProfiler),
+		pruners:         make(map[ProfileType]*profilePruner),
+		compressors:     make(map[ProfileType]*attachmentCompressor),
+		bodyCompression: cfg.bodyCompression,
 	}
 	for pt := range cfg.types {
+		p.initProfileType(pt)
+	}
+	// Execution traces are collected periodically when enabled, without
+	// being part of cfg.types.
+	p.initProfileType(executionTrace)
+	p.uploadFunc = p.upload
+	if cfg.remoteConfig {
+		p.initialConfig = cfg.runtimeConfig()
+		p.rcClient, err = newRemoteConfigClient(&p)
+		if err != nil {
+			log.Warn("Profiler remote configuration disabled: %v", err)
+		}
+	}
+	return &p, nil
+}
+
+// initProfileType sets up the delta profiler, pruner and compressor required by
+// the profile type pt, if they don't exist yet.
+func (p *profiler) initProfileType(pt ProfileType) {
+	if _, ok := p.compressors[pt]; !ok {
+		p.compressors[pt] = newAttachmentCompressor(p.cfg.compression, p.cfg.compressionLevel)
+	}
+	if _, ok := p.deltas[pt]; !ok {
 		if d := profileTypes[pt].DeltaValues; len(d) > 0 {
 			p.deltas[pt] = newDeltaProfiler(p.cfg, d...)
 		}
 	}
-	p.uploadFunc = p.upload
-	return &p, nil
+	if _, ok := p.pruners[pt]; !ok && (p.cfg.maxProfileBytes > 0 || p.cfg.maxProfileSamples > 0) {
+		if strings.HasSuffix(profileTypes[pt].Filename, ".pprof") {
+			p.pruners[pt] = newProfilePruner(p.cfg)
+		}
+	}
 }
 
 // run runs the profiler.
@@ -216,6 +269,10 @@ func (p *profiler) run() {
 		runtime.SetBlockProfileRate(p.cfg.blockRate)
 	}
 	startTelemetry(p.cfg)
+	if p.rcClient != nil {
+		p.rcClient.Start()
+	}
+	traceprof.GlobalCustomLabels().SetKeys(p.cfg.customLabelMaxValues, p.cfg.customLabelKeys...)
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
@@ -252,12 +309,20 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 		endpointCounter.SetEnabled(false)
 		endpointCounter.GetAndReset()
 	}()
+	// Same for custom labels, the tracer stops applying them once the
+	// profiler is stopped.
+	customLabels := traceprof.GlobalCustomLabels()
+	defer customLabels.SetKeys(0)
 
 	for {
+		// Apply the configuration received from remote config, if any. No
+		// profile is being collected at this point.
+		p.applyPendingConfig()
 		bat := batch{
-			seq:   p.seq,
-			host:  p.cfg.hostname,
-			start: now(),
+			seq:              p.seq,
+			host:             p.cfg.hostname,
+			start:            now(),
+			customAttributes: p.cfg.customLabelKeys,
 		}
 		p.seq++
 
@@ -315,6 +380,8 @@ func (p *profiler) collect(ticker <-chan time.Time) {
 		// Include endpoint hits from tracer in profile `event.json`.
 		// Also reset the counters for the next profile period.
 		bat.endpointCounts = endpointCounter.GetAndReset()
+		// Allow new custom label values for the next profile period.
+		customLabels.Reset()
 		// Record the end time of the profile.
 		// This is used by the backend to upscale the endpoint counts if the cpu
 		// duration is less than the profile duration. The formula is:
@@ -428,6 +495,9 @@ func (p *profiler) interruptibleSleep(d time.Duration) {
 // stop stops the profiler.
 func (p *profiler) stop() {
 	p.stopOnce.Do(func() {
+		if p.rcClient != nil {
+			p.rcClient.Stop()
+		}
 		close(p.exit)
 	})
 	p.wg.Wait()
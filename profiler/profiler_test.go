@@ -6,6 +6,7 @@
 package profiler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -20,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	pprofile "github.com/google/pprof/profile"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -291,6 +293,21 @@ func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 	profile.headers = r.Header.Clone()
+	if enc := r.Header.Get("Content-Encoding"); enc != "" {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			m.t.Fatalf("reading body: %s", err)
+			return
+		}
+		var d decompressor
+		if body, err = d.Decompress(body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			m.t.Fatalf("decompressing %s body: %s", enc, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
 	if err := r.ParseMultipartForm(50 << 20); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		m.t.Fatalf("bad multipart form: %s", err)
@@ -533,6 +550,55 @@ func TestEndpointCounts(t *testing.T) {
 	}
 }
 
+// TestCustomProfilerLabels verifies that custom labels applied to spans end up
+// in the CPU profile if their keys are allowed by the profiler.
+func TestCustomProfilerLabels(t *testing.T) {
+	got := make(chan profileMeta, 1)
+	server := httptest.NewServer(&mockBackend{t: t, profiles: got})
+	defer server.Close()
+
+	tracer.Start()
+	defer tracer.Stop()
+
+	err := Start(
+		WithAgentAddr(server.Listener.Addr().String()),
+		WithProfileTypes(CPUProfile),
+		WithPeriod(200*time.Millisecond),
+		WithCustomProfilerLabelKeys("tenant"),
+	)
+	require.NoError(t, err)
+	defer Stop()
+
+	// Burn CPU in spans until the first profile is finished
+	var m profileMeta
+	for m.attachments == nil {
+		select {
+		case m = <-got:
+		default:
+			span := tracer.StartSpan("job",
+				tracer.ProfilerLabel("tenant", "acme"),
+				tracer.ProfilerLabel("secret", "value"),
+			)
+			for start := time.Now(); time.Since(start) < 10*time.Millisecond; {
+			}
+			span.Finish()
+		}
+	}
+	require.Equal(t, []string{"tenant"}, m.event.CustomAttributes)
+
+	prof, err := pprofile.ParseData(m.attachments["cpu.pprof"])
+	require.NoError(t, err)
+	var labeled bool
+	for _, s := range prof.Sample {
+		require.Empty(t, s.Label["secret"])
+		if len(s.Label["tenant"]) > 0 {
+			require.Equal(t, []string{"acme"}, s.Label["tenant"])
+			labeled = true
+		}
+	}
+	require.True(t, labeled, "no sample with the custom label")
+}
+
 func TestExecutionTraceSizeLimit(t *testing.T) {
 	got := make(chan profileMeta)
 	server, client := httpmem.ServerAndClient(&mockBackend{t: t, profiles: got})
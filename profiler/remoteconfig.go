@@ -0,0 +1,289 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package profiler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/log"
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+)
+
+// rcPayload is the part of an APM_TRACING remote config that is relevant to
+// the profiler.
+type rcPayload struct {
+	Profiling *rcProfilingConfig `json:"profiling"`
+}
+
+// rcProfilingConfig holds the profiler settings that can be changed at
+// runtime. Settings that are omitted keep the value given to Start.
+type rcProfilingConfig struct {
+	// ProfileTypes are the names of the enabled profile types, e.g. "cpu" or
+	// "heap". The metrics profile is always enabled.
+	ProfileTypes []string `json:"profile_types"`
+	// CPUDuration is the duration of CPU profiles, e.g. "30s".
+	CPUDuration string `json:"cpu_duration"`
+	// MutexProfileFraction, see MutexProfileFraction.
+	MutexProfileFraction *int `json:"mutex_profile_fraction"`
+	// BlockProfileRate, see BlockProfileRate.
+	BlockProfileRate *int `json:"block_profile_rate"`
+	// ExecutionTraceEnabled toggles the collection of execution traces.
+	ExecutionTraceEnabled *bool `json:"execution_trace_enabled"`
+	// ExecutionTracePeriod is the time between execution traces, e.g. "15m".
+	ExecutionTracePeriod string `json:"execution_trace_period"`
+}
+
+// runtimeConfig is the subset of the profiler configuration that can be
+// changed through remote config.
+type runtimeConfig struct {
+	types         map[ProfileType]struct{}
+	cpuDuration   time.Duration
+	mutexFraction int
+	blockRate     int
+	traceEnabled  bool
+	traceConfig   executionTraceConfig
+}
+
+// runtimeConfig returns a copy of the current runtime configuration.
+func (c *config) runtimeConfig() runtimeConfig {
+	rc := runtimeConfig{
+		types:         make(map[ProfileType]struct{}, len(c.types)),
+		cpuDuration:   c.cpuDuration,
+		mutexFraction: c.mutexFraction,
+		blockRate:     c.blockRate,
+		traceEnabled:  c.traceEnabled,
+		traceConfig:   c.traceConfig,
+	}
+	for t := range c.types {
+		rc.types[t] = struct{}{}
+	}
+	return rc
+}
+
+// merge returns a copy of base with the settings of c applied to it, or an
+// error if c is invalid.
+func (c *rcProfilingConfig) merge(base runtimeConfig, period time.Duration) (runtimeConfig, error) {
+	next := base
+	if c.ProfileTypes != nil {
+		next.types = map[ProfileType]struct{}{MetricsProfile: {}}
+		for _, name := range c.ProfileTypes {
+			t, ok := profileTypeByName(name)
+			if !ok {
+				return base, fmt.Errorf("unknown profile type: %q", name)
+			}
+			next.types[t] = struct{}{}
+		}
+	}
+	if c.CPUDuration != "" {
+		d, err := time.ParseDuration(c.CPUDuration)
+		if err != nil {
+			return base, fmt.Errorf("cpu_duration: %v", err)
+		} else if d <= 0 {
+			return base, fmt.Errorf("cpu_duration: must be > 0: %s", d)
+		}
+		if d > period {
+			d = period
+		}
+		next.cpuDuration = d
+	}
+	if c.MutexProfileFraction != nil {
+		if *c.MutexProfileFraction < 0 {
+			return base, fmt.Errorf("mutex_profile_fraction: must be >= 0: %d", *c.MutexProfileFraction)
+		}
+		next.mutexFraction = *c.MutexProfileFraction
+	}
+	if c.BlockProfileRate != nil {
+		if *c.BlockProfileRate < 0 {
+			return base, fmt.Errorf("block_profile_rate: must be >= 0: %d", *c.BlockProfileRate)
+		}
+		next.blockRate = *c.BlockProfileRate
+	}
+	if c.ExecutionTracePeriod != "" {
+		d, err := time.ParseDuration(c.ExecutionTracePeriod)
+		if err != nil {
+			return base, fmt.Errorf("execution_trace_period: %v", err)
+		} else if d <= 0 {
+			return base, fmt.Errorf("execution_trace_period: must be > 0: %s", d)
+		}
+		next.traceConfig.Period = d
+	}
+	if c.ExecutionTraceEnabled != nil {
+		next.traceEnabled = *c.ExecutionTraceEnabled
+	}
+	if next.traceEnabled && next.traceConfig.Limit == 0 {
+		return base, errors.New("execution_trace_enabled: the execution trace size limit is 0")
+	}
+	return next, nil
+}
+
+// profileTypeByName returns the public profile type with the given name.
+func profileTypeByName(name string) (ProfileType, bool) {
+	for t, pt := range profileTypes {
+		if t == executionTrace || t == expGoroutineWaitProfile {
+			continue
+		}
+		if strings.EqualFold(pt.Name, name) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// newRemoteConfigClient returns a remote config client that forwards the
+// profiler configuration updates to p.
+func newRemoteConfigClient(p *profiler) (*remoteconfig.Client, error) {
+	if p.cfg.agentless {
+		return nil, errors.New("remote configuration requires the Datadog Agent")
+	}
+	rcCfg := remoteconfig.DefaultClientConfig()
+	rcCfg.AgentURL = strings.TrimSuffix(p.cfg.agentURL, "/profiling/v1/input")
+	rcCfg.Env = p.cfg.env
+	rcCfg.HTTP = p.cfg.httpClient
+	rcCfg.ServiceName = p.cfg.service
+	for _, t := range p.cfg.tags.Slice() {
+		if strings.HasPrefix(t, "version:") {
+			rcCfg.AppVersion = strings.TrimPrefix(t, "version:")
+		}
+	}
+	rcCfg.Products = []string{rc.ProductAPMTracing}
+	rcCfg.Capabilities = []remoteconfig.Capability{remoteconfig.APMTracingProfiling}
+	client, err := remoteconfig.NewClient(rcCfg)
+	if err != nil {
+		return nil, err
+	}
+	client.RegisterCallback(p.rcCallback, rc.ProductAPMTracing)
+	return client, nil
+}
+
+// rcCallback validates the profiler configuration received through remote
+// config. Valid configurations are applied at the start of the next profiling
+// period, see applyPendingConfig. A removed configuration restores the
+// configuration the profiler was started with.
+func (p *profiler) rcCallback(u remoteconfig.ProductUpdate) map[string]rc.ApplyStatus {
+	statuses := make(map[string]rc.ApplyStatus, len(u))
+	for path, raw := range u {
+		// A nil config means the configuration was removed
+		if raw == nil {
+			if p.forgetRCPath(path) {
+				log.Debug("profiler: Remote config: %s removed, restoring the initial configuration", path)
+				p.setPendingConfig("", p.initialConfig)
+			}
+			continue
+		}
+		var payload rcPayload
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			log.Error("profiler: Remote config: error while unmarshalling %s: %v. Configuration won't be applied.", path, err)
+			statuses[path] = rc.ApplyStatus{State: rc.ApplyStateError, Error: err.Error()}
+			continue
+		}
+		if payload.Profiling == nil {
+			// Not a profiler configuration, leave the status to the
+			// other subscribers of the product.
+			continue
+		}
+		next, err := payload.Profiling.merge(p.initialConfig, p.cfg.period)
+		if err != nil {
+			log.Error("profiler: Remote config: invalid configuration %s: %v. Configuration won't be applied.", path, err)
+			statuses[path] = rc.ApplyStatus{State: rc.ApplyStateError, Error: err.Error()}
+			continue
+		}
+		log.Debug("profiler: Remote config: processing %s", path)
+		p.setPendingConfig(path, next)
+		statuses[path] = rc.ApplyStatus{State: rc.ApplyStateAcknowledged}
+	}
+	return statuses
+}
+
+// setPendingConfig records c as the configuration to apply at the start of
+// the next profiling period. path is the remote config file it comes from.
+func (p *profiler) setPendingConfig(path string, c runtimeConfig) {
+	p.rcMu.Lock()
+	defer p.rcMu.Unlock()
+	p.rcPending = &c
+	p.rcPath = path
+}
+
+// forgetRCPath reports whether path is the remote config file of the current
+// profiler configuration, and forgets about it if so.
+func (p *profiler) forgetRCPath(path string) bool {
+	p.rcMu.Lock()
+	defer p.rcMu.Unlock()
+	if p.rcPath != path {
+		return false
+	}
+	p.rcPath = ""
+	return true
+}
+
+// applyPendingConfig applies the configuration received through remote config,
+// if any. It must only be called between profiling periods, when no profile
+// is being collected.
+func (p *profiler) applyPendingConfig() {
+	p.rcMu.Lock()
+	next := p.rcPending
+	p.rcPending = nil
+	p.rcMu.Unlock()
+	if next == nil {
+		return
+	}
+
+	prev := p.cfg.runtimeConfig()
+	p.cfg.types = next.types
+	p.cfg.cpuDuration = next.cpuDuration
+	p.cfg.mutexFraction = next.mutexFraction
+	p.cfg.blockRate = next.blockRate
+	p.cfg.traceEnabled = next.traceEnabled
+	p.cfg.traceConfig = next.traceConfig
+	for pt := range p.deltas {
+		// Drop the state of disabled delta profiles, so that the first
+		// profile after re-enabling them doesn't cover a longer period.
+		if _, ok := p.cfg.types[pt]; !ok {
+			delete(p.deltas, pt)
+		}
+	}
+	for pt := range p.cfg.types {
+		p.initProfileType(pt)
+	}
+	p.updateProfileRates(prev)
+	p.cfg.statsd.Count("datadog.profiling.go.remote_config_applied", 1, p.cfg.tags.Slice(), 1)
+	if p.cfg.logStartup {
+		logStartup(p.cfg)
+	}
+}
+
+// updateProfileRates updates the rates of the runtime's mutex and block
+// profilers whose profile types were enabled or disabled, or whose configured
+// rates changed, since the prev configuration. See updateProfileRate.
+func (p *profiler) updateProfileRates(prev runtimeConfig) {
+	_, wasEnabled := prev.types[MutexProfile]
+	_, enabled := p.cfg.types[MutexProfile]
+	updateProfileRate(func(rate int) { runtime.SetMutexProfileFraction(rate) },
+		wasEnabled, prev.mutexFraction, enabled, p.cfg.mutexFraction)
+	_, wasEnabled = prev.types[BlockProfile]
+	_, enabled = p.cfg.types[BlockProfile]
+	updateProfileRate(runtime.SetBlockProfileRate,
+		wasEnabled, prev.blockRate, enabled, p.cfg.blockRate)
+}
+
+// updateProfileRate sets the rate of a runtime profiler using set when its
+// profile type gets enabled or its rate changes, and resets it when its profile
+// type gets disabled, as the profiler set it earlier. The rates set by the
+// application while the profile type is disabled are left untouched.
+func updateProfileRate(set func(rate int), wasEnabled bool, prevRate int, enabled bool, rate int) {
+	switch {
+	case enabled && (!wasEnabled || prevRate != rate):
+		set(rate)
+	case !enabled && wasEnabled:
+		set(0)
+	}
+}
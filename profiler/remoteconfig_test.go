@@ -0,0 +1,144 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023 Datadog, Inc.
+
+package profiler
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	rc "github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/internal/remoteconfig"
+)
+
+func TestRemoteConfigCallback(t *testing.T) {
+	newTestProfiler := func(t *testing.T) *profiler {
+		p, err := unstartedProfiler(
+			WithRemoteConfiguration(true),
+			WithProfileTypes(CPUProfile, HeapProfile),
+			WithPeriod(time.Minute),
+		)
+		require.NoError(t, err)
+		require.NotNil(t, p.rcClient)
+		return p
+	}
+
+	t.Run("apply", func(t *testing.T) {
+		defer runtime.SetMutexProfileFraction(0)
+		defer runtime.SetBlockProfileRate(0)
+		p := newTestProfiler(t)
+		statuses := p.rcCallback(remoteconfig.ProductUpdate{
+			"datadog/2/APM_TRACING/profiling/config": []byte(`{"profiling": {
+				"profile_types": ["cpu", "mutex", "block"],
+				"cpu_duration": "2m",
+				"mutex_profile_fraction": 20,
+				"block_profile_rate": 1000
+			}}`),
+		})
+		require.Equal(t, map[string]rc.ApplyStatus{
+			"datadog/2/APM_TRACING/profiling/config": {State: rc.ApplyStateAcknowledged},
+		}, statuses)
+		// Nothing changes until the next profiling period
+		_, ok := p.cfg.types[MutexProfile]
+		require.False(t, ok)
+
+		p.applyPendingConfig()
+		assert.Equal(t, []ProfileType{CPUProfile, BlockProfile, MutexProfile, MetricsProfile}, p.enabledProfileTypes())
+		assert.Equal(t, time.Minute, p.cfg.cpuDuration) // capped to the period
+		assert.Equal(t, 20, p.cfg.mutexFraction)
+		assert.Equal(t, 20, runtime.SetMutexProfileFraction(-1))
+		assert.Equal(t, 1000, p.cfg.blockRate)
+		assert.Contains(t, p.deltas, MutexProfile)
+		assert.NotContains(t, p.deltas, HeapProfile)
+
+		// Removing the config restores the initial configuration
+		statuses = p.rcCallback(remoteconfig.ProductUpdate{
+			"datadog/2/APM_TRACING/profiling/config": nil,
+		})
+		require.Empty(t, statuses)
+		p.applyPendingConfig()
+		assert.Equal(t, []ProfileType{CPUProfile, HeapProfile, MetricsProfile}, p.enabledProfileTypes())
+		assert.Equal(t, DefaultDuration, p.cfg.cpuDuration)
+		assert.Equal(t, 0, runtime.SetMutexProfileFraction(-1))
+	})
+
+	t.Run("application-rates", func(t *testing.T) {
+		defer runtime.SetMutexProfileFraction(runtime.SetMutexProfileFraction(7))
+		p := newTestProfiler(t)
+		p.rcCallback(remoteconfig.ProductUpdate{
+			"path": []byte(`{"profiling": {"profile_types": ["cpu"], "mutex_profile_fraction": 20}}`),
+		})
+		p.applyPendingConfig()
+		// The rates of the disabled profile types set by the application
+		// are left untouched
+		assert.Equal(t, 7, runtime.SetMutexProfileFraction(-1))
+		p.rcCallback(remoteconfig.ProductUpdate{"path": nil})
+		p.applyPendingConfig()
+		assert.Equal(t, 7, runtime.SetMutexProfileFraction(-1))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		p := newTestProfiler(t)
+		for _, cfg := range []string{
+			`{"profiling": {"profile_types": ["cpu", "nope"]}}`,
+			`{"profiling": {"cpu_duration": "forever"}}`,
+			`{"profiling": {"mutex_profile_fraction": -1}}`,
+			`{"profiling": {"execution_trace_period": "-1s"}}`,
+			`{"profiling": `,
+		} {
+			statuses := p.rcCallback(remoteconfig.ProductUpdate{"path": []byte(cfg)})
+			assert.Equal(t, rc.ApplyStateError, statuses["path"].State, cfg)
+			assert.NotEmpty(t, statuses["path"].Error, cfg)
+		}
+		p.applyPendingConfig()
+		assert.Equal(t, []ProfileType{CPUProfile, HeapProfile, MetricsProfile}, p.enabledProfileTypes())
+	})
+
+	t.Run("not-profiling", func(t *testing.T) {
+		p := newTestProfiler(t)
+		statuses := p.rcCallback(remoteconfig.ProductUpdate{
+			"path": []byte(`{"tracing_sampling_rate": 0.5}`),
+		})
+		assert.Empty(t, statuses)
+		// Removing a config that isn't ours doesn't change anything
+		p.rcCallback(remoteconfig.ProductUpdate{"path": nil})
+		assert.Nil(t, p.rcPending)
+	})
+
+	t.Run("agentless", func(t *testing.T) {
+		p, err := unstartedProfiler(
+			WithRemoteConfiguration(true),
+			WithAgentlessUpload(),
+			WithAPIKey("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		)
+		require.NoError(t, err)
+		assert.Nil(t, p.rcClient)
+	})
+}
+
+func TestUpdateProfileRate(t *testing.T) {
+	for _, tt := range []struct {
+		name                string
+		wasEnabled, enabled bool
+		prevRate, rate      int
+		want                []int
+	}{
+		{name: "enabled", enabled: true, prevRate: 10, rate: 10, want: []int{10}},
+		{name: "rate-changed", wasEnabled: true, enabled: true, prevRate: 10, rate: 20, want: []int{20}},
+		{name: "unchanged", wasEnabled: true, enabled: true, prevRate: 10, rate: 10},
+		{name: "disabled", wasEnabled: true, prevRate: 10, rate: 10, want: []int{0}},
+		{name: "still-disabled", prevRate: 10, rate: 20},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var set []int
+			updateProfileRate(func(rate int) { set = append(set, rate) }, tt.wasEnabled, tt.prevRate, tt.enabled, tt.rate)
+			assert.Equal(t, tt.want, set)
+		})
+	}
+}
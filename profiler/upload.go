@@ -11,7 +11,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"math/rand"
 	"mime/multipart"
 	"net/http"
@@ -95,6 +94,17 @@ func (p *profiler) doRequest(bat batch) error {
 	if err != nil {
 		return err
 	}
+	encoding := p.bodyCompression
+	if encoding == compressionNone {
+		encoding = compressionLegacy
+	}
+	if encoding != compressionLegacy {
+		data, err := compressBody(encoding, p.cfg.compressionLevel, body.Bytes())
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(data)
+	}
 	funcExit := make(chan struct{})
 	defer close(funcExit)
 	// uploadTimeout is guaranteed to be >= 0, see newProfiler.
@@ -117,12 +127,25 @@ func (p *profiler) doRequest(bat batch) error {
 		req.Header.Set("Datadog-Container-ID", containerID)
 	}
 	req.Header.Set("Content-Type", contentType)
+	if encoding != compressionLegacy {
+		req.Header.Set("Content-Encoding", encoding)
+	}
 
 	resp, err := p.cfg.httpClient.Do(req)
 	if err != nil {
 		return &retriableError{err}
 	}
 	defer resp.Body.Close()
+	if (resp.StatusCode == http.StatusUnsupportedMediaType || resp.StatusCode == http.StatusBadRequest) && encoding != compressionLegacy {
+		// The compressed body may not be supported by the agent, retry
+		// right away with an uncompressed body. Uncompressed bodies are
+		// kept for all the following uploads, so that the fallback only
+		// happens once.
+		p.bodyCompression = compressionNone
+		log.Warn("Upload compression %q was rejected (%s), falling back to uncompressed uploads.", encoding, resp.Status)
+		p.cfg.statsd.Count("datadog.profiling.go.body_compression_fallback", 1, []string{"compression:" + encoding}, 1)
+		return p.doRequest(bat)
+	}
 	if resp.StatusCode/100 == 5 {
 		// 5xx can be retried
 		return &retriableError{errors.New(resp.Status)}
@@ -146,10 +169,12 @@ type uploadEvent struct {
 	Family         string            `json:"family"`
 	Version        string            `json:"version"`
 	EndpointCounts map[string]uint64 `json:"endpoint_counts,omitempty"`
+	// CustomAttributes are the custom pprof label keys
+	CustomAttributes []string `json:"custom_attributes,omitempty"`
 }
 
 // encode encodes the profile as a multipart mime request.
-func encode(bat batch, tags []string) (contentType string, body io.Reader, err error) {
+func encode(bat batch, tags []string) (contentType string, body *bytes.Buffer, err error) {
 	var buf bytes.Buffer
 
 	mw := multipart.NewWriter(&buf)
@@ -160,12 +185,13 @@ func encode(bat batch, tags []string) (contentType string, body io.Reader, err e
 	tags = append(tags, "runtime:go")
 
 	event := &uploadEvent{
-		Version:        "4",
-		Family:         "go",
-		Start:          bat.start.Format(time.RFC3339Nano),
-		End:            bat.end.Format(time.RFC3339Nano),
-		Tags:           strings.Join(tags, ","),
-		EndpointCounts: bat.endpointCounts,
+		Version:          "4",
+		Family:           "go",
+		Start:            bat.start.Format(time.RFC3339Nano),
+		End:              bat.end.Format(time.RFC3339Nano),
+		Tags:             strings.Join(tags, ","),
+		EndpointCounts:   bat.endpointCounts,
+		CustomAttributes: bat.customAttributes,
 	}
 
 	for _, p := range bat.profiles {
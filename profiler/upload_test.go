@@ -13,6 +13,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -91,6 +92,56 @@ func TestTryUpload(t *testing.T) {
 	}
 }
 
+func TestTryUploadBodyCompression(t *testing.T) {
+	profiles := make(chan profileMeta, 1)
+	server := httptest.NewServer(&mockBackend{t: t, profiles: profiles})
+	defer server.Close()
+	p, err := unstartedProfiler(
+		WithAgentAddr(server.Listener.Addr().String()),
+		WithBodyCompression("zstd"),
+	)
+	require.NoError(t, err)
+	require.NoError(t, p.doRequest(testBatch))
+	profile := <-profiles
+
+	assert.Equal(t, "zstd", profile.headers.Get("Content-Encoding"))
+	assert.Equal(t, []byte("my-cpu-profile"), profile.attachments["cpu.pprof"])
+}
+
+func TestTryUploadBodyCompressionRejected(t *testing.T) {
+	for _, status := range []int{http.StatusUnsupportedMediaType, http.StatusBadRequest} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			profiles := make(chan profileMeta, 1)
+			backend := &mockBackend{t: t, profiles: profiles}
+			var encodings []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				enc := r.Header.Get("Content-Encoding")
+				encodings = append(encodings, enc)
+				if enc != "" {
+					w.WriteHeader(status)
+					return
+				}
+				backend.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+			p, err := unstartedProfiler(
+				WithAgentAddr(server.Listener.Addr().String()),
+				WithBodyCompression("zstd"),
+			)
+			require.NoError(t, err)
+			require.NoError(t, p.doRequest(testBatch))
+			profile := <-profiles
+			assert.Equal(t, []string{"zstd", ""}, encodings)
+			assert.Equal(t, []byte("my-cpu-profile"), profile.attachments["cpu.pprof"])
+
+			// The following uploads are uncompressed right away
+			require.NoError(t, p.doRequest(testBatch))
+			<-profiles
+			assert.Equal(t, []string{"zstd", "", ""}, encodings)
+		})
+	}
+}
+
 func TestTryUploadUDS(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("Unix domain sockets are non-functional on windows.")